@@ -1,12 +1,14 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
 	"io"
 	"log"
 	"net/http"
 	"os"
+	"time"
 
 	"github.com/O-Tempora/SberIT/config"
 	"github.com/O-Tempora/SberIT/internal/server"
@@ -15,6 +17,9 @@ import (
 
 const defaultConfig = "config/default.yaml"
 
+// Environment variable with secret of HS256 tokens, which is not kept in config files
+const secretEnv = "JWT_SECRET"
+
 var (
 	configPath string
 )
@@ -53,12 +58,28 @@ func main() {
 	if err != nil {
 		log.Fatal(err.Error())
 	}
+	if _, err = time.LoadLocation(cf.Timezone); err != nil {
+		log.Fatal(err.Error())
+	}
+	if secret := os.Getenv(secretEnv); secret != "" {
+		cf.Auth.Secret = secret
+	}
+
+	// Subcommand mode: ./app -config=... migrate up|down|status
+	if flag.Arg(0) == "migrate" {
+		if err = runMigrate(cf, flag.Args()[1:]); err != nil {
+			log.Fatal(err.Error())
+		}
+		return
+	}
 
 	wr := getLoggerWriter()
 	s := server.InitServer(cf).
 		WithLogger(wr).
-		WithDb(cf.DbHost, cf.DbBase, cf.DbPort)
+		WithStorage().
+		WithTokens()
 	s.InitRouter()
+	go s.RunPurger(context.Background())
 
 	connectionInfo := fmt.Sprintf("%s:%d", cf.Host, cf.Port)
 	s.Logger.Info().Msgf("Server starts on %s", connectionInfo)
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/O-Tempora/SberIT/config"
+	"github.com/O-Tempora/SberIT/internal/migrations"
+	"github.com/O-Tempora/SberIT/internal/server"
+)
+
+const migrateUsage = "usage: migrate up | down [steps] | status"
+
+// Handles "migrate up", "migrate down [steps]" and "migrate status" subcommands
+func runMigrate(cf config.Config, args []string) error {
+	if len(args) == 0 {
+		return errors.New(migrateUsage)
+	}
+
+	db, err := server.OpenDb(cf)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	migrator, err := migrations.New(db)
+	if err != nil {
+		return err
+	}
+
+	switch args[0] {
+	case "up":
+		applied, err := migrator.Up()
+		for _, mg := range applied {
+			fmt.Printf("applied  %04d_%s\n", mg.Version, mg.Name)
+		}
+		if err != nil {
+			return err
+		}
+		if len(applied) == 0 {
+			fmt.Println("no pending migrations")
+		}
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			if steps, err = strconv.Atoi(args[1]); err != nil || steps < 1 {
+				return fmt.Errorf("invalid steps count %q: %s", args[1], migrateUsage)
+			}
+		}
+		reverted, err := migrator.Down(steps)
+		for _, mg := range reverted {
+			fmt.Printf("reverted %04d_%s\n", mg.Version, mg.Name)
+		}
+		if err != nil {
+			return err
+		}
+	case "status":
+		statuses, err := migrator.Status()
+		if err != nil {
+			return err
+		}
+		for _, st := range statuses {
+			if st.Applied {
+				fmt.Printf("%04d_%s\tapplied at %s\n", st.Version, st.Name, st.AppliedAt.Format(time.RFC1123))
+			} else {
+				fmt.Printf("%04d_%s\tpending\n", st.Version, st.Name)
+			}
+		}
+	default:
+		return errors.New(migrateUsage)
+	}
+	return nil
+}
@@ -1,9 +1,95 @@
 package config
 
+import "time"
+
 type Config struct {
-	Host   string `yaml:"host"`
-	Port   int    `yaml:"port"`
+	Host string `yaml:"host"`
+	Port int    `yaml:"port"`
+	// Storage driver: postgres (default), sqlite or memory
+	Driver string `yaml:"driver"`
 	DbHost string `yaml:"dbhost"`
 	DbPort int    `yaml:"dbport"`
 	DbBase string `yaml:"dbbase"`
+	// Path to sqlite database file
+	DbPath     string     `yaml:"dbpath"`
+	Validation Validation `yaml:"validation"`
+	// Require If-Match header with task ETag on PUT, PATCH and DELETE
+	RequireIfMatch bool `yaml:"requireifmatch"`
+	// IANA timezone of requests without X-Timezone header or tz parameter, UTC if empty
+	Timezone     string       `yaml:"timezone"`
+	Trash        Trash        `yaml:"trash"`
+	Subtasks     Subtasks     `yaml:"subtasks"`
+	Dependencies Dependencies `yaml:"dependencies"`
+	Agenda       Agenda       `yaml:"agenda"`
+	Users        Users        `yaml:"users"`
+	Auth         Auth         `yaml:"auth"`
+}
+
+// Limits of task fields. Zero values are replaced with defaults
+type Validation struct {
+	// Max length of header in characters
+	MaxHeader int `yaml:"maxheader"`
+	// Max length of description in characters
+	MaxDescription int `yaml:"maxdescription"`
+}
+
+// Purging of deleted tasks
+type Trash struct {
+	// Tasks are permanently deleted after being in trash this long, never if zero
+	Retention time.Duration `yaml:"retention"`
+	// Interval between purges, 1h if zero
+	PurgeInterval time.Duration `yaml:"purgeinterval"`
+}
+
+// Rules of tasks with subtasks
+type Subtasks struct {
+	// Task can not be marked done while any of its subtasks is not done
+	BlockParentDone bool `yaml:"blockparentdone"`
+}
+
+// Rules of tasks blocked by other tasks
+type Dependencies struct {
+	// Task can not be marked done while any of its blockers is not done
+	BlockDone bool `yaml:"blockdone"`
+}
+
+// Weights of scoring function which ranks tasks of agenda. Zero values are replaced with defaults
+type Agenda struct {
+	// Score of every priority level
+	PriorityWeight float64 `yaml:"priorityweight"`
+	// Score of task with deadline now, it grows linearly from zero at Horizon before deadline
+	DeadlineWeight float64 `yaml:"deadlineweight"`
+	// Score added to overdue tasks
+	OverdueWeight float64 `yaml:"overdueweight"`
+	// How long before deadline it starts to raise score, 168h if zero
+	Horizon time.Duration `yaml:"horizon"`
+}
+
+// Accounts of users
+type Users struct {
+	// Cost of bcrypt hashes of passwords, 10 if zero
+	PasswordCost int `yaml:"passwordcost"`
+}
+
+// Bearer tokens (JWT) which authenticate requests. Tokens are verified with keys from Secret, key files and JWKS file,
+// server issues them only if it has signing key: Secret for HS256 or PrivateKeyFile for RS256
+type Auth struct {
+	// Signing algorithm of tokens: HS256 (default) or RS256
+	Algorithm string `yaml:"algorithm"`
+	// Key of HS256 tokens, JWT_SECRET environment variable overrides it. Should not be kept in config files
+	Secret string `yaml:"secret"`
+	// PEM file with RSA private key which signs RS256 tokens, its public key verifies them
+	PrivateKeyFile string `yaml:"privatekeyfile"`
+	// PEM file with RSA public key which verifies RS256 tokens issued elsewhere
+	PublicKeyFile string `yaml:"publickeyfile"`
+	// Local JWKS file with keys which verify tokens, they are selected by kid header of token
+	JWKSFile string `yaml:"jwksfile"`
+	// Put into kid header of issued tokens
+	KeyId string `yaml:"keyid"`
+	// Put into iss claim of issued tokens and required in verified ones if set
+	Issuer string `yaml:"issuer"`
+	// Lifetime of access tokens, 15m if zero
+	AccessTTL time.Duration `yaml:"accessttl"`
+	// Lifetime of refresh tokens, 720h if zero
+	RefreshTTL time.Duration `yaml:"refreshttl"`
 }
@@ -15,9 +15,9 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
-        "/tasks": {
+        "/api-keys": {
             "get": {
-                "description": "Returns list of tasks with optional pagination (page + take) and optional filter by status (done)",
+                "description": "Returns api keys of user, revoked ones too, ordered by id. Keys themselves are not returned, only their prefixes. Requires credentials of user, not api key",
                 "consumes": [
                     "application/json"
                 ],
@@ -25,27 +25,15 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "GetList"
+                    "API keys"
                 ],
-                "summary": "Get task list",
+                "summary": "Get api keys",
                 "parameters": [
                     {
-                        "type": "boolean",
-                        "description": "Task status",
-                        "name": "done",
-                        "in": "query"
-                    },
-                    {
-                        "type": "integer",
-                        "description": "Page number",
-                        "name": "page",
-                        "in": "query"
-                    },
-                    {
-                        "type": "integer",
-                        "description": "Page size",
-                        "name": "take",
-                        "in": "query"
+                        "type": "string",
+                        "description": "ETag of cached response",
+                        "name": "If-None-Match",
+                        "in": "header"
                     }
                 ],
                 "responses": {
@@ -54,26 +42,190 @@ const docTemplate = `{
                         "schema": {
                             "type": "array",
                             "items": {
-                                "$ref": "#/definitions/models.Task"
+                                "$ref": "#/definitions/models.APIKey"
                             }
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "Hash of response"
+                            }
+                        }
+                    },
+                    "304": {
+                        "description": "Not Modified"
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/server.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/server.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/server.Problem"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Creates long-lived api key of user for service clients, e.g. CI bots. Key is sent in X-API-Key header and allows operations of its scopes: tasks:read for reading tasks, projects and tags, tasks:write for changing them. Key is returned only in this response. Requires credentials of user, not api key",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "API keys"
+                ],
+                "summary": "Create api key",
+                "parameters": [
+                    {
+                        "description": "Name and scopes of key",
+                        "name": "key",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.APIKey"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/models.APIKey"
                         }
                     },
                     "400": {
                         "description": "Bad Request",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/server.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/server.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/server.Problem"
+                        }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
+                            "$ref": "#/definitions/server.Problem"
                         }
                     },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/server.Problem"
                         }
                     }
                 }
-            },
+            }
+        },
+        "/api-keys/{id}": {
+            "delete": {
+                "description": "Revokes api key of user with id from path param, so it no longer authenticates requests. Revoked key stays in list of keys. Requires credentials of user, not api key",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "API keys"
+                ],
+                "summary": "Revoke api key",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Key id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/server.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/server.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/server.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/server.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/server.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/health": {
+            "get": {
+                "description": "Reports that server is up and its database is reachable. Does not require authentication",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Health"
+                ],
+                "summary": "Check health",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/server.Health"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/server.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/login": {
             "post": {
-                "description": "Creates task with fields in body param and returns inserted id if successfull",
+                "description": "Checks username and password and returns user",
                 "consumes": [
                     "application/json"
                 ],
@@ -81,17 +233,17 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Create"
+                    "Users"
                 ],
-                "summary": "Create task",
+                "summary": "Log in",
                 "parameters": [
                     {
-                        "description": "Task data",
-                        "name": "task",
+                        "description": "Username and password",
+                        "name": "credentials",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/models.Task"
+                            "$ref": "#/definitions/models.Credentials"
                         }
                     }
                 ],
@@ -99,27 +251,33 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "type": "integer"
+                            "$ref": "#/definitions/models.User"
                         }
                     },
                     "400": {
                         "description": "Bad Request",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/server.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/server.Problem"
                         }
                     },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/server.Problem"
                         }
                     }
                 }
             }
         },
-        "/tasks/byDate/{year}-{month}-{day}": {
+        "/projects": {
             "get": {
-                "description": "Returns tasks by date from path params and optional filter by status (done)",
+                "description": "Returns projects ordered by name with numbers of their open and done tasks, archived projects only with archived=true",
                 "consumes": [
                     "application/json"
                 ],
@@ -127,35 +285,32 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "GetList"
+                    "Projects"
                 ],
-                "summary": "Get tasks by date",
+                "summary": "Get projects",
                 "parameters": [
                     {
-                        "type": "integer",
-                        "description": "Year",
-                        "name": "year",
-                        "in": "path",
-                        "required": true
+                        "type": "boolean",
+                        "description": "List archived projects instead of active ones",
+                        "name": "archived",
+                        "in": "query"
                     },
                     {
-                        "type": "integer",
-                        "description": "Month",
-                        "name": "month",
-                        "in": "path",
-                        "required": true
+                        "type": "string",
+                        "description": "ETag of cached response",
+                        "name": "If-None-Match",
+                        "in": "header"
                     },
                     {
-                        "type": "integer",
-                        "description": "Day",
-                        "name": "day",
-                        "in": "path",
-                        "required": true
+                        "type": "string",
+                        "description": "IANA timezone of times, e.g. Europe/Moscow (timezone from config by default)",
+                        "name": "X-Timezone",
+                        "in": "header"
                     },
                     {
-                        "type": "boolean",
-                        "description": "Task status",
-                        "name": "done",
+                        "type": "string",
+                        "description": "Timezone if X-Timezone header is not set",
+                        "name": "tz",
                         "in": "query"
                     }
                 ],
@@ -165,28 +320,87 @@ const docTemplate = `{
                         "schema": {
                             "type": "array",
                             "items": {
-                                "$ref": "#/definitions/models.Task"
+                                "$ref": "#/definitions/models.Project"
+                            }
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "Hash of response"
                             }
                         }
                     },
+                    "304": {
+                        "description": "Not Modified"
+                    },
                     "400": {
                         "description": "Bad Request",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/server.Problem"
                         }
                     },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/server.Problem"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Creates project and returns its id. Name is required and at most 200 characters long",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Projects"
+                ],
+                "summary": "Create project",
+                "parameters": [
+                    {
+                        "description": "Project data",
+                        "name": "project",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.Project"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "type": "integer"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/server.Problem"
+                        }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
+                            "$ref": "#/definitions/server.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/server.Problem"
                         }
                     }
                 }
             }
         },
-        "/tasks/{id}": {
+        "/projects/{pid}": {
             "get": {
-                "description": "Returns task with id from id path vparam. Returns error if no task with such id exists",
+                "description": "Returns project with id from pid path param with numbers of its open and done tasks",
                 "consumes": [
                     "application/json"
                 ],
@@ -194,41 +408,74 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Get"
+                    "Projects"
                 ],
-                "summary": "Get task by id",
+                "summary": "Get project by id",
                 "parameters": [
                     {
                         "type": "integer",
-                        "description": "Task id",
-                        "name": "id",
+                        "description": "Project id",
+                        "name": "pid",
                         "in": "path",
                         "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag of cached response",
+                        "name": "If-None-Match",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "IANA timezone of times, e.g. Europe/Moscow (timezone from config by default)",
+                        "name": "X-Timezone",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Timezone if X-Timezone header is not set",
+                        "name": "tz",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/models.Task"
+                            "$ref": "#/definitions/models.Project"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "Hash of response"
+                            }
                         }
                     },
+                    "304": {
+                        "description": "Not Modified"
+                    },
                     "400": {
                         "description": "Bad Request",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/server.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/server.Problem"
                         }
                     },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/server.Problem"
                         }
                     }
                 }
             },
             "put": {
-                "description": "Updates task using data from body and with id from path param. If some fields of body struct are omitted, they will be overwritten by default values",
+                "description": "Renames project with id from pid path param, project is validated the same way as on create. Only user who created project changes it",
                 "consumes": [
                     "application/json"
                 ],
@@ -236,24 +483,24 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Update"
+                    "Projects"
                 ],
-                "summary": "Update task",
+                "summary": "Rename project",
                 "parameters": [
                     {
                         "type": "integer",
-                        "description": "Task id",
-                        "name": "id",
+                        "description": "Project id",
+                        "name": "pid",
                         "in": "path",
                         "required": true
                     },
                     {
-                        "description": "Task data",
-                        "name": "task",
+                        "description": "Project data",
+                        "name": "project",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/models.Task"
+                            "$ref": "#/definitions/models.Project"
                         }
                     }
                 ],
@@ -264,19 +511,39 @@ const docTemplate = `{
                     "400": {
                         "description": "Bad Request",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/server.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/server.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/server.Problem"
+                        }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
+                            "$ref": "#/definitions/server.Problem"
                         }
                     },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/server.Problem"
                         }
                     }
                 }
-            },
-            "delete": {
-                "description": "Deletes task by id from id path param",
+            }
+        },
+        "/projects/{pid}/archive": {
+            "post": {
+                "description": "Archives project with id from pid path param together with its tasks available to user, tasks of other users are left as they are. Archived tasks are listed only in their project and can not be changed.\nTasks in trash are not archived, but are restored archived. Does nothing if project is already archived. Only user who created project archives it",
                 "consumes": [
                     "application/json"
                 ],
@@ -284,14 +551,14 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Delete"
+                    "Projects"
                 ],
-                "summary": "Delete task by id",
+                "summary": "Archive project",
                 "parameters": [
                     {
                         "type": "integer",
-                        "description": "Task id",
-                        "name": "id",
+                        "description": "Project id",
+                        "name": "pid",
                         "in": "path",
                         "required": true
                     }
@@ -303,13 +570,3159 @@ const docTemplate = `{
                     "400": {
                         "description": "Bad Request",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/server.Problem"
                         }
                     },
-                    "500": {
-                        "description": "Internal Server Error",
+                    "403": {
+                        "description": "Forbidden",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/server.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/server.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/server.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/projects/{pid}/tasks": {
+            "get": {
+                "description": "Returns tasks of project with id from pid path param, archived tasks included.\nSupports the same filters, sorting and pagination as GET /tasks",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Projects"
+                ],
+                "summary": "Get tasks of project",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Project id",
+                        "name": "pid",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Task status",
+                        "name": "done",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Case insensitive text in header or description",
+                        "name": "q",
+                        "in": "query"
+                    },
+                    {
+                        "type": "array",
+                        "items": {
+                            "type": "string"
+                        },
+                        "collectionFormat": "multi",
+                        "description": "Names of tags",
+                        "name": "tag",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Order of tasks, cursor pagination supports only id",
+                        "name": "sort",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Cursor of page from next_cursor or prev_cursor of previous response",
+                        "name": "cursor",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Page size of cursor pagination (1-100, 20 by default)",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Wrap array of tasks in TaskList",
+                        "name": "envelope",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag of cached response",
+                        "name": "If-None-Match",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "IANA timezone of dates and deadlines, e.g. Europe/Moscow (timezone from config by default)",
+                        "name": "X-Timezone",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Timezone if X-Timezone header is not set",
+                        "name": "tz",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/server.TaskPage"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "Hash of response"
+                            },
+                            "X-Total-Count": {
+                                "type": "integer",
+                                "description": "Number of tasks matching filter"
+                            }
+                        }
+                    },
+                    "304": {
+                        "description": "Not Modified"
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/server.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/server.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/server.Problem"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Creates task in project with id from pid path param the same way as POST /tasks. Project must not be archived",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Projects"
+                ],
+                "summary": "Create task in project",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Project id",
+                        "name": "pid",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Task data",
+                        "name": "task",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.Task"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "IANA timezone of dates and deadlines, e.g. Europe/Moscow (timezone from config by default)",
+                        "name": "X-Timezone",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Timezone if X-Timezone header is not set",
+                        "name": "tz",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "type": "integer"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/server.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/server.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/server.Problem"
+                        }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
+                            "$ref": "#/definitions/server.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/server.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/projects/{pid}/tasks/{id}": {
+            "put": {
+                "description": "Moves task with id from id path param to project with id from pid path param. Neither task nor project can be archived.\nTask is removed from project by setting its project_id to null",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Projects"
+                ],
+                "summary": "Move task to project",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Project id",
+                        "name": "pid",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Task id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag of task or its version in quotes, required if enabled in config",
+                        "name": "If-Match",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/server.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/server.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/server.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/server.Problem"
+                        }
+                    },
+                    "412": {
+                        "description": "Precondition Failed",
+                        "schema": {
+                            "$ref": "#/definitions/server.Problem"
+                        }
+                    },
+                    "428": {
+                        "description": "Precondition Required",
+                        "schema": {
+                            "$ref": "#/definitions/server.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/server.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/projects/{pid}/unarchive": {
+            "post": {
+                "description": "Brings project with id from pid path param and its tasks available to user back from archive. Does nothing if project is not archived. Only user who created project unarchives it",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Projects"
+                ],
+                "summary": "Unarchive project",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Project id",
+                        "name": "pid",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/server.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/server.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/server.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/server.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/tags": {
+            "get": {
+                "description": "Returns all tags ordered by name",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Tags"
+                ],
+                "summary": "Get tags",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ETag of cached response",
+                        "name": "If-None-Match",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.Tag"
+                            }
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "Hash of response"
+                            }
+                        }
+                    },
+                    "304": {
+                        "description": "Not Modified"
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/server.Problem"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Creates tag and returns its id. Name is required, unique and at most 50 characters long, colour is optional hex colour #rrggbb",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Tags"
+                ],
+                "summary": "Create tag",
+                "parameters": [
+                    {
+                        "description": "Tag data",
+                        "name": "tag",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.Tag"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "type": "integer"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/server.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/server.Problem"
+                        }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
+                            "$ref": "#/definitions/server.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/server.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/tags/{id}": {
+            "put": {
+                "description": "Renames tag with id from path param and sets its colour, tag is validated the same way as on create. Tasks with the tag show new name and colour. Only user who created tag changes it",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Tags"
+                ],
+                "summary": "Update tag",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Tag id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Tag data",
+                        "name": "tag",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.Tag"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/server.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/server.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/server.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/server.Problem"
+                        }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
+                            "$ref": "#/definitions/server.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/server.Problem"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Deletes tag with id from path param and detaches it from all tasks. Only user who created tag deletes it and only if it is not attached to tasks of other users",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Tags"
+                ],
+                "summary": "Delete tag",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Tag id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/server.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/server.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/server.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/server.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/server.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/tasks": {
+            "get": {
+                "description": "Returns list of tasks matching all set filters, ordered by fields of sort and then by id.\nSort is comma separated list of fields (id, header, deadline, done, priority), \"-\" prefix sets descending order, e.g. sort=deadline,-id.\nIf cursor or limit is set, returns page of cursor pagination with cursors of adjacent pages, which are also sent in Link header.\nPagination with page + take is deprecated: it returns array of tasks and sets Deprecation header.\nWithout pagination parameters returns array of all tasks.\nNumber of all tasks matching filter is sent in X-Total-Count header. With envelope=true array is wrapped in server.TaskList with total, page, page_size and has_more",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "GetList"
+                ],
+                "summary": "Get task list",
+                "parameters": [
+                    {
+                        "type": "boolean",
+                        "description": "Task status",
+                        "name": "done",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Earliest deadline (YYYY-MM-DD), inclusive",
+                        "name": "deadline_from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Latest deadline (YYYY-MM-DD), inclusive",
+                        "name": "deadline_to",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Task is not done and its deadline has passed",
+                        "name": "overdue",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Case insensitive text in header or description",
+                        "name": "q",
+                        "in": "query"
+                    },
+                    {
+                        "type": "array",
+                        "items": {
+                            "type": "string"
+                        },
+                        "collectionFormat": "multi",
+                        "description": "Names of tags, task has any of them (or all with tag_match=all)",
+                        "name": "tag",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "any",
+                            "all"
+                        ],
+                        "type": "string",
+                        "default": "any",
+                        "description": "Whether task has any or all of tags",
+                        "name": "tag_match",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Order of tasks, cursor pagination supports only id",
+                        "name": "sort",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Cursor of page from next_cursor or prev_cursor of previous response",
+                        "name": "cursor",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Page size of cursor pagination (1-100, 20 by default)",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Page number (deprecated)",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Page size (deprecated, 1-100)",
+                        "name": "take",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Wrap array of tasks in TaskList",
+                        "name": "envelope",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag of cached response",
+                        "name": "If-None-Match",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "IANA timezone of dates and deadlines, e.g. Europe/Moscow (timezone from config by default)",
+                        "name": "X-Timezone",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Timezone if X-Timezone header is not set",
+                        "name": "tz",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/server.TaskPage"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "Hash of response"
+                            },
+                            "Link": {
+                                "type": "string",
+                                "description": "Urls of next and previous pages"
+                            },
+                            "X-Total-Count": {
+                                "type": "integer",
+                                "description": "Number of tasks matching filter"
+                            }
+                        }
+                    },
+                    "304": {
+                        "description": "Not Modified"
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/server.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/server.Problem"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Creates task with fields in body param and returns inserted id if successfull. Date only deadline means the end of that day in timezone of request. Task is validated: header is required, header and description lengths are limited, deadline is required and can not be in the past, id must be omitted",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Create"
+                ],
+                "summary": "Create task",
+                "parameters": [
+                    {
+                        "description": "Task data",
+                        "name": "task",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.Task"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "IANA timezone of dates and deadlines, e.g. Europe/Moscow (timezone from config by default)",
+                        "name": "X-Timezone",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Timezone if X-Timezone header is not set",
+                        "name": "tz",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "integer"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/server.Problem"
+                        }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
+                            "$ref": "#/definitions/server.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/server.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/tasks/agenda": {
+            "get": {
+                "description": "Returns tasks matching filters ranked by score, highest first. Score is the sum of priority weight for every priority level,\nshare of deadline weight which grows as deadline approaches within horizon and overdue weight for overdue tasks, weights are set in config.\nFilters are the same as of /tasks, but only open tasks are returned unless done is set. Done tasks are scored only by priority",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "GetList"
+                ],
+                "summary": "Get tasks ranked by importance",
+                "parameters": [
+                    {
+                        "type": "boolean",
+                        "description": "Task status, false by default",
+                        "name": "done",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Earliest deadline (YYYY-MM-DD), inclusive",
+                        "name": "deadline_from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Latest deadline (YYYY-MM-DD), inclusive",
+                        "name": "deadline_to",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Task is not done and its deadline has passed",
+                        "name": "overdue",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Case insensitive text in header or description",
+                        "name": "q",
+                        "in": "query"
+                    },
+                    {
+                        "type": "array",
+                        "items": {
+                            "type": "string"
+                        },
+                        "collectionFormat": "multi",
+                        "description": "Names of tags, task has any of them (or all with tag_match=all)",
+                        "name": "tag",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "any",
+                            "all"
+                        ],
+                        "type": "string",
+                        "default": "any",
+                        "description": "Whether task has any or all of tags",
+                        "name": "tag_match",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag of cached response",
+                        "name": "If-None-Match",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "IANA timezone of dates and deadlines, e.g. Europe/Moscow (timezone from config by default)",
+                        "name": "X-Timezone",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Timezone if X-Timezone header is not set",
+                        "name": "tz",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/server.AgendaTask"
+                            }
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "Hash of response"
+                            }
+                        }
+                    },
+                    "304": {
+                        "description": "Not Modified"
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/server.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/server.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/tasks/byDate/{year}-{month}-{day}": {
+            "get": {
+                "description": "Returns tasks by date from path params and optional filter by status (done).\nUnless done=true, virtual future occurrences of open recurring tasks follow them ordered by deadline, they have id 0 and id of their task in occurrence_of.\nNumber of tasks is sent in X-Total-Count header. With envelope=true array is wrapped in server.TaskList",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "GetList"
+                ],
+                "summary": "Get tasks by date",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Year",
+                        "name": "year",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Month",
+                        "name": "month",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Day",
+                        "name": "day",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Task status",
+                        "name": "done",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Wrap array of tasks in TaskList",
+                        "name": "envelope",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag of cached response",
+                        "name": "If-None-Match",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "IANA timezone of dates and deadlines, e.g. Europe/Moscow (timezone from config by default)",
+                        "name": "X-Timezone",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Timezone if X-Timezone header is not set",
+                        "name": "tz",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.Task"
+                            }
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "Hash of response"
+                            },
+                            "X-Total-Count": {
+                                "type": "integer",
+                                "description": "Number of tasks"
+                            }
+                        }
+                    },
+                    "304": {
+                        "description": "Not Modified"
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/server.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/server.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/tasks/month/{month}": {
+            "get": {
+                "description": "Returns tasks with deadline in month grouped by day, with optional filter by status (done).\nVirtual occurrences of recurring tasks are included as in /tasks/byDate",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Calendar"
+                ],
+                "summary": "Get tasks by month",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Month (YYYY-MM), e.g. 2024-02",
+                        "name": "month",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Task status",
+                        "name": "done",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag of cached response",
+                        "name": "If-None-Match",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "IANA timezone of dates and deadlines, e.g. Europe/Moscow (timezone from config by default)",
+                        "name": "X-Timezone",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Timezone if X-Timezone header is not set",
+                        "name": "tz",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/server.CalendarDay"
+                            }
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "Hash of response"
+                            }
+                        }
+                    },
+                    "304": {
+                        "description": "Not Modified"
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/server.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/server.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/tasks/range": {
+            "get": {
+                "description": "Returns tasks with deadline from first to last date inclusive grouped by day, with optional filter by status (done).\nEvery day of range is present, range can not be longer than 366 days. Virtual occurrences of recurring tasks are included as in /tasks/byDate",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Calendar"
+                ],
+                "summary": "Get tasks by date range",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "First date (YYYY-MM-DD)",
+                        "name": "from",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Last date (YYYY-MM-DD)",
+                        "name": "to",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Task status",
+                        "name": "done",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag of cached response",
+                        "name": "If-None-Match",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "IANA timezone of dates and deadlines, e.g. Europe/Moscow (timezone from config by default)",
+                        "name": "X-Timezone",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Timezone if X-Timezone header is not set",
+                        "name": "tz",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/server.CalendarDay"
+                            }
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "Hash of response"
+                            }
+                        }
+                    },
+                    "304": {
+                        "description": "Not Modified"
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/server.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/server.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/tasks/ready": {
+            "get": {
+                "description": "Returns open tasks which are not blocked by open tasks ordered by deadline. Blockers in trash or archived do not block, open blockers of other users do.\nWith all=true other open tasks follow them in topological order, every task after all its blockers",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Dependencies"
+                ],
+                "summary": "Get tasks to work on now",
+                "parameters": [
+                    {
+                        "type": "boolean",
+                        "description": "Return all open tasks in order they can be done",
+                        "name": "all",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag of cached response",
+                        "name": "If-None-Match",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "IANA timezone of dates and deadlines, e.g. Europe/Moscow (timezone from config by default)",
+                        "name": "X-Timezone",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Timezone if X-Timezone header is not set",
+                        "name": "tz",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.Task"
+                            }
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "Hash of response"
+                            }
+                        }
+                    },
+                    "304": {
+                        "description": "Not Modified"
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/server.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/server.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/tasks/trash": {
+            "get": {
+                "description": "Returns tasks in trash with time of deletion, recently deleted first",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Trash"
+                ],
+                "summary": "Get deleted tasks",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "IANA timezone of dates and deadlines, e.g. Europe/Moscow (timezone from config by default)",
+                        "name": "X-Timezone",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Timezone if X-Timezone header is not set",
+                        "name": "tz",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag of cached response",
+                        "name": "If-None-Match",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.Task"
+                            }
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "Hash of response"
+                            }
+                        }
+                    },
+                    "304": {
+                        "description": "Not Modified"
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/server.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/server.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/tasks/week/{week}": {
+            "get": {
+                "description": "Returns tasks with deadline in ISO 8601 week (from monday to sunday) grouped by day, with optional filter by status (done).\nVirtual occurrences of recurring tasks are included as in /tasks/byDate",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Calendar"
+                ],
+                "summary": "Get tasks by week",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ISO week (YYYY-Www), e.g. 2024-W05",
+                        "name": "week",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Task status",
+                        "name": "done",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag of cached response",
+                        "name": "If-None-Match",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "IANA timezone of dates and deadlines, e.g. Europe/Moscow (timezone from config by default)",
+                        "name": "X-Timezone",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Timezone if X-Timezone header is not set",
+                        "name": "tz",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/server.CalendarDay"
+                            }
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "Hash of response"
+                            }
+                        }
+                    },
+                    "304": {
+                        "description": "Not Modified"
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/server.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/server.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/tasks/{id}": {
+            "get": {
+                "description": "Returns task with id from id path vparam. Returns error if no task with such id exists.\nWith expand=children task has tree of its subtasks in children.\nETag is version of task with hash of response, as progress and role of task change without its version. If-Match of changes takes this ETag",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Get"
+                ],
+                "summary": "Get task by id",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Task id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "enum": [
+                            "children"
+                        ],
+                        "type": "string",
+                        "description": "Embed subtasks at any depth",
+                        "name": "expand",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag of cached response",
+                        "name": "If-None-Match",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "IANA timezone of dates and deadlines, e.g. Europe/Moscow (timezone from config by default)",
+                        "name": "X-Timezone",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Timezone if X-Timezone header is not set",
+                        "name": "tz",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.Task"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "Version of task and hash of response, e.g. \\\"3-af63bd4c8601b7be\\"
+                            }
+                        }
+                    },
+                    "304": {
+                        "description": "Not Modified"
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/server.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/server.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/server.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/server.Problem"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "description": "Updates task using data from body and with id from path param. If some fields of body struct are omitted, they will be overwritten by default values. Task is validated the same way as on create, id may be omitted or equal to id from path. Use PATCH to change only some fields",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Update"
+                ],
+                "summary": "Update task",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Task id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Task data",
+                        "name": "task",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.Task"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag of task or its version in quotes, required if enabled in config",
+                        "name": "If-Match",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "IANA timezone of dates and deadlines, e.g. Europe/Moscow (timezone from config by default)",
+                        "name": "X-Timezone",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Timezone if X-Timezone header is not set",
+                        "name": "tz",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/server.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/server.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/server.Problem"
+                        }
+                    },
+                    "412": {
+                        "description": "Precondition Failed",
+                        "schema": {
+                            "$ref": "#/definitions/server.Problem"
+                        }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
+                            "$ref": "#/definitions/server.Problem"
+                        }
+                    },
+                    "428": {
+                        "description": "Precondition Required",
+                        "schema": {
+                            "$ref": "#/definitions/server.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/server.Problem"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Moves task with id from id path param to trash. Tasks in trash are not listed and can be restored until they are purged after retention period from config",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Delete"
+                ],
+                "summary": "Delete task by id",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Task id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag of task or its version in quotes, required if enabled in config",
+                        "name": "If-Match",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/server.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/server.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/server.Problem"
+                        }
+                    },
+                    "412": {
+                        "description": "Precondition Failed",
+                        "schema": {
+                            "$ref": "#/definitions/server.Problem"
+                        }
+                    },
+                    "428": {
+                        "description": "Precondition Required",
+                        "schema": {
+                            "$ref": "#/definitions/server.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/server.Problem"
+                        }
+                    }
+                }
+            },
+            "patch": {
+                "description": "Updates only fields present in body of task with id from path param.\nWith \"application/merge-patch+json\" (or \"application/json\") body is RFC 7396 JSON Merge Patch: null resets field to default value.\nWith \"application/json-patch+json\" body is RFC 6902 JSON Patch, array of server.PatchOperation with paths /header, /description, /deadline, /done and operations add, replace, remove, test.\nTask is changed only if it still has version which test operations were evaluated against, otherwise 412 is returned.\nOnly changed fields are validated",
+                "consumes": [
+                    "application/merge-patch+json",
+                    "application/json-patch+json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Update"
+                ],
+                "summary": "Patch task",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Task id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Fields to change",
+                        "name": "patch",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.TaskPatch"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag of task or its version in quotes, required if enabled in config",
+                        "name": "If-Match",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "IANA timezone of dates and deadlines, e.g. Europe/Moscow (timezone from config by default)",
+                        "name": "X-Timezone",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Timezone if X-Timezone header is not set",
+                        "name": "tz",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/server.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/server.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/server.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/server.Problem"
+                        }
+                    },
+                    "412": {
+                        "description": "Precondition Failed",
+                        "schema": {
+                            "$ref": "#/definitions/server.Problem"
+                        }
+                    },
+                    "415": {
+                        "description": "Unsupported Media Type",
+                        "schema": {
+                            "$ref": "#/definitions/server.Problem"
+                        }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
+                            "$ref": "#/definitions/server.Problem"
+                        }
+                    },
+                    "428": {
+                        "description": "Precondition Required",
+                        "schema": {
+                            "$ref": "#/definitions/server.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/server.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/tasks/{id}/checklist": {
+            "get": {
+                "description": "Returns checklist items of task with id from path param in order they were added",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Checklist"
+                ],
+                "summary": "Get checklist of task",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Task id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag of cached response",
+                        "name": "If-None-Match",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.ChecklistItem"
+                            }
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "Hash of response"
+                            }
+                        }
+                    },
+                    "304": {
+                        "description": "Not Modified"
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/server.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/server.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/server.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/server.Problem"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Appends item to checklist of task with id from path param and returns id of item. Text is required and at most 500 characters long.\nChanges version of task and is recorded in its history",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Checklist"
+                ],
+                "summary": "Add checklist item",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Task id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Checklist item",
+                        "name": "item",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.ChecklistItem"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag of task or its version in quotes, required if enabled in config",
+                        "name": "If-Match",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "type": "integer"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/server.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/server.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/server.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/server.Problem"
+                        }
+                    },
+                    "412": {
+                        "description": "Precondition Failed",
+                        "schema": {
+                            "$ref": "#/definitions/server.Problem"
+                        }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
+                            "$ref": "#/definitions/server.Problem"
+                        }
+                    },
+                    "428": {
+                        "description": "Precondition Required",
+                        "schema": {
+                            "$ref": "#/definitions/server.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/server.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/tasks/{id}/checklist/{item}": {
+            "put": {
+                "description": "Changes text and status of item with id from item path param in checklist of task with id from id path param, item is validated the same way as on create.\nChanges version of task and is recorded in its history, unless item already has the same text and status",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Checklist"
+                ],
+                "summary": "Update checklist item",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Task id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Checklist item id",
+                        "name": "item",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Checklist item",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.ChecklistItem"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag of task or its version in quotes, required if enabled in config",
+                        "name": "If-Match",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/server.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/server.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/server.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/server.Problem"
+                        }
+                    },
+                    "412": {
+                        "description": "Precondition Failed",
+                        "schema": {
+                            "$ref": "#/definitions/server.Problem"
+                        }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
+                            "$ref": "#/definitions/server.Problem"
+                        }
+                    },
+                    "428": {
+                        "description": "Precondition Required",
+                        "schema": {
+                            "$ref": "#/definitions/server.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/server.Problem"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Removes item with id from item path param from checklist of task with id from id path param.\nChanges version of task and is recorded in its history",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Checklist"
+                ],
+                "summary": "Delete checklist item",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Task id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Checklist item id",
+                        "name": "item",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag of task or its version in quotes, required if enabled in config",
+                        "name": "If-Match",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/server.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/server.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/server.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/server.Problem"
+                        }
+                    },
+                    "412": {
+                        "description": "Precondition Failed",
+                        "schema": {
+                            "$ref": "#/definitions/server.Problem"
+                        }
+                    },
+                    "428": {
+                        "description": "Precondition Required",
+                        "schema": {
+                            "$ref": "#/definitions/server.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/server.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/tasks/{id}/dependencies": {
+            "get": {
+                "description": "Returns tasks which directly block task with id from path param and tasks which are directly blocked by it, ordered by id",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Dependencies"
+                ],
+                "summary": "Get dependencies of task",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Task id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag of cached response",
+                        "name": "If-None-Match",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "IANA timezone of dates and deadlines, e.g. Europe/Moscow (timezone from config by default)",
+                        "name": "X-Timezone",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Timezone if X-Timezone header is not set",
+                        "name": "tz",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/server.DependencyList"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "Hash of response"
+                            }
+                        }
+                    },
+                    "304": {
+                        "description": "Not Modified"
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/server.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/server.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/server.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/server.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/tasks/{id}/dependencies/{blocker}": {
+            "put": {
+                "description": "Makes task with id from id path param blocked by task with id from blocker path param, does nothing if it is already blocked by it.\nFails with conflict if blocker depends on the task. Changes version of task and is recorded in its history",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Dependencies"
+                ],
+                "summary": "Block task by other task",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Task id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Id of blocking task",
+                        "name": "blocker",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag of task or its version in quotes, required if enabled in config",
+                        "name": "If-Match",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/server.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/server.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/server.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/server.Problem"
+                        }
+                    },
+                    "412": {
+                        "description": "Precondition Failed",
+                        "schema": {
+                            "$ref": "#/definitions/server.Problem"
+                        }
+                    },
+                    "428": {
+                        "description": "Precondition Required",
+                        "schema": {
+                            "$ref": "#/definitions/server.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/server.Problem"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Makes task with id from id path param no longer blocked by task with id from blocker path param, does nothing if it is not blocked by it.\nChanges version of task and is recorded in its history",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Dependencies"
+                ],
+                "summary": "Unblock task",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Task id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Id of blocking task",
+                        "name": "blocker",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag of task or its version in quotes, required if enabled in config",
+                        "name": "If-Match",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/server.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/server.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/server.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/server.Problem"
+                        }
+                    },
+                    "412": {
+                        "description": "Precondition Failed",
+                        "schema": {
+                            "$ref": "#/definitions/server.Problem"
+                        }
+                    },
+                    "428": {
+                        "description": "Precondition Required",
+                        "schema": {
+                            "$ref": "#/definitions/server.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/server.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/tasks/{id}/history": {
+            "get": {
+                "description": "Returns changes of task with id from id path param, oldest first: who made them, when, in which request and which fields were changed.\nHistory is kept for tasks in trash and after they are purged. Users task is shared with see its history until it is purged, then only its owner",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "History"
+                ],
+                "summary": "Get history of task",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Task id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag of cached response",
+                        "name": "If-None-Match",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "IANA timezone of times of changes, e.g. Europe/Moscow (timezone from config by default)",
+                        "name": "X-Timezone",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Timezone if X-Timezone header is not set",
+                        "name": "tz",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.TaskEvent"
+                            }
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "Hash of response"
+                            }
+                        }
+                    },
+                    "304": {
+                        "description": "Not Modified"
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/server.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/server.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/server.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/tasks/{id}/restore": {
+            "post": {
+                "description": "Moves task with id from id path param back from trash",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Trash"
+                ],
+                "summary": "Restore deleted task",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Task id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/server.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/server.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/server.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/server.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/tasks/{id}/shares": {
+            "get": {
+                "description": "Returns users task with id from id path param is shared with and their roles, ordered by username. Requires read access to task",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Shares"
+                ],
+                "summary": "Get shares of task",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Task id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag of cached response",
+                        "name": "If-None-Match",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.TaskShare"
+                            }
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "Hash of response"
+                            }
+                        }
+                    },
+                    "304": {
+                        "description": "Not Modified"
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/server.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/server.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/server.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/server.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/server.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/tasks/{id}/shares/{username}": {
+            "put": {
+                "description": "Grants user with username from path param role viewer or editor on task with id from id path param, replacing role user had before.\nViewer can read task, editor can also change it, its tags, checklist and dependencies. Only owner of task can share it, delete and restore it",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Shares"
+                ],
+                "summary": "Share task with user",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Task id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Username",
+                        "name": "username",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Role of user",
+                        "name": "share",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/server.ShareRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/server.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/server.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/server.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/server.Problem"
+                        }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
+                            "$ref": "#/definitions/server.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/server.Problem"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Revokes role of user with username from path param on task with id from id path param. Only owner of task can do it",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Shares"
+                ],
+                "summary": "Stop sharing task with user",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Task id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Username",
+                        "name": "username",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/server.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/server.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/server.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/server.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/server.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/tasks/{id}/tags": {
+            "get": {
+                "description": "Returns tags attached to task with id from path param ordered by name",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Tags"
+                ],
+                "summary": "Get tags of task",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Task id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag of cached response",
+                        "name": "If-None-Match",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.Tag"
+                            }
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "Hash of response"
+                            }
+                        }
+                    },
+                    "304": {
+                        "description": "Not Modified"
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/server.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/server.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/server.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/server.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/tasks/{id}/tags/{tag}": {
+            "put": {
+                "description": "Attaches tag with id from tag path param to task with id from id path param, does nothing if it is already attached.\nChanges version of task and is recorded in its history",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Tags"
+                ],
+                "summary": "Attach tag to task",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Task id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Tag id",
+                        "name": "tag",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag of task or its version in quotes, required if enabled in config",
+                        "name": "If-Match",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/server.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/server.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/server.Problem"
+                        }
+                    },
+                    "412": {
+                        "description": "Precondition Failed",
+                        "schema": {
+                            "$ref": "#/definitions/server.Problem"
+                        }
+                    },
+                    "428": {
+                        "description": "Precondition Required",
+                        "schema": {
+                            "$ref": "#/definitions/server.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/server.Problem"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Detaches tag with id from tag path param from task with id from id path param, does nothing if it is not attached.\nChanges version of task and is recorded in its history",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Tags"
+                ],
+                "summary": "Detach tag from task",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Task id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Tag id",
+                        "name": "tag",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag of task or its version in quotes, required if enabled in config",
+                        "name": "If-Match",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/server.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/server.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/server.Problem"
+                        }
+                    },
+                    "412": {
+                        "description": "Precondition Failed",
+                        "schema": {
+                            "$ref": "#/definitions/server.Problem"
+                        }
+                    },
+                    "428": {
+                        "description": "Precondition Required",
+                        "schema": {
+                            "$ref": "#/definitions/server.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/server.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/token": {
+            "post": {
+                "description": "Checks username and password and issues access token, which authenticates requests in Authorization header as Bearer token, and refresh token, which is exchanged for new tokens before access token expires",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Users"
+                ],
+                "summary": "Issue token",
+                "parameters": [
+                    {
+                        "description": "Username and password",
+                        "name": "credentials",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.Credentials"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/server.TokenPair"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/server.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/server.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/server.Problem"
+                        }
+                    },
+                    "501": {
+                        "description": "Not Implemented",
+                        "schema": {
+                            "$ref": "#/definitions/server.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/token/refresh": {
+            "post": {
+                "description": "Exchanges refresh token for new access and refresh tokens. User of token must still exist",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Users"
+                ],
+                "summary": "Refresh token",
+                "parameters": [
+                    {
+                        "description": "Refresh token",
+                        "name": "token",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/server.RefreshRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/server.TokenPair"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/server.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/server.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/server.Problem"
+                        }
+                    },
+                    "501": {
+                        "description": "Not Implemented",
+                        "schema": {
+                            "$ref": "#/definitions/server.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/users": {
+            "post": {
+                "description": "Creates user and returns its id. Username is required, unique and consists of 3 to 50 latin letters, digits, dots, dashes or underscores, password must be 8 to 72 bytes long. User authenticates requests to tasks, projects and tags with bearer token from /token or with Basic authorization and sees only own tasks",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Users"
+                ],
+                "summary": "Register user",
+                "parameters": [
+                    {
+                        "description": "Username and password",
+                        "name": "credentials",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.Credentials"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "type": "integer"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/server.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/server.Problem"
+                        }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
+                            "$ref": "#/definitions/server.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/server.Problem"
                         }
                     }
                 }
@@ -317,23 +3730,748 @@ const docTemplate = `{
         }
     },
     "definitions": {
+        "models.APIKey": {
+            "type": "object",
+            "required": [
+                "name",
+                "scopes"
+            ],
+            "properties": {
+                "created_at": {
+                    "type": "string",
+                    "readOnly": true
+                },
+                "id": {
+                    "type": "integer",
+                    "readOnly": true
+                },
+                "key": {
+                    "description": "Returned only on create, server keeps only its hash",
+                    "type": "string",
+                    "readOnly": true
+                },
+                "last_used_at": {
+                    "type": "string",
+                    "readOnly": true
+                },
+                "name": {
+                    "description": "Required, at most 100 characters",
+                    "type": "string",
+                    "maxLength": 100,
+                    "minLength": 1,
+                    "example": "CI bot"
+                },
+                "prefix": {
+                    "description": "The first characters of key, which identify it in lists",
+                    "type": "string",
+                    "readOnly": true,
+                    "example": "tdl_Xq3vB9"
+                },
+                "revoked_at": {
+                    "description": "Revoked keys are kept to show when they were used",
+                    "type": "string",
+                    "readOnly": true
+                },
+                "scopes": {
+                    "description": "Operations allowed with key: tasks:read and tasks:write, at least one is required",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "tasks:read"
+                    ]
+                },
+                "user_id": {
+                    "type": "integer",
+                    "readOnly": true
+                }
+            }
+        },
+        "models.ChecklistItem": {
+            "type": "object",
+            "required": [
+                "text"
+            ],
+            "properties": {
+                "done": {
+                    "type": "boolean"
+                },
+                "id": {
+                    "description": "Assigned by server, must be omitted on create",
+                    "type": "integer",
+                    "readOnly": true
+                },
+                "text": {
+                    "description": "Required, at most 500 characters",
+                    "type": "string",
+                    "maxLength": 500,
+                    "minLength": 1,
+                    "example": "Write tests"
+                }
+            }
+        },
+        "models.Credentials": {
+            "type": "object",
+            "required": [
+                "password",
+                "username"
+            ],
+            "properties": {
+                "password": {
+                    "description": "8 to 72 bytes",
+                    "type": "string",
+                    "maxLength": 72,
+                    "minLength": 8,
+                    "example": "correct horse"
+                },
+                "username": {
+                    "type": "string",
+                    "example": "alice"
+                }
+            }
+        },
+        "models.FieldChange": {
+            "type": "object",
+            "properties": {
+                "field": {
+                    "type": "string",
+                    "example": "done"
+                },
+                "new": {},
+                "old": {}
+            }
+        },
+        "models.Project": {
+            "type": "object",
+            "required": [
+                "name"
+            ],
+            "properties": {
+                "archived_at": {
+                    "description": "Time of archiving, set only for archived projects",
+                    "type": "string",
+                    "readOnly": true
+                },
+                "done_tasks": {
+                    "type": "integer",
+                    "readOnly": true
+                },
+                "id": {
+                    "description": "Assigned by server, must be omitted on create",
+                    "type": "integer",
+                    "readOnly": true
+                },
+                "name": {
+                    "description": "Required, at most 200 characters",
+                    "type": "string",
+                    "maxLength": 200,
+                    "minLength": 1,
+                    "example": "Sprint 42"
+                },
+                "open_tasks": {
+                    "description": "Numbers of not done and done tasks of project, tasks in trash are not counted",
+                    "type": "integer",
+                    "readOnly": true
+                },
+                "owner_id": {
+                    "description": "User who created project and may change it, null for projects created before users were added",
+                    "type": "integer",
+                    "readOnly": true
+                }
+            }
+        },
+        "models.Tag": {
+            "type": "object",
+            "required": [
+                "name"
+            ],
+            "properties": {
+                "color": {
+                    "description": "Optional colour in hex format #rrggbb",
+                    "type": "string",
+                    "example": "#ff8800"
+                },
+                "id": {
+                    "description": "Assigned by server, must be omitted on create",
+                    "type": "integer",
+                    "readOnly": true
+                },
+                "name": {
+                    "description": "Required, at most 50 characters",
+                    "type": "string",
+                    "maxLength": 50,
+                    "minLength": 1,
+                    "example": "work"
+                },
+                "owner_id": {
+                    "description": "User who created tag and may change it, null for tags created before users were added",
+                    "type": "integer",
+                    "readOnly": true
+                }
+            }
+        },
         "models.Task": {
             "type": "object",
+            "required": [
+                "deadline",
+                "header"
+            ],
             "properties": {
+                "archived_at": {
+                    "description": "Time of archiving with project, set only for tasks of archived projects. Archived task can not be changed",
+                    "type": "string",
+                    "readOnly": true
+                },
+                "blocked_by": {
+                    "description": "Ids of tasks which must be done before this one, changed with /tasks/{id}/dependencies",
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    },
+                    "readOnly": true
+                },
+                "checklist": {
+                    "description": "Checklist items of task in order they were added, changed with /tasks/{id}/checklist",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.ChecklistItem"
+                    },
+                    "readOnly": true
+                },
+                "children": {
+                    "description": "Subtasks ordered by id, only with expand=children",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.Task"
+                    },
+                    "readOnly": true
+                },
                 "deadline": {
+                    "description": "Required, can not be in the past. RFC 3339 date-time or date only (YYYY-MM-DD), which means\nthe end of that day in timezone of request. Returned in timezone of request",
+                    "type": "string",
+                    "example": "2024-03-01T23:59:59+03:00"
+                },
+                "deleted_at": {
+                    "description": "Time of moving to trash, set only for tasks in trash",
+                    "type": "string",
+                    "readOnly": true
+                },
+                "description": {
+                    "description": "At most 10000 characters by default (see validation config)",
+                    "type": "string",
+                    "maxLength": 10000
+                },
+                "done": {
+                    "type": "boolean"
+                },
+                "effort": {
+                    "description": "Estimated effort in minutes, null if not estimated",
+                    "type": "integer",
+                    "minimum": 1,
+                    "example": 30
+                },
+                "header": {
+                    "description": "Required, at most 200 characters by default (see validation config)",
+                    "type": "string",
+                    "maxLength": 200,
+                    "minLength": 1
+                },
+                "id": {
+                    "description": "Assigned by server, must be omitted on create",
+                    "type": "integer",
+                    "readOnly": true
+                },
+                "occurrence_of": {
+                    "description": "Id of recurring task, set only for virtual future occurrences of it, which have id 0",
+                    "type": "integer",
+                    "readOnly": true
+                },
+                "owner_id": {
+                    "description": "User who created task, null for tasks created before users were added, which are available to nobody",
+                    "type": "integer",
+                    "readOnly": true
+                },
+                "parent_id": {
+                    "description": "Parent of subtask, null for top level task. Task can not become a subtask of itself or of its subtasks",
+                    "type": "integer",
+                    "example": 1
+                },
+                "priority": {
+                    "description": "From 0 (no priority) to 4 (urgent)",
+                    "type": "integer",
+                    "maximum": 4,
+                    "minimum": 0
+                },
+                "progress": {
+                    "description": "Percentage of done subtasks and checklist items, 100 for done task and 0 for task without them",
+                    "type": "integer",
+                    "maximum": 100,
+                    "minimum": 0,
+                    "readOnly": true
+                },
+                "project_id": {
+                    "description": "Project of task, null if task is not in any project. Task is moved to other project by changing it",
+                    "type": "integer",
+                    "example": 1
+                },
+                "recurrence": {
+                    "description": "RFC 5545 recurrence rule without DTSTART, e.g. FREQ=WEEKLY;BYDAY=MO,FR. Occurrences start at deadline\nin timezone from config. When recurring task is marked done, its next occurrence is created as new task\nwith the rule and the rule is removed from the done task. Empty for task which does not recur",
+                    "type": "string",
+                    "example": "FREQ=WEEKLY;BYDAY=MO"
+                },
+                "role": {
+                    "description": "Role of user who requested task: owner, or editor or viewer of task shared with user",
+                    "type": "string",
+                    "enum": [
+                        "owner",
+                        "editor",
+                        "viewer"
+                    ],
+                    "readOnly": true
+                },
+                "tags": {
+                    "description": "Tags attached to task ordered by name, changed with /tasks/{id}/tags",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.Tag"
+                    },
+                    "readOnly": true
+                },
+                "version": {
+                    "description": "Incremented on every change, returned as ETag",
+                    "type": "integer",
+                    "readOnly": true
+                }
+            }
+        },
+        "models.TaskEvent": {
+            "type": "object",
+            "properties": {
+                "action": {
+                    "type": "string",
+                    "enum": [
+                        "create",
+                        "update",
+                        "delete",
+                        "restore",
+                        "purge",
+                        "archive",
+                        "unarchive"
+                    ]
+                },
+                "actor": {
+                    "description": "User who made the change, \"anonymous\" for unauthenticated requests and \"system\" for background jobs",
+                    "type": "string"
+                },
+                "changes": {
+                    "description": "Fields which were changed, computed from OldData and NewData",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.FieldChange"
+                    }
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "request_id": {
+                    "description": "Id of request which made the change, sent in X-Request-Id header",
                     "type": "string"
                 },
+                "task_id": {
+                    "type": "integer"
+                }
+            }
+        },
+        "models.TaskPatch": {
+            "type": "object",
+            "properties": {
+                "deadline": {
+                    "type": "string",
+                    "example": "2024-03-01"
+                },
                 "description": {
                     "type": "string"
                 },
                 "done": {
                     "type": "boolean"
                 },
+                "effort": {
+                    "description": "0 removes estimate",
+                    "type": "integer"
+                },
                 "header": {
                     "type": "string"
                 },
-                "id": {
+                "parent_id": {
+                    "description": "0 makes task a top level task",
+                    "type": "integer"
+                },
+                "priority": {
+                    "type": "integer"
+                },
+                "project_id": {
+                    "description": "0 removes task from its project",
                     "type": "integer"
+                },
+                "recurrence": {
+                    "description": "Empty string makes task not recurring",
+                    "type": "string"
+                }
+            }
+        },
+        "models.TaskShare": {
+            "type": "object",
+            "required": [
+                "role"
+            ],
+            "properties": {
+                "created_at": {
+                    "type": "string",
+                    "readOnly": true
+                },
+                "role": {
+                    "description": "Viewer can read task, editor can also change it",
+                    "type": "string",
+                    "enum": [
+                        "viewer",
+                        "editor"
+                    ],
+                    "example": "editor"
+                },
+                "task_id": {
+                    "type": "integer",
+                    "readOnly": true
+                },
+                "user_id": {
+                    "type": "integer",
+                    "readOnly": true
+                },
+                "username": {
+                    "type": "string",
+                    "readOnly": true,
+                    "example": "bob"
+                }
+            }
+        },
+        "models.User": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string",
+                    "readOnly": true
+                },
+                "id": {
+                    "type": "integer",
+                    "readOnly": true
+                },
+                "username": {
+                    "description": "Unique, 3 to 50 latin letters, digits, dots, dashes and underscores",
+                    "type": "string",
+                    "example": "alice"
+                }
+            }
+        },
+        "server.AgendaTask": {
+            "type": "object",
+            "required": [
+                "deadline",
+                "header"
+            ],
+            "properties": {
+                "archived_at": {
+                    "description": "Time of archiving with project, set only for tasks of archived projects. Archived task can not be changed",
+                    "type": "string",
+                    "readOnly": true
+                },
+                "blocked_by": {
+                    "description": "Ids of tasks which must be done before this one, changed with /tasks/{id}/dependencies",
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    },
+                    "readOnly": true
+                },
+                "checklist": {
+                    "description": "Checklist items of task in order they were added, changed with /tasks/{id}/checklist",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.ChecklistItem"
+                    },
+                    "readOnly": true
+                },
+                "children": {
+                    "description": "Subtasks ordered by id, only with expand=children",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.Task"
+                    },
+                    "readOnly": true
+                },
+                "deadline": {
+                    "description": "Required, can not be in the past. RFC 3339 date-time or date only (YYYY-MM-DD), which means\nthe end of that day in timezone of request. Returned in timezone of request",
+                    "type": "string",
+                    "example": "2024-03-01T23:59:59+03:00"
+                },
+                "deleted_at": {
+                    "description": "Time of moving to trash, set only for tasks in trash",
+                    "type": "string",
+                    "readOnly": true
+                },
+                "description": {
+                    "description": "At most 10000 characters by default (see validation config)",
+                    "type": "string",
+                    "maxLength": 10000
+                },
+                "done": {
+                    "type": "boolean"
+                },
+                "effort": {
+                    "description": "Estimated effort in minutes, null if not estimated",
+                    "type": "integer",
+                    "minimum": 1,
+                    "example": 30
+                },
+                "header": {
+                    "description": "Required, at most 200 characters by default (see validation config)",
+                    "type": "string",
+                    "maxLength": 200,
+                    "minLength": 1
+                },
+                "id": {
+                    "description": "Assigned by server, must be omitted on create",
+                    "type": "integer",
+                    "readOnly": true
+                },
+                "occurrence_of": {
+                    "description": "Id of recurring task, set only for virtual future occurrences of it, which have id 0",
+                    "type": "integer",
+                    "readOnly": true
+                },
+                "owner_id": {
+                    "description": "User who created task, null for tasks created before users were added, which are available to nobody",
+                    "type": "integer",
+                    "readOnly": true
+                },
+                "parent_id": {
+                    "description": "Parent of subtask, null for top level task. Task can not become a subtask of itself or of its subtasks",
+                    "type": "integer",
+                    "example": 1
+                },
+                "priority": {
+                    "description": "From 0 (no priority) to 4 (urgent)",
+                    "type": "integer",
+                    "maximum": 4,
+                    "minimum": 0
+                },
+                "progress": {
+                    "description": "Percentage of done subtasks and checklist items, 100 for done task and 0 for task without them",
+                    "type": "integer",
+                    "maximum": 100,
+                    "minimum": 0,
+                    "readOnly": true
+                },
+                "project_id": {
+                    "description": "Project of task, null if task is not in any project. Task is moved to other project by changing it",
+                    "type": "integer",
+                    "example": 1
+                },
+                "recurrence": {
+                    "description": "RFC 5545 recurrence rule without DTSTART, e.g. FREQ=WEEKLY;BYDAY=MO,FR. Occurrences start at deadline\nin timezone from config. When recurring task is marked done, its next occurrence is created as new task\nwith the rule and the rule is removed from the done task. Empty for task which does not recur",
+                    "type": "string",
+                    "example": "FREQ=WEEKLY;BYDAY=MO"
+                },
+                "role": {
+                    "description": "Role of user who requested task: owner, or editor or viewer of task shared with user",
+                    "type": "string",
+                    "enum": [
+                        "owner",
+                        "editor",
+                        "viewer"
+                    ],
+                    "readOnly": true
+                },
+                "score": {
+                    "description": "Higher score means that task should be done earlier",
+                    "type": "number",
+                    "example": 4.5
+                },
+                "tags": {
+                    "description": "Tags attached to task ordered by name, changed with /tasks/{id}/tags",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.Tag"
+                    },
+                    "readOnly": true
+                },
+                "version": {
+                    "description": "Incremented on every change, returned as ETag",
+                    "type": "integer",
+                    "readOnly": true
+                }
+            }
+        },
+        "server.CalendarDay": {
+            "type": "object",
+            "properties": {
+                "date": {
+                    "type": "string",
+                    "example": "2023-12-04"
+                },
+                "tasks": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.Task"
+                    }
+                }
+            }
+        },
+        "server.DependencyList": {
+            "type": "object",
+            "properties": {
+                "blocked_by": {
+                    "description": "Tasks which must be done before the task",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.Task"
+                    }
+                },
+                "blocking": {
+                    "description": "Tasks which wait for the task",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.Task"
+                    }
+                }
+            }
+        },
+        "server.FieldError": {
+            "type": "object",
+            "properties": {
+                "field": {
+                    "type": "string",
+                    "example": "deadline"
+                },
+                "message": {
+                    "type": "string",
+                    "example": "task deadline can not be in the past"
+                }
+            }
+        },
+        "server.Health": {
+            "type": "object",
+            "properties": {
+                "status": {
+                    "type": "string",
+                    "example": "ok"
+                }
+            }
+        },
+        "server.Problem": {
+            "type": "object",
+            "properties": {
+                "detail": {
+                    "type": "string",
+                    "example": "deadline: task deadline can not be in the past"
+                },
+                "errors": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/server.FieldError"
+                    }
+                },
+                "instance": {
+                    "type": "string",
+                    "example": "/tasks/1"
+                },
+                "status": {
+                    "type": "integer",
+                    "example": 422
+                },
+                "title": {
+                    "type": "string",
+                    "example": "Unprocessable Entity"
+                },
+                "type": {
+                    "type": "string",
+                    "example": "/problems/validation"
+                }
+            }
+        },
+        "server.RefreshRequest": {
+            "type": "object",
+            "required": [
+                "refresh_token"
+            ],
+            "properties": {
+                "refresh_token": {
+                    "type": "string"
+                }
+            }
+        },
+        "server.ShareRequest": {
+            "type": "object",
+            "properties": {
+                "role": {
+                    "type": "string",
+                    "enum": [
+                        "viewer",
+                        "editor"
+                    ],
+                    "example": "viewer"
+                }
+            }
+        },
+        "server.TaskPage": {
+            "type": "object",
+            "properties": {
+                "has_more": {
+                    "type": "boolean"
+                },
+                "items": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.Task"
+                    }
+                },
+                "next_cursor": {
+                    "type": "string",
+                    "example": "eyJpZCI6MjB9"
+                },
+                "page_size": {
+                    "type": "integer",
+                    "example": 20
+                },
+                "prev_cursor": {
+                    "type": "string",
+                    "example": "eyJpZCI6MSwiYiI6dHJ1ZX0"
+                },
+                "total": {
+                    "type": "integer",
+                    "example": 42
+                }
+            }
+        },
+        "server.TokenPair": {
+            "type": "object",
+            "properties": {
+                "access_token": {
+                    "type": "string"
+                },
+                "expires_in": {
+                    "description": "Lifetime of access token in seconds",
+                    "type": "integer",
+                    "example": 900
+                },
+                "refresh_token": {
+                    "type": "string"
+                },
+                "token_type": {
+                    "type": "string",
+                    "example": "Bearer"
                 }
             }
         }
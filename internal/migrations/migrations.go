@@ -0,0 +1,263 @@
+package migrations
+
+import (
+	"context"
+	"embed"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+//go:embed sql/postgres/*.sql sql/sqlite/*.sql
+var files embed.FS
+
+// Key of the postgres advisory lock held while migrations are applied,
+// so that several replicas starting at once don't race each other
+const lockKey = 2023120401
+
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+type Status struct {
+	Version   int
+	Name      string
+	Applied   bool
+	AppliedAt *time.Time
+}
+
+type Migrator struct {
+	Db         *sqlx.DB
+	Migrations []Migration
+}
+
+// New returns migrator with migrations for db's driver (postgres or sqlite)
+func New(db *sqlx.DB) (*Migrator, error) {
+	var dir string
+	switch db.DriverName() {
+	case "postgres":
+		dir = "sql/postgres"
+	case "sqlite":
+		dir = "sql/sqlite"
+	default:
+		return nil, fmt.Errorf("migrations are not supported for driver %q", db.DriverName())
+	}
+
+	migrations, err := load(files, dir)
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{
+		Db:         db,
+		Migrations: migrations,
+	}, nil
+}
+
+// Up applies all pending migrations and returns the ones that were applied
+func (m *Migrator) Up() ([]Migration, error) {
+	var applied []Migration
+	err := m.withLock(func(conn *sqlx.Conn) error {
+		versions, err := appliedVersions(conn)
+		if err != nil {
+			return err
+		}
+		for _, mg := range m.Migrations {
+			if _, ok := versions[mg.Version]; ok {
+				continue
+			}
+			if err := apply(conn, mg.Up,
+				m.Db.Rebind(`insert into schema_migrations (version, name) values (?, ?)`), mg.Version, mg.Name); err != nil {
+				return fmt.Errorf("migration %04d_%s up: %w", mg.Version, mg.Name, err)
+			}
+			applied = append(applied, mg)
+		}
+		return nil
+	})
+	return applied, err
+}
+
+// Down rolls back last n applied migrations and returns the ones that were rolled back
+func (m *Migrator) Down(n int) ([]Migration, error) {
+	var reverted []Migration
+	err := m.withLock(func(conn *sqlx.Conn) error {
+		versions, err := appliedVersions(conn)
+		if err != nil {
+			return err
+		}
+		for i := len(m.Migrations) - 1; i >= 0 && len(reverted) < n; i-- {
+			mg := m.Migrations[i]
+			if _, ok := versions[mg.Version]; !ok {
+				continue
+			}
+			if err := apply(conn, mg.Down,
+				m.Db.Rebind(`delete from schema_migrations where version = ?`), mg.Version); err != nil {
+				return fmt.Errorf("migration %04d_%s down: %w", mg.Version, mg.Name, err)
+			}
+			reverted = append(reverted, mg)
+		}
+		return nil
+	})
+	return reverted, err
+}
+
+// Status returns every known migration with information on whether it was applied
+func (m *Migrator) Status() ([]Status, error) {
+	var statuses []Status
+	err := m.withLock(func(conn *sqlx.Conn) error {
+		versions, err := appliedVersions(conn)
+		if err != nil {
+			return err
+		}
+		for _, mg := range m.Migrations {
+			st := Status{
+				Version: mg.Version,
+				Name:    mg.Name,
+			}
+			if at, ok := versions[mg.Version]; ok {
+				st.Applied = true
+				st.AppliedAt = &at
+			}
+			statuses = append(statuses, st)
+		}
+		return nil
+	})
+	return statuses, err
+}
+
+// Runs fn on a single connection holding the advisory lock.
+// Sqlite database is a local file and is never shared between replicas, so it is not locked.
+// Bookkeeping table is created beforehand if it does not exist yet
+func (m *Migrator) withLock(fn func(conn *sqlx.Conn) error) error {
+	ctx := context.Background()
+	conn, err := m.Db.Connx(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	bookkeeping := `create table if not exists schema_migrations(
+		version integer PRIMARY KEY NOT NULL,
+		name text NOT NULL,
+		applied_at timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP
+	)`
+	if m.Db.DriverName() == "postgres" {
+		if _, err = conn.ExecContext(ctx, `select pg_advisory_lock($1)`, lockKey); err != nil {
+			return err
+		}
+		defer conn.ExecContext(ctx, `select pg_advisory_unlock($1)`, lockKey)
+
+		bookkeeping = `create table if not exists schema_migrations(
+			version int8 PRIMARY KEY NOT NULL,
+			name text NOT NULL,
+			applied_at timestamptz NOT NULL DEFAULT now()
+		)`
+	}
+
+	if _, err = conn.ExecContext(ctx, bookkeeping); err != nil {
+		return err
+	}
+	return fn(conn)
+}
+
+func appliedVersions(conn *sqlx.Conn) (map[int]time.Time, error) {
+	var rows []struct {
+		Version   int       `db:"version"`
+		AppliedAt time.Time `db:"applied_at"`
+	}
+	if err := conn.SelectContext(context.Background(), &rows,
+		`select version, applied_at from schema_migrations`); err != nil {
+		return nil, err
+	}
+	versions := make(map[int]time.Time, len(rows))
+	for _, r := range rows {
+		versions[r.Version] = r.AppliedAt
+	}
+	return versions, nil
+}
+
+// Executes migration script and bookkeeping statement in one transaction
+func apply(conn *sqlx.Conn, script, bookkeeping string, args ...interface{}) error {
+	ctx := context.Background()
+	tx, err := conn.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if _, err = tx.ExecContext(ctx, script); err != nil {
+		tx.Rollback()
+		return err
+	}
+	if _, err = tx.ExecContext(ctx, bookkeeping, args...); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// Reads migrations from dir. Files must be named as {version}_{name}.up.sql / {version}_{name}.down.sql
+func load(fsys fs.FS, dir string) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[int]*Migration)
+	for _, e := range entries {
+		fileName := e.Name()
+		var direction string
+		switch {
+		case strings.HasSuffix(fileName, ".up.sql"):
+			direction = "up"
+		case strings.HasSuffix(fileName, ".down.sql"):
+			direction = "down"
+		default:
+			continue
+		}
+
+		base := strings.TrimSuffix(fileName, "."+direction+".sql")
+		versionStr, name, found := strings.Cut(base, "_")
+		if !found {
+			return nil, fmt.Errorf("invalid migration file name: %s", fileName)
+		}
+		version, err := strconv.Atoi(versionStr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid migration version in %s: %w", fileName, err)
+		}
+
+		body, err := fs.ReadFile(fsys, path.Join(dir, fileName))
+		if err != nil {
+			return nil, err
+		}
+
+		mg, ok := byVersion[version]
+		if !ok {
+			mg = &Migration{Version: version, Name: name}
+			byVersion[version] = mg
+		}
+		if direction == "up" {
+			mg.Up = string(body)
+		} else {
+			mg.Down = string(body)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, mg := range byVersion {
+		if mg.Up == "" || mg.Down == "" {
+			return nil, fmt.Errorf("migration %04d_%s must have both up and down scripts", mg.Version, mg.Name)
+		}
+		migrations = append(migrations, *mg)
+	}
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+	return migrations, nil
+}
@@ -0,0 +1,7 @@
+create table if not exists tasks(
+	id serial4 PRIMARY KEY NOT NULL,
+	header text,
+	description text,
+	deadline date,
+	done bool
+);
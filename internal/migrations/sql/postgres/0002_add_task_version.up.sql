@@ -0,0 +1 @@
+alter table tasks add column if not exists version int4 NOT NULL DEFAULT 1;
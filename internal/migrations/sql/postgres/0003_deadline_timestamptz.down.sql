@@ -0,0 +1 @@
+alter table tasks alter column deadline type date using (deadline at time zone 'UTC')::date;
@@ -0,0 +1,2 @@
+-- Existing date-only deadlines become end of that day in UTC
+alter table tasks alter column deadline type timestamptz using (deadline + time '23:59:59') at time zone 'UTC';
@@ -0,0 +1,11 @@
+create table if not exists task_events(
+	id bigserial PRIMARY KEY NOT NULL,
+	task_id int4 NOT NULL,
+	action text NOT NULL,
+	old_data jsonb,
+	new_data jsonb,
+	actor text NOT NULL,
+	request_id text NOT NULL DEFAULT '',
+	created_at timestamptz NOT NULL
+);
+create index if not exists task_events_task_id_idx on task_events (task_id, id);
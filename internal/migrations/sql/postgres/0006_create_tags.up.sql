@@ -0,0 +1,11 @@
+create table if not exists tags(
+	id serial4 PRIMARY KEY NOT NULL,
+	name text NOT NULL UNIQUE,
+	color text NOT NULL DEFAULT ''
+);
+create table if not exists task_tags(
+	task_id int4 NOT NULL REFERENCES tasks (id) ON DELETE CASCADE,
+	tag_id int4 NOT NULL REFERENCES tags (id) ON DELETE CASCADE,
+	PRIMARY KEY (task_id, tag_id)
+);
+create index if not exists task_tags_tag_id_idx on task_tags (tag_id);
@@ -0,0 +1,8 @@
+create table if not exists projects(
+	id serial4 PRIMARY KEY NOT NULL,
+	name text NOT NULL,
+	archived_at timestamptz
+);
+alter table tasks add column if not exists project_id int4 REFERENCES projects (id);
+alter table tasks add column if not exists archived_at timestamptz;
+create index if not exists tasks_project_id_idx on tasks (project_id);
@@ -0,0 +1,3 @@
+drop table if exists checklist_items;
+drop index if exists tasks_parent_id_idx;
+alter table tasks drop column if exists parent_id;
@@ -0,0 +1,9 @@
+alter table tasks add column if not exists parent_id int4 REFERENCES tasks (id);
+create index if not exists tasks_parent_id_idx on tasks (parent_id);
+create table if not exists checklist_items(
+	id serial4 PRIMARY KEY NOT NULL,
+	task_id int4 NOT NULL REFERENCES tasks (id) ON DELETE CASCADE,
+	text text NOT NULL,
+	done bool NOT NULL DEFAULT false
+);
+create index if not exists checklist_items_task_id_idx on checklist_items (task_id);
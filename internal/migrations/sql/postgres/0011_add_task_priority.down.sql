@@ -0,0 +1,2 @@
+alter table tasks drop column if exists effort;
+alter table tasks drop column if exists priority;
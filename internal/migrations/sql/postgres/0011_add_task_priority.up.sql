@@ -0,0 +1,2 @@
+alter table tasks add column if not exists priority int4 NOT NULL DEFAULT 0;
+alter table tasks add column if not exists effort int4;
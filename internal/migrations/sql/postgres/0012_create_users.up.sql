@@ -0,0 +1,8 @@
+create table if not exists users(
+	id serial4 PRIMARY KEY NOT NULL,
+	username text NOT NULL UNIQUE,
+	password_hash text NOT NULL,
+	created_at timestamptz NOT NULL
+);
+alter table tasks add column if not exists owner_id int4 REFERENCES users (id);
+create index if not exists tasks_owner_id_idx on tasks (owner_id);
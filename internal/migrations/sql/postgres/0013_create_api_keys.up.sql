@@ -0,0 +1,13 @@
+create table if not exists api_keys(
+	id serial4 PRIMARY KEY NOT NULL,
+	user_id int4 NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+	name text NOT NULL,
+	prefix text NOT NULL,
+	key_hash text NOT NULL UNIQUE,
+	-- Separated by spaces
+	scopes text NOT NULL,
+	created_at timestamptz NOT NULL,
+	last_used_at timestamptz,
+	revoked_at timestamptz
+);
+create index if not exists api_keys_user_id_idx on api_keys (user_id);
@@ -0,0 +1,2 @@
+drop index if exists task_shares_user_id_idx;
+drop table if exists task_shares;
@@ -0,0 +1,8 @@
+create table if not exists task_shares(
+	task_id int4 NOT NULL REFERENCES tasks (id) ON DELETE CASCADE,
+	user_id int4 NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+	role text NOT NULL CHECK (role in ('viewer', 'editor')),
+	created_at timestamptz NOT NULL,
+	PRIMARY KEY (task_id, user_id)
+);
+create index if not exists task_shares_user_id_idx on task_shares (user_id);
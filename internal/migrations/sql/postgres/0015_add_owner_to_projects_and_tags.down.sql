@@ -0,0 +1,2 @@
+alter table tags drop column if exists owner_id;
+alter table projects drop column if exists owner_id;
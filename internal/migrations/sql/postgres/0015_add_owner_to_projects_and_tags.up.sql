@@ -0,0 +1,3 @@
+-- Projects and tags are listed to all users, but only their creator changes them
+alter table projects add column if not exists owner_id int4 REFERENCES users (id);
+alter table tags add column if not exists owner_id int4 REFERENCES users (id);
@@ -0,0 +1,7 @@
+create table if not exists tasks(
+	id integer PRIMARY KEY AUTOINCREMENT NOT NULL,
+	header text,
+	description text,
+	deadline date,
+	done bool
+);
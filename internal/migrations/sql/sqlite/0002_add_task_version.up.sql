@@ -0,0 +1 @@
+alter table tasks add column version integer NOT NULL DEFAULT 1;
@@ -0,0 +1,12 @@
+create table tasks_new(
+	id integer PRIMARY KEY AUTOINCREMENT NOT NULL,
+	header text,
+	description text,
+	deadline date,
+	done bool,
+	version integer NOT NULL DEFAULT 1
+);
+insert into tasks_new (id, header, description, deadline, done, version)
+	select id, header, description, substr(deadline, 1, 10), done, version from tasks;
+drop table tasks;
+alter table tasks_new rename to tasks;
@@ -0,0 +1,14 @@
+-- Column type can not be changed in sqlite, so table is recreated.
+-- Deadlines are stored as UTC text of fixed width to be comparable, date-only deadlines become end of that day
+create table tasks_new(
+	id integer PRIMARY KEY AUTOINCREMENT NOT NULL,
+	header text,
+	description text,
+	deadline timestamp,
+	done bool,
+	version integer NOT NULL DEFAULT 1
+);
+insert into tasks_new (id, header, description, deadline, done, version)
+	select id, header, description, deadline || ' 23:59:59.000000Z', done, version from tasks;
+drop table tasks;
+alter table tasks_new rename to tasks;
@@ -0,0 +1 @@
+alter table tasks add column deleted_at timestamp;
@@ -0,0 +1,11 @@
+create table if not exists task_events(
+	id integer PRIMARY KEY AUTOINCREMENT NOT NULL,
+	task_id integer NOT NULL,
+	action text NOT NULL,
+	old_data text,
+	new_data text,
+	actor text NOT NULL,
+	request_id text NOT NULL DEFAULT '',
+	created_at timestamp NOT NULL
+);
+create index if not exists task_events_task_id_idx on task_events (task_id, id);
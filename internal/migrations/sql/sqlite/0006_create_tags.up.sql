@@ -0,0 +1,11 @@
+create table if not exists tags(
+	id integer PRIMARY KEY AUTOINCREMENT NOT NULL,
+	name text NOT NULL UNIQUE,
+	color text NOT NULL DEFAULT ''
+);
+create table if not exists task_tags(
+	task_id integer NOT NULL REFERENCES tasks (id) ON DELETE CASCADE,
+	tag_id integer NOT NULL REFERENCES tags (id) ON DELETE CASCADE,
+	PRIMARY KEY (task_id, tag_id)
+);
+create index if not exists task_tags_tag_id_idx on task_tags (tag_id);
@@ -0,0 +1,10 @@
+create table if not exists projects(
+	id integer PRIMARY KEY AUTOINCREMENT NOT NULL,
+	name text NOT NULL,
+	archived_at timestamp
+);
+-- Column with foreign key can not be dropped in sqlite, and recreating tasks table would cascade to task_tags,
+-- so existence of project is checked only by service
+alter table tasks add column project_id integer;
+alter table tasks add column archived_at timestamp;
+create index if not exists tasks_project_id_idx on tasks (project_id);
@@ -0,0 +1,10 @@
+-- Column with foreign key can not be dropped in sqlite, so existence of parent is checked only by service
+alter table tasks add column parent_id integer;
+create index if not exists tasks_parent_id_idx on tasks (parent_id);
+create table if not exists checklist_items(
+	id integer PRIMARY KEY AUTOINCREMENT NOT NULL,
+	task_id integer NOT NULL REFERENCES tasks (id) ON DELETE CASCADE,
+	text text NOT NULL,
+	done bool NOT NULL DEFAULT false
+);
+create index if not exists checklist_items_task_id_idx on checklist_items (task_id);
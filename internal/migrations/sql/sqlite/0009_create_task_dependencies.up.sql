@@ -0,0 +1,7 @@
+create table if not exists task_dependencies(
+	task_id integer NOT NULL REFERENCES tasks (id) ON DELETE CASCADE,
+	blocker_id integer NOT NULL REFERENCES tasks (id) ON DELETE CASCADE,
+	PRIMARY KEY (task_id, blocker_id),
+	CHECK (task_id <> blocker_id)
+);
+create index if not exists task_dependencies_blocker_id_idx on task_dependencies (blocker_id);
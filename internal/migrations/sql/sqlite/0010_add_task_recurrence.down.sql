@@ -0,0 +1 @@
+alter table tasks drop column recurrence;
@@ -0,0 +1 @@
+alter table tasks add column recurrence text NOT NULL DEFAULT '';
@@ -0,0 +1,2 @@
+alter table tasks drop column effort;
+alter table tasks drop column priority;
@@ -0,0 +1,2 @@
+alter table tasks add column priority integer NOT NULL DEFAULT 0;
+alter table tasks add column effort integer;
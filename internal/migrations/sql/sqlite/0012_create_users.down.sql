@@ -0,0 +1,3 @@
+drop index if exists tasks_owner_id_idx;
+alter table tasks drop column owner_id;
+drop table if exists users;
@@ -0,0 +1,9 @@
+create table if not exists users(
+	id integer PRIMARY KEY AUTOINCREMENT NOT NULL,
+	username text NOT NULL UNIQUE,
+	password_hash text NOT NULL,
+	created_at timestamp NOT NULL
+);
+-- Column with foreign key can not be dropped in sqlite, so owner is set only by service to authenticated user
+alter table tasks add column owner_id integer;
+create index if not exists tasks_owner_id_idx on tasks (owner_id);
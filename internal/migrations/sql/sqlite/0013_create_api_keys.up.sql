@@ -0,0 +1,13 @@
+create table if not exists api_keys(
+	id integer PRIMARY KEY AUTOINCREMENT NOT NULL,
+	user_id integer NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+	name text NOT NULL,
+	prefix text NOT NULL,
+	key_hash text NOT NULL UNIQUE,
+	-- Separated by spaces
+	scopes text NOT NULL,
+	created_at timestamp NOT NULL,
+	last_used_at timestamp,
+	revoked_at timestamp
+);
+create index if not exists api_keys_user_id_idx on api_keys (user_id);
@@ -0,0 +1,2 @@
+alter table tags drop column owner_id;
+alter table projects drop column owner_id;
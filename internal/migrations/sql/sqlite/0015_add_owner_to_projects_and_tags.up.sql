@@ -0,0 +1,3 @@
+-- Projects and tags are listed to all users, but only their creator changes them
+alter table projects add column owner_id integer;
+alter table tags add column owner_id integer;
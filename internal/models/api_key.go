@@ -0,0 +1,24 @@
+package models
+
+import "time"
+
+// APIKey authenticates requests of service clients, e.g. CI bots, on behalf of user who created it
+type APIKey struct {
+	Id     int `json:"id" readonly:"true"`
+	UserId int `json:"user_id" db:"user_id" readonly:"true"`
+	// Required, at most 100 characters
+	Name string `json:"name" validate:"required" minLength:"1" maxLength:"100" example:"CI bot"`
+	// Operations allowed with key: tasks:read and tasks:write, at least one is required
+	Scopes []string `json:"scopes" db:"-" validate:"required" example:"tasks:read"`
+	// The first characters of key, which identify it in lists
+	Prefix string `json:"prefix" readonly:"true" example:"tdl_Xq3vB9"`
+	// Returned only on create, server keeps only its hash
+	Key     string `json:"key,omitempty" db:"-" readonly:"true"`
+	KeyHash string `json:"-" db:"key_hash"`
+	// Username of user, set only when key authenticates request
+	Username   string     `json:"-" db:"username"`
+	CreatedAt  time.Time  `json:"created_at" db:"created_at" readonly:"true"`
+	LastUsedAt *time.Time `json:"last_used_at" db:"last_used_at" readonly:"true"`
+	// Revoked keys are kept to show when they were used
+	RevokedAt *time.Time `json:"revoked_at,omitempty" db:"revoked_at" readonly:"true"`
+}
@@ -0,0 +1,11 @@
+package models
+
+// ChecklistItem is a step of task which is too small to be a subtask
+type ChecklistItem struct {
+	// Assigned by server, must be omitted on create
+	Id     int `json:"id" readonly:"true"`
+	TaskId int `json:"-" db:"task_id"`
+	// Required, at most 500 characters
+	Text string `json:"text" validate:"required" minLength:"1" maxLength:"500" example:"Write tests"`
+	Done bool   `json:"done"`
+}
@@ -0,0 +1,18 @@
+package models
+
+import "time"
+
+// Project is a named list of tasks
+type Project struct {
+	// Assigned by server, must be omitted on create
+	Id int `json:"id" readonly:"true"`
+	// Required, at most 200 characters
+	Name string `json:"name" validate:"required" minLength:"1" maxLength:"200" example:"Sprint 42"`
+	// Time of archiving, set only for archived projects
+	ArchivedAt *time.Time `json:"archived_at,omitempty" db:"archived_at" readonly:"true"`
+	// User who created project and may change it, null for projects created before users were added
+	OwnerId *int `json:"owner_id" db:"owner_id" readonly:"true"`
+	// Numbers of not done and done tasks of project, tasks in trash are not counted
+	OpenTasks int `json:"open_tasks" db:"open_tasks" readonly:"true"`
+	DoneTasks int `json:"done_tasks" db:"done_tasks" readonly:"true"`
+}
@@ -0,0 +1,13 @@
+package models
+
+// Tag is a label of tasks, its name is unique
+type Tag struct {
+	// Assigned by server, must be omitted on create
+	Id int `json:"id" readonly:"true"`
+	// Required, at most 50 characters
+	Name string `json:"name" validate:"required" minLength:"1" maxLength:"50" example:"work"`
+	// Optional colour in hex format #rrggbb
+	Color string `json:"color" example:"#ff8800"`
+	// User who created tag and may change it, null for tags created before users were added
+	OwnerId *int `json:"owner_id" db:"owner_id" readonly:"true"`
+}
@@ -3,9 +3,65 @@ package models
 import "time"
 
 type Task struct {
-	Id          int       `json:"id"`
-	Header      string    `json:"header"`
-	Description string    `json:"description"`
-	Deadline    time.Time `json:"deadline"`
-	Done        bool      `json:"done"`
+	// Assigned by server, must be omitted on create
+	Id int `json:"id" readonly:"true"`
+	// Required, at most 200 characters by default (see validation config)
+	Header string `json:"header" validate:"required" minLength:"1" maxLength:"200"`
+	// At most 10000 characters by default (see validation config)
+	Description string `json:"description" maxLength:"10000"`
+	// Required, can not be in the past. RFC 3339 date-time or date only (YYYY-MM-DD), which means
+	// the end of that day in timezone of request. Returned in timezone of request
+	Deadline time.Time `json:"deadline" validate:"required" example:"2024-03-01T23:59:59+03:00"`
+	Done     bool      `json:"done"`
+	// From 0 (no priority) to 4 (urgent)
+	Priority int `json:"priority" minimum:"0" maximum:"4"`
+	// Estimated effort in minutes, null if not estimated
+	Effort *int `json:"effort" minimum:"1" example:"30"`
+	// Incremented on every change, returned as ETag
+	Version int `json:"version" readonly:"true"`
+	// Time of moving to trash, set only for tasks in trash
+	DeletedAt *time.Time `json:"deleted_at,omitempty" db:"deleted_at" readonly:"true"`
+	// Tags attached to task ordered by name, changed with /tasks/{id}/tags
+	Tags []Tag `json:"tags" db:"-" readonly:"true"`
+	// Project of task, null if task is not in any project. Task is moved to other project by changing it
+	ProjectId *int `json:"project_id" db:"project_id" example:"1"`
+	// Time of archiving with project, set only for tasks of archived projects. Archived task can not be changed
+	ArchivedAt *time.Time `json:"archived_at,omitempty" db:"archived_at" readonly:"true"`
+	// Parent of subtask, null for top level task. Task can not become a subtask of itself or of its subtasks
+	ParentId *int `json:"parent_id" db:"parent_id" example:"1"`
+	// Checklist items of task in order they were added, changed with /tasks/{id}/checklist
+	Checklist []ChecklistItem `json:"checklist" db:"-" readonly:"true"`
+	// Percentage of done subtasks and checklist items, 100 for done task and 0 for task without them
+	Progress int `json:"progress" db:"-" readonly:"true" minimum:"0" maximum:"100"`
+	// Ids of tasks which must be done before this one, changed with /tasks/{id}/dependencies
+	BlockedBy []int `json:"blocked_by" db:"-" readonly:"true"`
+	// RFC 5545 recurrence rule without DTSTART, e.g. FREQ=WEEKLY;BYDAY=MO,FR. Occurrences start at deadline
+	// in timezone from config. When recurring task is marked done, its next occurrence is created as new task
+	// with the rule and the rule is removed from the done task. Empty for task which does not recur
+	Recurrence string `json:"recurrence" db:"recurrence" example:"FREQ=WEEKLY;BYDAY=MO"`
+	// Id of recurring task, set only for virtual future occurrences of it, which have id 0
+	OccurrenceOf *int `json:"occurrence_of,omitempty" db:"-" readonly:"true"`
+	// User who created task, null for tasks created before users were added, which are available to nobody
+	OwnerId *int `json:"owner_id" db:"owner_id" readonly:"true"`
+	// Role of user who requested task: owner, or editor or viewer of task shared with user
+	Role string `json:"role,omitempty" db:"-" readonly:"true" enums:"owner,editor,viewer"`
+	// Subtasks ordered by id, only with expand=children
+	Children []Task `json:"children,omitempty" db:"-" readonly:"true"`
+}
+
+// TaskPatch holds fields of task to change, nil fields are left as is
+type TaskPatch struct {
+	Header      *string    `json:"header"`
+	Description *string    `json:"description"`
+	Deadline    *time.Time `json:"deadline" example:"2024-03-01"`
+	Done        *bool      `json:"done"`
+	Priority    *int       `json:"priority"`
+	// 0 removes estimate
+	Effort *int `json:"effort"`
+	// 0 removes task from its project
+	ProjectId *int `json:"project_id"`
+	// 0 makes task a top level task
+	ParentId *int `json:"parent_id"`
+	// Empty string makes task not recurring
+	Recurrence *string `json:"recurrence"`
 }
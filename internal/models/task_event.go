@@ -0,0 +1,27 @@
+package models
+
+import "time"
+
+// TaskEvent is a recorded change of task
+type TaskEvent struct {
+	Id     int    `json:"id" db:"id"`
+	TaskId int    `json:"task_id" db:"task_id"`
+	Action string `json:"action" db:"action" enums:"create,update,delete,restore,purge,archive,unarchive"`
+	// User who made the change, "anonymous" for unauthenticated requests and "system" for background jobs
+	Actor string `json:"actor" db:"actor"`
+	// Id of request which made the change, sent in X-Request-Id header
+	RequestId string    `json:"request_id" db:"request_id"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	// JSON of task before and after the change, null if task did not exist before or after it
+	OldData []byte `json:"-" db:"old_data"`
+	NewData []byte `json:"-" db:"new_data"`
+	// Fields which were changed, computed from OldData and NewData
+	Changes []FieldChange `json:"changes" db:"-"`
+}
+
+// FieldChange is a change of task field, old or new value is null if task did not exist
+type FieldChange struct {
+	Field string      `json:"field" example:"done"`
+	Old   interface{} `json:"old"`
+	New   interface{} `json:"new"`
+}
@@ -0,0 +1,13 @@
+package models
+
+import "time"
+
+// TaskShare grants user other than owner of task a role on it
+type TaskShare struct {
+	TaskId   int    `json:"task_id" db:"task_id" readonly:"true"`
+	UserId   int    `json:"user_id" db:"user_id" readonly:"true"`
+	Username string `json:"username" db:"username" readonly:"true" example:"bob"`
+	// Viewer can read task, editor can also change it
+	Role      string    `json:"role" db:"role" validate:"required" enums:"viewer,editor" example:"editor"`
+	CreatedAt time.Time `json:"created_at" db:"created_at" readonly:"true"`
+}
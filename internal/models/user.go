@@ -0,0 +1,20 @@
+package models
+
+import "time"
+
+// User owns tasks and authenticates with username and password
+type User struct {
+	Id int `json:"id" readonly:"true"`
+	// Unique, 3 to 50 latin letters, digits, dots, dashes and underscores
+	Username string `json:"username" example:"alice"`
+	// Hash of password, never returned
+	PasswordHash string    `json:"-" db:"password_hash"`
+	CreatedAt    time.Time `json:"created_at" db:"created_at" readonly:"true"`
+}
+
+// Credentials are username and password of user
+type Credentials struct {
+	Username string `json:"username" validate:"required" example:"alice"`
+	// 8 to 72 bytes
+	Password string `json:"password" validate:"required" minLength:"8" maxLength:"72" example:"correct horse"`
+}
@@ -0,0 +1,70 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/O-Tempora/SberIT/internal/models"
+)
+
+// AgendaTask is a task of agenda with its score
+type AgendaTask struct {
+	models.Task
+	// Higher score means that task should be done earlier
+	Score float64 `json:"score" example:"4.5"`
+}
+
+// GetAgenda godoc
+//
+//	@Summary		Get tasks ranked by importance
+//	@Description	Returns tasks matching filters ranked by score, highest first. Score is the sum of priority weight for every priority level,
+//	@Description	share of deadline weight which grows as deadline approaches within horizon and overdue weight for overdue tasks, weights are set in config.
+//	@Description	Filters are the same as of /tasks, but only open tasks are returned unless done is set. Done tasks are scored only by priority
+//	@Tags			GetList
+//	@Accept			json
+//	@Produce		json
+//	@Param			done			query	bool		false	"Task status, false by default"
+//	@Param			deadline_from	query	string		false	"Earliest deadline (YYYY-MM-DD), inclusive"
+//	@Param			deadline_to		query	string		false	"Latest deadline (YYYY-MM-DD), inclusive"
+//	@Param			overdue			query	bool		false	"Task is not done and its deadline has passed"
+//	@Param			q				query	string		false	"Case insensitive text in header or description"
+//	@Param			tag				query	[]string	false	"Names of tags, task has any of them (or all with tag_match=all)"	collectionFormat(multi)
+//	@Param			tag_match		query	string		false	"Whether task has any or all of tags"								Enums(any, all)	default(any)
+//	@Param			If-None-Match	header	string		false	"ETag of cached response"
+//	@Param			X-Timezone		header	string		false	"IANA timezone of dates and deadlines, e.g. Europe/Moscow (timezone from config by default)"
+//	@Param			tz				query	string		false	"Timezone if X-Timezone header is not set"
+//	@Router			/tasks/agenda [get]
+//	@Success		200	{array}		AgendaTask
+//	@Header			200	{string}	ETag	"Hash of response"
+//	@Success		304
+//	@Failure		400	{object}	Problem
+//	@Failure		500	{object}	Problem
+func (s *Server) handleGetAgenda(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	loc, err := s.requestLocation(r)
+	if err != nil {
+		s.respond(w, r, http.StatusBadRequest, nil, err)
+		return
+	}
+	query := r.URL.Query()
+	filter, err := parseTaskFilter(query, loc)
+	if err == nil && query.Get("sort") != "" {
+		err = badParam("sort", errors.New("agenda is always ordered by score"))
+	}
+	if err != nil {
+		s.respond(w, r, http.StatusBadRequest, nil, err)
+		return
+	}
+	ranked, err := s.Service.GetAgenda(r.Context(), filter)
+	if err != nil {
+		s.respond(w, r, http.StatusInternalServerError, nil, err)
+		return
+	}
+	res := make([]AgendaTask, len(ranked))
+	for i, task := range ranked {
+		tasks := []models.Task{task.Task}
+		inLocation(tasks, loc)
+		res[i] = AgendaTask{Task: tasks[0], Score: task.Score}
+	}
+	s.respond(w, r, http.StatusOK, res, nil)
+}
@@ -0,0 +1,116 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/O-Tempora/SberIT/internal/models"
+	"github.com/O-Tempora/SberIT/internal/service"
+	"github.com/go-chi/chi/v5"
+)
+
+// Header with api key, which authenticates request instead of Authorization header
+const apiKeyHeader = "X-API-Key"
+
+// GetAPIKeys godoc
+//
+//	@Summary		Get api keys
+//	@Description	Returns api keys of user, revoked ones too, ordered by id. Keys themselves are not returned, only their prefixes. Requires credentials of user, not api key
+//	@Tags			API keys
+//	@Accept			json
+//	@Produce		json
+//	@Param			If-None-Match	header	string	false	"ETag of cached response"
+//	@Router			/api-keys [get]
+//	@Success		200	{array}		models.APIKey
+//	@Header			200	{string}	ETag	"Hash of response"
+//	@Success		304
+//	@Failure		401	{object}	Problem
+//	@Failure		403	{object}	Problem
+//	@Failure		500	{object}	Problem
+func (s *Server) handleGetAPIKeys(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	keys, err := s.Service.GetAPIKeys(r.Context())
+	if err != nil {
+		s.respond(w, r, http.StatusInternalServerError, nil, err)
+		return
+	}
+	s.respond(w, r, http.StatusOK, keys, nil)
+}
+
+// CreateAPIKey godoc
+//
+//	@Summary		Create api key
+//	@Description	Creates long-lived api key of user for service clients, e.g. CI bots. Key is sent in X-API-Key header and allows operations of its scopes: tasks:read for reading tasks, projects and tags, tasks:write for changing them. Key is returned only in this response. Requires credentials of user, not api key
+//	@Tags			API keys
+//	@Accept			json
+//	@Produce		json
+//	@Param			key	body	models.APIKey	true	"Name and scopes of key"
+//	@Router			/api-keys [post]
+//	@Success		201	{object}	models.APIKey
+//	@Failure		400	{object}	Problem
+//	@Failure		401	{object}	Problem
+//	@Failure		403	{object}	Problem
+//	@Failure		422	{object}	Problem
+//	@Failure		500	{object}	Problem
+func (s *Server) handleCreateAPIKey(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	var req models.APIKey
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.respond(w, r, http.StatusBadRequest, nil, err)
+		return
+	}
+	key, err := s.Service.CreateAPIKey(r.Context(), req)
+	if err != nil {
+		s.respond(w, r, http.StatusInternalServerError, nil, err)
+		return
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	s.respond(w, r, http.StatusCreated, key, nil)
+}
+
+// RevokeAPIKey godoc
+//
+//	@Summary		Revoke api key
+//	@Description	Revokes api key of user with id from path param, so it no longer authenticates requests. Revoked key stays in list of keys. Requires credentials of user, not api key
+//	@Tags			API keys
+//	@Accept			json
+//	@Produce		json
+//	@Param			id	path	int	true	"Key id"
+//	@Router			/api-keys/{id} [delete]
+//	@Success		200
+//	@Failure		400	{object}	Problem
+//	@Failure		401	{object}	Problem
+//	@Failure		403	{object}	Problem
+//	@Failure		404	{object}	Problem
+//	@Failure		500	{object}	Problem
+func (s *Server) handleRevokeAPIKey(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		s.respond(w, r, http.StatusBadRequest, nil, badParam("id", err))
+		return
+	}
+	if err := s.Service.RevokeAPIKey(r.Context(), id); err != nil {
+		s.respond(w, r, http.StatusInternalServerError, nil, err)
+		return
+	}
+	s.respond(w, r, http.StatusOK, nil, nil)
+}
+
+// Rejects requests whose principal is not allowed to do their operation with 403. Safe methods read tasks,
+// other methods write them
+func (s *Server) authorizeScopes(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		scope := service.ScopeTasksWrite
+		switch r.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			scope = service.ScopeTasksRead
+		}
+		if principal, _ := service.PrincipalFrom(r.Context()); !principal.Allows(scope) {
+			s.respond(w, r, http.StatusForbidden, nil, &service.ForbiddenError{Message: "api key has no scope " + scope})
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
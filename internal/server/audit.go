@@ -0,0 +1,22 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/O-Tempora/SberIT/internal/service"
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+// Actor of changes made by requests which are not authenticated
+const anonymousActor = "anonymous"
+
+// Puts actor and request id into context of request, so changes of tasks are recorded with them.
+// Request id is sent back in X-Request-Id header, it is taken from the same header of request if set
+func withAudit(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := middleware.GetReqID(r.Context())
+		w.Header().Set(middleware.RequestIDHeader, id)
+		ctx := service.WithAudit(r.Context(), service.Audit{Actor: anonymousActor, RequestId: id})
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
@@ -0,0 +1,103 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/O-Tempora/SberIT/internal/models"
+)
+
+// Upper bound of days in range of calendar endpoints
+const maxCalendarDays = 366
+
+// ISO 8601 week ("2024-W05") and month ("2024-02") in extended format
+var (
+	isoWeekPattern  = regexp.MustCompile(`^(\d{4})-W(\d{2})$`)
+	isoMonthPattern = regexp.MustCompile(`^(\d{4})-(\d{2})$`)
+)
+
+// CalendarDay is a day of calendar view with tasks which deadline is on that day
+type CalendarDay struct {
+	Date  string        `json:"date" example:"2023-12-04"`
+	Tasks []models.Task `json:"tasks"`
+}
+
+// Parses ISO 8601 calendar date "YYYY-MM-DD" of param
+func parseISODate(param, value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, badParam(param, errors.New("is required"))
+	}
+	date, err := time.Parse(dateLayout, value)
+	if err != nil {
+		return time.Time{}, badParam(param, fmt.Errorf("%q is not a valid date in format YYYY-MM-DD", value))
+	}
+	return date, nil
+}
+
+// Parses ISO 8601 week "YYYY-Www" of param and returns its monday and sunday
+func parseISOWeek(param, value string) (time.Time, time.Time, error) {
+	match := isoWeekPattern.FindStringSubmatch(value)
+	if match == nil {
+		return time.Time{}, time.Time{}, badParam(param, fmt.Errorf("%q is not a week in format YYYY-Www", value))
+	}
+	year, _ := strconv.Atoi(match[1])
+	week, _ := strconv.Atoi(match[2])
+
+	// January 4th is always in the first week of ISO year
+	jan4 := time.Date(year, time.January, 4, 0, 0, 0, 0, time.UTC)
+	monday := jan4.AddDate(0, 0, -(int(jan4.Weekday())+6)%7+(week-1)*7)
+	if y, w := monday.ISOWeek(); week < 1 || y != year || w != week {
+		return time.Time{}, time.Time{}, badParam(param, fmt.Errorf("year %d has no week %d", year, week))
+	}
+	return monday, monday.AddDate(0, 0, 6), nil
+}
+
+// Parses ISO 8601 month "YYYY-MM" of param and returns its first and last days
+func parseISOMonth(param, value string) (time.Time, time.Time, error) {
+	match := isoMonthPattern.FindStringSubmatch(value)
+	if match == nil {
+		return time.Time{}, time.Time{}, badParam(param, fmt.Errorf("%q is not a month in format YYYY-MM", value))
+	}
+	year, _ := strconv.Atoi(match[1])
+	month, _ := strconv.Atoi(match[2])
+	if month < 1 || month > 12 {
+		return time.Time{}, time.Time{}, badParam(param, errors.New("month must be between 01 and 12"))
+	}
+	first := time.Date(year, time.Month(month), 1, 0, 0, 0, 0, time.UTC)
+	return first, first.AddDate(0, 1, -1), nil
+}
+
+// Responds with tasks from first to last day grouped by day, optionally filtered by "done" query parameter.
+// Days are taken in timezone of request
+func (s *Server) respondCalendar(w http.ResponseWriter, r *http.Request, first, last time.Time) {
+	loc, err := s.requestLocation(r)
+	if err != nil {
+		s.respond(w, r, http.StatusBadRequest, nil, err)
+		return
+	}
+	var done *bool
+	if value := r.URL.Query().Get("done"); value != "" {
+		parsed, err := strconv.ParseBool(value)
+		if err != nil {
+			s.respond(w, r, http.StatusBadRequest, nil, badParam("done", err))
+			return
+		}
+		done = &parsed
+	}
+
+	days, err := s.Service.GetByDays(r.Context(), startOfDay(first, loc), startOfDay(last, loc), done)
+	if err != nil {
+		s.respond(w, r, http.StatusInternalServerError, nil, err)
+		return
+	}
+	res := make([]CalendarDay, 0, len(days))
+	for _, day := range days {
+		inLocation(day.Tasks, loc)
+		res = append(res, CalendarDay{Date: day.Date.Format(dateLayout), Tasks: day.Tasks})
+	}
+	s.respond(w, r, http.StatusOK, res, nil)
+}
@@ -0,0 +1,196 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/O-Tempora/SberIT/internal/models"
+	"github.com/O-Tempora/SberIT/internal/service"
+	"github.com/go-chi/chi/v5"
+)
+
+// GetChecklist godoc
+//
+//	@Summary		Get checklist of task
+//	@Description	Returns checklist items of task with id from path param in order they were added
+//	@Tags			Checklist
+//	@Accept			json
+//	@Produce		json
+//	@Param			id				path	int		true	"Task id"
+//	@Param			If-None-Match	header	string	false	"ETag of cached response"
+//	@Router			/tasks/{id}/checklist [get]
+//	@Success		200	{array}		models.ChecklistItem
+//	@Header			200	{string}	ETag	"Hash of response"
+//	@Success		304
+//	@Failure		400	{object}	Problem
+//	@Failure		403	{object}	Problem
+//	@Failure		404	{object}	Problem
+//	@Failure		500	{object}	Problem
+func (s *Server) handleGetChecklist(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		s.respond(w, r, http.StatusBadRequest, nil, badParam("id", err))
+		return
+	}
+	if !s.authorize(w, r, id, service.OperationRead) {
+		return
+	}
+	task, err := s.Service.Get(r.Context(), id)
+	if err != nil {
+		s.respond(w, r, http.StatusInternalServerError, nil, err)
+		return
+	}
+	s.respond(w, r, http.StatusOK, task.Checklist, nil)
+}
+
+// AddChecklistItem godoc
+//
+//	@Summary		Add checklist item
+//	@Description	Appends item to checklist of task with id from path param and returns id of item. Text is required and at most 500 characters long.
+//	@Description	Changes version of task and is recorded in its history
+//	@Tags			Checklist
+//	@Accept			json
+//	@Produce		json
+//	@Param			id			path	int						true	"Task id"
+//	@Param			item		body	models.ChecklistItem	true	"Checklist item"
+//	@Param			If-Match	header	string					false	"ETag of task or its version in quotes, required if enabled in config"
+//	@Router			/tasks/{id}/checklist [post]
+//	@Success		201	{integer}	Id
+//	@Failure		400	{object}	Problem
+//	@Failure		403	{object}	Problem
+//	@Failure		404	{object}	Problem
+//	@Failure		409	{object}	Problem
+//	@Failure		412	{object}	Problem
+//	@Failure		422	{object}	Problem
+//	@Failure		428	{object}	Problem
+//	@Failure		500	{object}	Problem
+func (s *Server) handleAddChecklistItem(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		s.respond(w, r, http.StatusBadRequest, nil, badParam("id", err))
+		return
+	}
+	if !s.authorize(w, r, id, service.OperationUpdate) {
+		return
+	}
+	version, err := s.ifMatchVersion(r)
+	if err != nil {
+		s.respond(w, r, http.StatusBadRequest, nil, err)
+		return
+	}
+	var req models.ChecklistItem
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.respond(w, r, http.StatusBadRequest, nil, err)
+		return
+	}
+	itemId, err := s.Service.AddChecklistItem(r.Context(), id, version, req)
+	if err != nil {
+		s.respond(w, r, http.StatusInternalServerError, nil, err)
+		return
+	}
+	s.respond(w, r, http.StatusCreated, itemId, nil)
+}
+
+// UpdateChecklistItem godoc
+//
+//	@Summary		Update checklist item
+//	@Description	Changes text and status of item with id from item path param in checklist of task with id from id path param, item is validated the same way as on create.
+//	@Description	Changes version of task and is recorded in its history, unless item already has the same text and status
+//	@Tags			Checklist
+//	@Accept			json
+//	@Produce		json
+//	@Param			id			path	int						true	"Task id"
+//	@Param			item		path	int						true	"Checklist item id"
+//	@Param			body		body	models.ChecklistItem	true	"Checklist item"
+//	@Param			If-Match	header	string					false	"ETag of task or its version in quotes, required if enabled in config"
+//	@Router			/tasks/{id}/checklist/{item} [put]
+//	@Success		200
+//	@Failure		400	{object}	Problem
+//	@Failure		403	{object}	Problem
+//	@Failure		404	{object}	Problem
+//	@Failure		409	{object}	Problem
+//	@Failure		412	{object}	Problem
+//	@Failure		422	{object}	Problem
+//	@Failure		428	{object}	Problem
+//	@Failure		500	{object}	Problem
+func (s *Server) handleUpdateChecklistItem(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		s.respond(w, r, http.StatusBadRequest, nil, badParam("id", err))
+		return
+	}
+	if !s.authorize(w, r, id, service.OperationUpdate) {
+		return
+	}
+	itemId, err := strconv.Atoi(chi.URLParam(r, "item"))
+	if err != nil {
+		s.respond(w, r, http.StatusBadRequest, nil, badParam("item", err))
+		return
+	}
+	version, err := s.ifMatchVersion(r)
+	if err != nil {
+		s.respond(w, r, http.StatusBadRequest, nil, err)
+		return
+	}
+	var req models.ChecklistItem
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.respond(w, r, http.StatusBadRequest, nil, err)
+		return
+	}
+	if err := s.Service.UpdateChecklistItem(r.Context(), id, version, itemId, req); err != nil {
+		s.respond(w, r, http.StatusInternalServerError, nil, err)
+		return
+	}
+	s.respond(w, r, http.StatusOK, nil, nil)
+}
+
+// DeleteChecklistItem godoc
+//
+//	@Summary		Delete checklist item
+//	@Description	Removes item with id from item path param from checklist of task with id from id path param.
+//	@Description	Changes version of task and is recorded in its history
+//	@Tags			Checklist
+//	@Accept			json
+//	@Produce		json
+//	@Param			id			path	int		true	"Task id"
+//	@Param			item		path	int		true	"Checklist item id"
+//	@Param			If-Match	header	string	false	"ETag of task or its version in quotes, required if enabled in config"
+//	@Router			/tasks/{id}/checklist/{item} [delete]
+//	@Success		200
+//	@Failure		400	{object}	Problem
+//	@Failure		403	{object}	Problem
+//	@Failure		404	{object}	Problem
+//	@Failure		409	{object}	Problem
+//	@Failure		412	{object}	Problem
+//	@Failure		428	{object}	Problem
+//	@Failure		500	{object}	Problem
+func (s *Server) handleDeleteChecklistItem(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		s.respond(w, r, http.StatusBadRequest, nil, badParam("id", err))
+		return
+	}
+	if !s.authorize(w, r, id, service.OperationUpdate) {
+		return
+	}
+	itemId, err := strconv.Atoi(chi.URLParam(r, "item"))
+	if err != nil {
+		s.respond(w, r, http.StatusBadRequest, nil, badParam("item", err))
+		return
+	}
+	version, err := s.ifMatchVersion(r)
+	if err != nil {
+		s.respond(w, r, http.StatusBadRequest, nil, err)
+		return
+	}
+	if err := s.Service.DeleteChecklistItem(r.Context(), id, version, itemId); err != nil {
+		s.respond(w, r, http.StatusInternalServerError, nil, err)
+		return
+	}
+	s.respond(w, r, http.StatusOK, nil, nil)
+}
@@ -0,0 +1,183 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+
+	"github.com/O-Tempora/SberIT/internal/models"
+	"github.com/O-Tempora/SberIT/internal/service"
+	"github.com/go-chi/chi/v5"
+)
+
+// DependencyList holds tasks which block task and tasks which are blocked by it
+type DependencyList struct {
+	// Tasks which must be done before the task
+	BlockedBy []models.Task `json:"blocked_by"`
+	// Tasks which wait for the task
+	Blocking []models.Task `json:"blocking"`
+}
+
+// GetDependencies godoc
+//
+//	@Summary		Get dependencies of task
+//	@Description	Returns tasks which directly block task with id from path param and tasks which are directly blocked by it, ordered by id
+//	@Tags			Dependencies
+//	@Accept			json
+//	@Produce		json
+//	@Param			id				path	int		true	"Task id"
+//	@Param			If-None-Match	header	string	false	"ETag of cached response"
+//	@Param			X-Timezone		header	string	false	"IANA timezone of dates and deadlines, e.g. Europe/Moscow (timezone from config by default)"
+//	@Param			tz				query	string	false	"Timezone if X-Timezone header is not set"
+//	@Router			/tasks/{id}/dependencies [get]
+//	@Success		200	{object}	DependencyList
+//	@Header			200	{string}	ETag	"Hash of response"
+//	@Success		304
+//	@Failure		400	{object}	Problem
+//	@Failure		403	{object}	Problem
+//	@Failure		404	{object}	Problem
+//	@Failure		500	{object}	Problem
+func (s *Server) handleGetDependencies(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		s.respond(w, r, http.StatusBadRequest, nil, badParam("id", err))
+		return
+	}
+	if !s.authorize(w, r, id, service.OperationRead) {
+		return
+	}
+	loc, err := s.requestLocation(r)
+	if err != nil {
+		s.respond(w, r, http.StatusBadRequest, nil, err)
+		return
+	}
+	deps, err := s.Service.GetDependencies(r.Context(), id)
+	if err != nil {
+		s.respond(w, r, http.StatusInternalServerError, nil, err)
+		return
+	}
+	res := DependencyList{BlockedBy: []models.Task{}, Blocking: []models.Task{}}
+	res.BlockedBy = append(res.BlockedBy, deps.BlockedBy...)
+	res.Blocking = append(res.Blocking, deps.Blocking...)
+	inLocation(res.BlockedBy, loc)
+	inLocation(res.Blocking, loc)
+	s.respond(w, r, http.StatusOK, res, nil)
+}
+
+// AddBlocker godoc
+//
+//	@Summary		Block task by other task
+//	@Description	Makes task with id from id path param blocked by task with id from blocker path param, does nothing if it is already blocked by it.
+//	@Description	Fails with conflict if blocker depends on the task. Changes version of task and is recorded in its history
+//	@Tags			Dependencies
+//	@Accept			json
+//	@Produce		json
+//	@Param			id			path	int		true	"Task id"
+//	@Param			blocker		path	int		true	"Id of blocking task"
+//	@Param			If-Match	header	string	false	"ETag of task or its version in quotes, required if enabled in config"
+//	@Router			/tasks/{id}/dependencies/{blocker} [put]
+//	@Success		200
+//	@Failure		400	{object}	Problem
+//	@Failure		403	{object}	Problem
+//	@Failure		404	{object}	Problem
+//	@Failure		409	{object}	Problem
+//	@Failure		412	{object}	Problem
+//	@Failure		428	{object}	Problem
+//	@Failure		500	{object}	Problem
+func (s *Server) handleAddBlocker(w http.ResponseWriter, r *http.Request) {
+	s.changeBlocker(w, r, s.Service.AddBlocker)
+}
+
+// RemoveBlocker godoc
+//
+//	@Summary		Unblock task
+//	@Description	Makes task with id from id path param no longer blocked by task with id from blocker path param, does nothing if it is not blocked by it.
+//	@Description	Changes version of task and is recorded in its history
+//	@Tags			Dependencies
+//	@Accept			json
+//	@Produce		json
+//	@Param			id			path	int		true	"Task id"
+//	@Param			blocker		path	int		true	"Id of blocking task"
+//	@Param			If-Match	header	string	false	"ETag of task or its version in quotes, required if enabled in config"
+//	@Router			/tasks/{id}/dependencies/{blocker} [delete]
+//	@Success		200
+//	@Failure		400	{object}	Problem
+//	@Failure		403	{object}	Problem
+//	@Failure		404	{object}	Problem
+//	@Failure		409	{object}	Problem
+//	@Failure		412	{object}	Problem
+//	@Failure		428	{object}	Problem
+//	@Failure		500	{object}	Problem
+func (s *Server) handleRemoveBlocker(w http.ResponseWriter, r *http.Request) {
+	s.changeBlocker(w, r, s.Service.RemoveBlocker)
+}
+
+// Calls change with task and blocker from path and version from If-Match header
+func (s *Server) changeBlocker(w http.ResponseWriter, r *http.Request, change func(ctx context.Context, id, version, blockerId int) error) {
+	w.Header().Set("Content-Type", "application/json")
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		s.respond(w, r, http.StatusBadRequest, nil, badParam("id", err))
+		return
+	}
+	if !s.authorize(w, r, id, service.OperationUpdate) {
+		return
+	}
+	blockerId, err := strconv.Atoi(chi.URLParam(r, "blocker"))
+	if err != nil {
+		s.respond(w, r, http.StatusBadRequest, nil, badParam("blocker", err))
+		return
+	}
+	version, err := s.ifMatchVersion(r)
+	if err != nil {
+		s.respond(w, r, http.StatusBadRequest, nil, err)
+		return
+	}
+	if err := change(r.Context(), id, version, blockerId); err != nil {
+		s.respond(w, r, http.StatusInternalServerError, nil, err)
+		return
+	}
+	s.respond(w, r, http.StatusOK, nil, nil)
+}
+
+// GetReady godoc
+//
+//	@Summary		Get tasks to work on now
+//	@Description	Returns open tasks which are not blocked by open tasks ordered by deadline. Blockers in trash or archived do not block, open blockers of other users do.
+//	@Description	With all=true other open tasks follow them in topological order, every task after all its blockers
+//	@Tags			Dependencies
+//	@Accept			json
+//	@Produce		json
+//	@Param			all				query	bool	false	"Return all open tasks in order they can be done"
+//	@Param			If-None-Match	header	string	false	"ETag of cached response"
+//	@Param			X-Timezone		header	string	false	"IANA timezone of dates and deadlines, e.g. Europe/Moscow (timezone from config by default)"
+//	@Param			tz				query	string	false	"Timezone if X-Timezone header is not set"
+//	@Router			/tasks/ready [get]
+//	@Success		200	{array}		models.Task
+//	@Header			200	{string}	ETag	"Hash of response"
+//	@Success		304
+//	@Failure		400	{object}	Problem
+//	@Failure		500	{object}	Problem
+func (s *Server) handleGetReady(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	loc, err := s.requestLocation(r)
+	if err != nil {
+		s.respond(w, r, http.StatusBadRequest, nil, err)
+		return
+	}
+	all := false
+	if value := r.URL.Query().Get("all"); value != "" {
+		if all, err = strconv.ParseBool(value); err != nil {
+			s.respond(w, r, http.StatusBadRequest, nil, badParam("all", err))
+			return
+		}
+	}
+	tasks, err := s.Service.GetReady(r.Context(), all)
+	if err != nil {
+		s.respond(w, r, http.StatusInternalServerError, nil, err)
+		return
+	}
+	inLocation(tasks, loc)
+	s.respond(w, r, http.StatusOK, tasks, nil)
+}
@@ -0,0 +1,78 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/O-Tempora/SberIT/internal/models"
+)
+
+var errPreconditionRequired = errors.New("If-Match header with ETag of task is required")
+
+// Weak ETag of response body, used for responses without own ETag
+func bodyETag(body []byte) string {
+	return fmt.Sprintf(`W/"%s"`, bodyHash(body))
+}
+
+// Strong ETag of task with its version, which If-Match is checked by, and hash of its representation,
+// which also changes with progress and role of task, e.g. "3-af63bd4c8601b7be"
+func taskETag(task models.Task) (string, error) {
+	body, err := json.Marshal(task)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf(`"%d-%s"`, task.Version, bodyHash(append(body, '\n'))), nil
+}
+
+func bodyHash(body []byte) string {
+	h := fnv.New64a()
+	h.Write(body)
+	return fmt.Sprintf("%x", h.Sum64())
+}
+
+// Returns task version from If-Match header, which is ETag of task from GET /tasks/{id} or version in quotes, e.g. "3".
+// 0 if any version matches ("*" or no header when it is not required)
+func (s *Server) ifMatchVersion(r *http.Request) (int, error) {
+	header := strings.TrimSpace(r.Header.Get("If-Match"))
+	switch header {
+	case "":
+		if s.Config.RequireIfMatch {
+			return 0, errPreconditionRequired
+		}
+		return 0, nil
+	case "*":
+		return 0, nil
+	}
+
+	// Weak ETags never match If-Match, which uses strong comparison. Hash of task is not compared, as changes
+	// of task which matter for If-Match increment its version
+	tag, quoted := strings.CutPrefix(header, `"`)
+	tag, _, _ = strings.Cut(strings.TrimSuffix(tag, `"`), "-")
+	version, err := strconv.Atoi(tag)
+	if err != nil || version <= 0 || !quoted || !strings.HasSuffix(header, `"`) {
+		return 0, badParam("If-Match", fmt.Errorf("%s is not a strong ETag of task", header))
+	}
+	return version, nil
+}
+
+// Reports whether If-None-Match header matches etag using weak comparison
+func ifNoneMatch(r *http.Request, etag string) bool {
+	header := r.Header.Get("If-None-Match")
+	if header == "" {
+		return false
+	}
+	if strings.TrimSpace(header) == "*" {
+		return true
+	}
+	for _, tag := range strings.Split(header, ",") {
+		if strings.TrimPrefix(strings.TrimSpace(tag), "W/") == strings.TrimPrefix(etag, "W/") {
+			return true
+		}
+	}
+	return false
+}
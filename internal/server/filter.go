@@ -0,0 +1,77 @@
+package server
+
+import (
+	"errors"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/O-Tempora/SberIT/internal/service"
+)
+
+// Layout of dates in query parameters
+const dateLayout = "2006-01-02"
+
+// Builds filter of task list from query parameters, all invalid parameters are reported together.
+// Dates are days in loc
+func parseTaskFilter(query url.Values, loc *time.Location) (service.TaskFilter, error) {
+	var filter service.TaskFilter
+	var errs []error
+
+	parseBool := func(param string) *bool {
+		if query.Get(param) == "" {
+			return nil
+		}
+		value, err := strconv.ParseBool(query.Get(param))
+		if err != nil {
+			errs = append(errs, badParam(param, err))
+			return nil
+		}
+		return &value
+	}
+	parseDate := func(param string) *time.Time {
+		if query.Get(param) == "" {
+			return nil
+		}
+		value, err := parseISODate(param, query.Get(param))
+		if err != nil {
+			errs = append(errs, err)
+			return nil
+		}
+		value = startOfDay(value, loc)
+		return &value
+	}
+
+	filter.Done = parseBool("done")
+	filter.Overdue = parseBool("overdue")
+	filter.DeadlineFrom = parseDate("deadline_from")
+	// Last day is included, so deadline must be before the next one
+	if to := parseDate("deadline_to"); to != nil {
+		if filter.DeadlineFrom != nil && filter.DeadlineFrom.After(*to) {
+			errs = append(errs, badParam("deadline_to", errors.New("must not be earlier than deadline_from")))
+		}
+		before := to.AddDate(0, 0, 1)
+		filter.DeadlineBefore = &before
+	}
+	filter.Search = query.Get("q")
+	for _, tag := range query["tag"] {
+		if tag != "" {
+			filter.Tags = append(filter.Tags, tag)
+		}
+	}
+	switch query.Get("tag_match") {
+	case "", "any":
+	case "all":
+		filter.AllTags = true
+	default:
+		errs = append(errs, badParam("tag_match", errors.New(`must be "any" or "all"`)))
+	}
+
+	sort, err := service.ParseSort(query.Get("sort"))
+	if err != nil {
+		errs = append(errs, badParam("sort", err))
+	}
+	filter.Sort = sort
+
+	return filter, errors.Join(errs...)
+}
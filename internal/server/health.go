@@ -0,0 +1,28 @@
+package server
+
+import "net/http"
+
+// Health is status of server
+type Health struct {
+	Status string `json:"status" example:"ok"`
+}
+
+// Health godoc
+//
+//	@Summary		Check health
+//	@Description	Reports that server is up and its database is reachable. Does not require authentication
+//	@Tags			Health
+//	@Produce		json
+//	@Router			/health [get]
+//	@Success		200	{object}	Health
+//	@Failure		503	{object}	Problem
+func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if s.Db != nil {
+		if err := s.Db.PingContext(r.Context()); err != nil {
+			s.respond(w, r, http.StatusServiceUnavailable, nil, err)
+			return
+		}
+	}
+	s.respond(w, r, http.StatusOK, Health{Status: "ok"}, nil)
+}
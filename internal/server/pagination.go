@@ -0,0 +1,160 @@
+package server
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/O-Tempora/SberIT/internal/models"
+	"github.com/O-Tempora/SberIT/internal/service"
+)
+
+// Page size of cursor pagination if limit is not set and its upper bound, also applied to take of page pagination
+const (
+	defaultLimit = 20
+	maxLimit     = 100
+)
+
+// Header with number of all tasks matching filter, set on list responses
+const totalCountHeader = "X-Total-Count"
+
+// TaskPage is a page of cursor pagination. Cursors are null if there is no adjacent page,
+// has_more shows whether there are more tasks in direction of pagination
+type TaskPage struct {
+	Items      []models.Task `json:"items"`
+	NextCursor *string       `json:"next_cursor" example:"eyJpZCI6MjB9"`
+	PrevCursor *string       `json:"prev_cursor" example:"eyJpZCI6MSwiYiI6dHJ1ZX0"`
+	Total      int           `json:"total" example:"42"`
+	PageSize   int           `json:"page_size" example:"20"`
+	HasMore    bool          `json:"has_more"`
+}
+
+// TaskList is an envelope of task array returned instead of bare array if envelope=true is set
+type TaskList struct {
+	Items    []models.Task `json:"items"`
+	Total    int           `json:"total" example:"42"`
+	Page     int           `json:"page" example:"1"`
+	PageSize int           `json:"page_size" example:"20"`
+	HasMore  bool          `json:"has_more"`
+}
+
+// Cursors are opaque for clients, they get base64 encoded json
+type cursorToken struct {
+	Id       int  `json:"id"`
+	Backward bool `json:"b,omitempty"`
+}
+
+func encodeCursor(cursor *service.Cursor) *string {
+	if cursor == nil {
+		return nil
+	}
+	bytes, _ := json.Marshal(cursorToken{Id: cursor.Id, Backward: cursor.Backward})
+	encoded := base64.RawURLEncoding.EncodeToString(bytes)
+	return &encoded
+}
+
+func decodeCursor(encoded string) (*service.Cursor, error) {
+	if encoded == "" {
+		return nil, nil
+	}
+	bytes, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, badParam("cursor", errors.New("malformed cursor"))
+	}
+	var token cursorToken
+	if err = json.Unmarshal(bytes, &token); err != nil || token.Id <= 0 {
+		return nil, badParam("cursor", errors.New("malformed cursor"))
+	}
+	return &service.Cursor{Id: token.Id, Backward: token.Backward}, nil
+}
+
+// Parses positive integer query parameter not greater than maxLimit, def is returned if parameter is not set
+func parseLimit(query url.Values, param string, def int) (int, error) {
+	value := query.Get(param)
+	if value == "" {
+		return def, nil
+	}
+	limit, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, badParam(param, err)
+	}
+	if limit < 1 || limit > maxLimit {
+		return 0, badParam(param, fmt.Errorf("must be between 1 and %d", maxLimit))
+	}
+	return limit, nil
+}
+
+// Reports whether list should be wrapped in TaskList, which is selected by envelope query parameter
+func wantsEnvelope(query url.Values) (bool, error) {
+	if query.Get("envelope") == "" {
+		return false, nil
+	}
+	envelope, err := strconv.ParseBool(query.Get("envelope"))
+	if err != nil {
+		return false, badParam("envelope", err)
+	}
+	return envelope, nil
+}
+
+// Builds response and Link header value (RFC 8288) with urls of adjacent pages
+func newTaskPage(r *http.Request, page *service.CursorPage, limit int) (TaskPage, string) {
+	res := TaskPage{
+		Items:      page.Tasks,
+		NextCursor: encodeCursor(page.Next),
+		PrevCursor: encodeCursor(page.Prev),
+		Total:      page.Total,
+		PageSize:   limit,
+		HasMore:    page.HasMore,
+	}
+	if res.Items == nil {
+		res.Items = []models.Task{}
+	}
+
+	var links []string
+	for _, l := range []struct {
+		rel    string
+		cursor *string
+	}{{"next", res.NextCursor}, {"prev", res.PrevCursor}} {
+		if l.cursor == nil {
+			continue
+		}
+		query := r.URL.Query()
+		query.Set("cursor", *l.cursor)
+		links = append(links, fmt.Sprintf(`<%s?%s>; rel="%s"`, r.URL.Path, query.Encode(), l.rel))
+	}
+	return res, strings.Join(links, ", ")
+}
+
+// Unpaginated list is a single page with all tasks
+func wholeList(tasks []models.Task) *service.OffsetPage {
+	return &service.OffsetPage{
+		Tasks: tasks,
+		Page:  1,
+		Take:  len(tasks),
+		Total: len(tasks),
+	}
+}
+
+// Sets total count header and returns response body: tasks as is or wrapped in TaskList if envelope is set
+func newTaskList(w http.ResponseWriter, page *service.OffsetPage, envelope bool) interface{} {
+	w.Header().Set(totalCountHeader, strconv.Itoa(page.Total))
+	if !envelope {
+		return page.Tasks
+	}
+	res := TaskList{
+		Items:    page.Tasks,
+		Total:    page.Total,
+		Page:     page.Page,
+		PageSize: page.Take,
+		HasMore:  page.HasMore,
+	}
+	if res.Items == nil {
+		res.Items = []models.Task{}
+	}
+	return res
+}
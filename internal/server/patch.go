@@ -0,0 +1,215 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/O-Tempora/SberIT/internal/models"
+	"github.com/O-Tempora/SberIT/internal/service"
+)
+
+const (
+	mergePatchContentType = "application/merge-patch+json"
+	jsonPatchContentType  = "application/json-patch+json"
+)
+
+var errUnsupportedPatch = errors.New("unsupported patch content type, use " +
+	mergePatchContentType + " or " + jsonPatchContentType)
+
+// PatchOperation is an operation of RFC 6902 JSON Patch
+type PatchOperation struct {
+	Op    string          `json:"op" enums:"add,replace,remove,test"`
+	Path  string          `json:"path" example:"/done"`
+	Value json.RawMessage `json:"value,omitempty" swaggertype:"object"`
+}
+
+// Decodes body of PATCH request into task patch according to its content type, date only deadline is resolved in loc.
+// current is called only if JSON Patch contains "test" operations
+func decodePatch(contentType string, body io.Reader, id int, loc *time.Location, current func() (*models.Task, error)) (models.TaskPatch, error) {
+	mediaType := "application/json"
+	if contentType != "" {
+		var err error
+		if mediaType, _, err = mime.ParseMediaType(contentType); err != nil {
+			return models.TaskPatch{}, errUnsupportedPatch
+		}
+	}
+
+	switch mediaType {
+	case mergePatchContentType, "application/json":
+		return decodeMergePatch(body, id, loc)
+	case jsonPatchContentType:
+		return decodeJSONPatch(body, loc, current)
+	default:
+		return models.TaskPatch{}, errUnsupportedPatch
+	}
+}
+
+// RFC 7396 JSON Merge Patch. Null removes the field, which resets it to its default value
+func decodeMergePatch(body io.Reader, id int, loc *time.Location) (models.TaskPatch, error) {
+	var members map[string]json.RawMessage
+	if err := json.NewDecoder(body).Decode(&members); err != nil {
+		return models.TaskPatch{}, err
+	}
+
+	// Sorted to report violations in stable order
+	names := make([]string, 0, len(members))
+	for name := range members {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var patch models.TaskPatch
+	var errs []error
+	for _, name := range names {
+		value := members[name]
+		if name == "id" {
+			var patchId int
+			if err := json.Unmarshal(value, &patchId); err != nil || patchId != id {
+				errs = append(errs, &service.ValidationError{Field: "id", Message: "must not differ from id in path"})
+			}
+			continue
+		}
+		if err := setPatchField(&patch, name, value, loc); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return patch, errors.Join(errs...)
+}
+
+// RFC 6902 JSON Patch. Operations "add" and "replace" set the field, "remove" resets it to default value,
+// "test" compares field of current task with value and fails with conflict if they differ
+func decodeJSONPatch(body io.Reader, loc *time.Location, current func() (*models.Task, error)) (models.TaskPatch, error) {
+	var ops []PatchOperation
+	if err := json.NewDecoder(body).Decode(&ops); err != nil {
+		return models.TaskPatch{}, err
+	}
+
+	var patch models.TaskPatch
+	for i, op := range ops {
+		name, found := strings.CutPrefix(op.Path, "/")
+		if !found || strings.Contains(name, "/") {
+			return models.TaskPatch{}, &service.ValidationError{
+				Field:   fmt.Sprintf("[%d].path", i),
+				Message: fmt.Sprintf("unsupported path %q", op.Path),
+			}
+		}
+
+		var err error
+		switch op.Op {
+		case "add", "replace":
+			err = setPatchField(&patch, name, op.Value, loc)
+		case "remove":
+			err = setPatchField(&patch, name, json.RawMessage("null"), loc)
+		case "test":
+			err = testPatchField(current, name, op.Value, loc)
+		default:
+			err = &service.ValidationError{
+				Field:   fmt.Sprintf("[%d].op", i),
+				Message: fmt.Sprintf("unsupported operation %q", op.Op),
+			}
+		}
+		if err != nil {
+			return models.TaskPatch{}, err
+		}
+	}
+	return patch, nil
+}
+
+// Sets field of patch from raw json value, null sets field to its default value
+func setPatchField(patch *models.TaskPatch, name string, value json.RawMessage, loc *time.Location) error {
+	var err error
+	switch name {
+	case "header":
+		patch.Header = new(string)
+		err = unmarshalNullable(value, patch.Header)
+	case "description":
+		patch.Description = new(string)
+		err = unmarshalNullable(value, patch.Description)
+	case "deadline":
+		var deadline deadlineValue
+		err = unmarshalNullable(value, &deadline)
+		patch.Deadline = new(time.Time)
+		*patch.Deadline = deadline.resolve(loc)
+	case "done":
+		patch.Done = new(bool)
+		err = unmarshalNullable(value, patch.Done)
+	case "priority":
+		patch.Priority = new(int)
+		err = unmarshalNullable(value, patch.Priority)
+	case "effort":
+		patch.Effort = new(int)
+		err = unmarshalNullable(value, patch.Effort)
+	case "project_id":
+		patch.ProjectId = new(int)
+		err = unmarshalNullable(value, patch.ProjectId)
+	case "parent_id":
+		patch.ParentId = new(int)
+		err = unmarshalNullable(value, patch.ParentId)
+	case "recurrence":
+		patch.Recurrence = new(string)
+		err = unmarshalNullable(value, patch.Recurrence)
+	default:
+		return &service.ValidationError{Field: name, Message: "unknown field"}
+	}
+	if err != nil {
+		return &service.ValidationError{Field: name, Message: "invalid value"}
+	}
+	return nil
+}
+
+func testPatchField(current func() (*models.Task, error), name string, value json.RawMessage, loc *time.Location) error {
+	var expected models.TaskPatch
+	if err := setPatchField(&expected, name, value, loc); err != nil {
+		return err
+	}
+	task, err := current()
+	if err != nil {
+		return err
+	}
+
+	var equal bool
+	switch name {
+	case "header":
+		equal = *expected.Header == task.Header
+	case "description":
+		equal = *expected.Description == task.Description
+	case "deadline":
+		// Date only value matches any time of that day
+		var deadline deadlineValue
+		json.Unmarshal(value, &deadline)
+		equal = deadline.matches(task.Deadline, loc)
+	case "done":
+		equal = *expected.Done == task.Done
+	case "priority":
+		equal = *expected.Priority == task.Priority
+	case "effort":
+		equal = *expected.Effort == 0 && task.Effort == nil ||
+			task.Effort != nil && *expected.Effort == *task.Effort
+	case "project_id":
+		equal = *expected.ProjectId == 0 && task.ProjectId == nil ||
+			task.ProjectId != nil && *expected.ProjectId == *task.ProjectId
+	case "parent_id":
+		equal = *expected.ParentId == 0 && task.ParentId == nil ||
+			task.ParentId != nil && *expected.ParentId == *task.ParentId
+	case "recurrence":
+		equal = *expected.Recurrence == task.Recurrence
+	}
+	if !equal {
+		return &service.ConflictError{Message: fmt.Sprintf("test of %s failed: value differs", name)}
+	}
+	return nil
+}
+
+// Leaves zero value in dst if value is null
+func unmarshalNullable(value json.RawMessage, dst interface{}) error {
+	if string(value) == "null" {
+		return nil
+	}
+	return json.Unmarshal(value, dst)
+}
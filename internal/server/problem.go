@@ -0,0 +1,90 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/O-Tempora/SberIT/internal/service"
+)
+
+const problemContentType = "application/problem+json"
+
+// Problem types. "about:blank" means that problem has no semantics beyond http status
+const (
+	problemTypeBlank        = "about:blank"
+	problemTypeBadRequest   = "/problems/bad-request"
+	problemTypeNotFound     = "/problems/not-found"
+	problemTypeValidation   = "/problems/validation"
+	problemTypeConflict     = "/problems/conflict"
+	problemTypeModified     = "/problems/modified"
+	problemTypeUnauthorized = "/problems/unauthorized"
+	problemTypeForbidden    = "/problems/forbidden"
+)
+
+// Problem is an error response body as described in RFC 7807
+type Problem struct {
+	Type     string       `json:"type" example:"/problems/validation"`
+	Title    string       `json:"title" example:"Unprocessable Entity"`
+	Status   int          `json:"status" example:"422"`
+	Detail   string       `json:"detail,omitempty" example:"deadline: task deadline can not be in the past"`
+	Instance string       `json:"instance" example:"/tasks/1"`
+	Errors   []FieldError `json:"errors,omitempty"`
+}
+
+// FieldError describes invalid field of request body or invalid request parameter
+type FieldError struct {
+	Field   string `json:"field" example:"deadline"`
+	Message string `json:"message" example:"task deadline can not be in the past"`
+}
+
+// Builds problem for error. Details of internal errors are not exposed to client
+func newProblem(r *http.Request, code int, err error) Problem {
+	problem := Problem{
+		Type:     problemTypeBlank,
+		Title:    http.StatusText(code),
+		Status:   code,
+		Detail:   err.Error(),
+		Instance: r.URL.Path,
+		Errors:   fieldErrors(err),
+	}
+
+	switch {
+	case errors.Is(err, service.ErrUnauthorized):
+		problem.Type = problemTypeUnauthorized
+	case errors.Is(err, service.ErrForbidden):
+		problem.Type = problemTypeForbidden
+	case errors.Is(err, service.ErrNotFound):
+		problem.Type = problemTypeNotFound
+	case errors.Is(err, service.ErrValidation):
+		problem.Type = problemTypeValidation
+	case errors.Is(err, service.ErrConflict):
+		problem.Type = problemTypeConflict
+	case errors.Is(err, service.ErrPreconditionFailed):
+		problem.Type = problemTypeModified
+	case code == http.StatusBadRequest:
+		problem.Type = problemTypeBadRequest
+	case code >= http.StatusInternalServerError:
+		problem.Detail = "internal error occurred while processing request"
+		problem.Errors = nil
+	}
+	return problem
+}
+
+// Collects field level errors from err, errors wrapped into it and errors joined with errors.Join
+func fieldErrors(err error) []FieldError {
+	switch e := err.(type) {
+	case *service.ValidationError:
+		return []FieldError{{Field: e.Field, Message: e.Message}}
+	case *paramError:
+		return []FieldError{{Field: e.param, Message: e.message()}}
+	case interface{ Unwrap() []error }:
+		var res []FieldError
+		for _, inner := range e.Unwrap() {
+			res = append(res, fieldErrors(inner)...)
+		}
+		return res
+	case interface{ Unwrap() error }:
+		return fieldErrors(e.Unwrap())
+	}
+	return nil
+}
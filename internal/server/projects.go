@@ -0,0 +1,331 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/O-Tempora/SberIT/internal/models"
+	"github.com/O-Tempora/SberIT/internal/service"
+	"github.com/go-chi/chi/v5"
+)
+
+// GetProjects godoc
+//
+//	@Summary		Get projects
+//	@Description	Returns projects ordered by name with numbers of their open and done tasks, archived projects only with archived=true
+//	@Tags			Projects
+//	@Accept			json
+//	@Produce		json
+//	@Param			archived		query	bool	false	"List archived projects instead of active ones"
+//	@Param			If-None-Match	header	string	false	"ETag of cached response"
+//	@Param			X-Timezone		header	string	false	"IANA timezone of times, e.g. Europe/Moscow (timezone from config by default)"
+//	@Param			tz				query	string	false	"Timezone if X-Timezone header is not set"
+//	@Router			/projects [get]
+//	@Success		200	{array}		models.Project
+//	@Header			200	{string}	ETag	"Hash of response"
+//	@Success		304
+//	@Failure		400	{object}	Problem
+//	@Failure		500	{object}	Problem
+func (s *Server) handleGetProjects(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	loc, err := s.requestLocation(r)
+	if err != nil {
+		s.respond(w, r, http.StatusBadRequest, nil, err)
+		return
+	}
+	archived := false
+	if value := r.URL.Query().Get("archived"); value != "" {
+		if archived, err = strconv.ParseBool(value); err != nil {
+			s.respond(w, r, http.StatusBadRequest, nil, badParam("archived", err))
+			return
+		}
+	}
+	projects, err := s.Service.GetProjects(r.Context(), archived)
+	if err != nil {
+		s.respond(w, r, http.StatusInternalServerError, nil, err)
+		return
+	}
+	for i := range projects {
+		projects[i].ArchivedAt = timeIn(projects[i].ArchivedAt, loc)
+	}
+	s.respond(w, r, http.StatusOK, projects, nil)
+}
+
+// GetProject godoc
+//
+//	@Summary		Get project by id
+//	@Description	Returns project with id from pid path param with numbers of its open and done tasks
+//	@Tags			Projects
+//	@Accept			json
+//	@Produce		json
+//	@Param			pid				path	int		true	"Project id"
+//	@Param			If-None-Match	header	string	false	"ETag of cached response"
+//	@Param			X-Timezone		header	string	false	"IANA timezone of times, e.g. Europe/Moscow (timezone from config by default)"
+//	@Param			tz				query	string	false	"Timezone if X-Timezone header is not set"
+//	@Router			/projects/{pid} [get]
+//	@Success		200	{object}	models.Project
+//	@Header			200	{string}	ETag	"Hash of response"
+//	@Success		304
+//	@Failure		400	{object}	Problem
+//	@Failure		404	{object}	Problem
+//	@Failure		500	{object}	Problem
+func (s *Server) handleGetProject(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	pid, err := strconv.Atoi(chi.URLParam(r, "pid"))
+	if err != nil {
+		s.respond(w, r, http.StatusBadRequest, nil, badParam("pid", err))
+		return
+	}
+	loc, err := s.requestLocation(r)
+	if err != nil {
+		s.respond(w, r, http.StatusBadRequest, nil, err)
+		return
+	}
+	project, err := s.Service.GetProject(r.Context(), pid)
+	if err != nil {
+		s.respond(w, r, http.StatusInternalServerError, nil, err)
+		return
+	}
+	project.ArchivedAt = timeIn(project.ArchivedAt, loc)
+	s.respond(w, r, http.StatusOK, project, nil)
+}
+
+// CreateProject godoc
+//
+//	@Summary		Create project
+//	@Description	Creates project and returns its id. Name is required and at most 200 characters long
+//	@Tags			Projects
+//	@Accept			json
+//	@Produce		json
+//	@Param			project	body	models.Project	true	"Project data"
+//	@Router			/projects [post]
+//	@Success		201	{integer}	Id
+//	@Failure		400	{object}	Problem
+//	@Failure		422	{object}	Problem
+//	@Failure		500	{object}	Problem
+func (s *Server) handleCreateProject(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	var req models.Project
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.respond(w, r, http.StatusBadRequest, nil, err)
+		return
+	}
+	id, err := s.Service.CreateProject(r.Context(), req)
+	if err != nil {
+		s.respond(w, r, http.StatusInternalServerError, nil, err)
+		return
+	}
+	s.respond(w, r, http.StatusCreated, id, nil)
+}
+
+// UpdateProject godoc
+//
+//	@Summary		Rename project
+//	@Description	Renames project with id from pid path param, project is validated the same way as on create. Only user who created project changes it
+//	@Tags			Projects
+//	@Accept			json
+//	@Produce		json
+//	@Param			pid		path	int				true	"Project id"
+//	@Param			project	body	models.Project	true	"Project data"
+//	@Router			/projects/{pid} [put]
+//	@Success		200
+//	@Failure		400	{object}	Problem
+//	@Failure		403	{object}	Problem
+//	@Failure		404	{object}	Problem
+//	@Failure		422	{object}	Problem
+//	@Failure		500	{object}	Problem
+func (s *Server) handleUpdateProject(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	pid, err := strconv.Atoi(chi.URLParam(r, "pid"))
+	if err != nil {
+		s.respond(w, r, http.StatusBadRequest, nil, badParam("pid", err))
+		return
+	}
+	var req models.Project
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.respond(w, r, http.StatusBadRequest, nil, err)
+		return
+	}
+	if err := s.Service.UpdateProject(r.Context(), pid, req); err != nil {
+		s.respond(w, r, http.StatusInternalServerError, nil, err)
+		return
+	}
+	s.respond(w, r, http.StatusOK, nil, nil)
+}
+
+// ArchiveProject godoc
+//
+//	@Summary		Archive project
+//	@Description	Archives project with id from pid path param together with its tasks available to user, tasks of other users are left as they are. Archived tasks are listed only in their project and can not be changed.
+//	@Description	Tasks in trash are not archived, but are restored archived. Does nothing if project is already archived. Only user who created project archives it
+//	@Tags			Projects
+//	@Accept			json
+//	@Produce		json
+//	@Param			pid	path	int	true	"Project id"
+//	@Router			/projects/{pid}/archive [post]
+//	@Success		200
+//	@Failure		400	{object}	Problem
+//	@Failure		403	{object}	Problem
+//	@Failure		404	{object}	Problem
+//	@Failure		500	{object}	Problem
+func (s *Server) handleArchiveProject(w http.ResponseWriter, r *http.Request) {
+	s.setProjectArchived(w, r, true)
+}
+
+// UnarchiveProject godoc
+//
+//	@Summary		Unarchive project
+//	@Description	Brings project with id from pid path param and its tasks available to user back from archive. Does nothing if project is not archived. Only user who created project unarchives it
+//	@Tags			Projects
+//	@Accept			json
+//	@Produce		json
+//	@Param			pid	path	int	true	"Project id"
+//	@Router			/projects/{pid}/unarchive [post]
+//	@Success		200
+//	@Failure		400	{object}	Problem
+//	@Failure		403	{object}	Problem
+//	@Failure		404	{object}	Problem
+//	@Failure		500	{object}	Problem
+func (s *Server) handleUnarchiveProject(w http.ResponseWriter, r *http.Request) {
+	s.setProjectArchived(w, r, false)
+}
+
+func (s *Server) setProjectArchived(w http.ResponseWriter, r *http.Request, archive bool) {
+	w.Header().Set("Content-Type", "application/json")
+	pid, err := strconv.Atoi(chi.URLParam(r, "pid"))
+	if err != nil {
+		s.respond(w, r, http.StatusBadRequest, nil, badParam("pid", err))
+		return
+	}
+	if archive {
+		err = s.Service.ArchiveProject(r.Context(), pid)
+	} else {
+		err = s.Service.UnarchiveProject(r.Context(), pid)
+	}
+	if err != nil {
+		s.respond(w, r, http.StatusInternalServerError, nil, err)
+		return
+	}
+	s.respond(w, r, http.StatusOK, nil, nil)
+}
+
+// GetProjectTasks godoc
+//
+//	@Summary		Get tasks of project
+//	@Description	Returns tasks of project with id from pid path param, archived tasks included.
+//	@Description	Supports the same filters, sorting and pagination as GET /tasks
+//	@Tags			Projects
+//	@Accept			json
+//	@Produce		json
+//	@Param			pid				path	int			true	"Project id"
+//	@Param			done			query	bool		false	"Task status"
+//	@Param			q				query	string		false	"Case insensitive text in header or description"
+//	@Param			tag				query	[]string	false	"Names of tags"	collectionFormat(multi)
+//	@Param			sort			query	string		false	"Order of tasks, cursor pagination supports only id"
+//	@Param			cursor			query	string		false	"Cursor of page from next_cursor or prev_cursor of previous response"
+//	@Param			limit			query	int			false	"Page size of cursor pagination (1-100, 20 by default)"
+//	@Param			envelope		query	bool		false	"Wrap array of tasks in TaskList"
+//	@Param			If-None-Match	header	string		false	"ETag of cached response"
+//	@Param			X-Timezone		header	string		false	"IANA timezone of dates and deadlines, e.g. Europe/Moscow (timezone from config by default)"
+//	@Param			tz				query	string		false	"Timezone if X-Timezone header is not set"
+//	@Router			/projects/{pid}/tasks [get]
+//	@Success		200	{object}	TaskPage
+//	@Header			200	{string}	ETag			"Hash of response"
+//	@Header			200	{integer}	X-Total-Count	"Number of tasks matching filter"
+//	@Success		304
+//	@Failure		400	{object}	Problem
+//	@Failure		404	{object}	Problem
+//	@Failure		500	{object}	Problem
+func (s *Server) handleGetProjectTasks(w http.ResponseWriter, r *http.Request) {
+	pid, ok := s.pathProject(w, r)
+	if !ok {
+		return
+	}
+	s.respondTaskList(w, r, &pid)
+}
+
+// CreateProjectTask godoc
+//
+//	@Summary		Create task in project
+//	@Description	Creates task in project with id from pid path param the same way as POST /tasks. Project must not be archived
+//	@Tags			Projects
+//	@Accept			json
+//	@Produce		json
+//	@Param			pid			path	int			true	"Project id"
+//	@Param			task		body	models.Task	true	"Task data"
+//	@Param			X-Timezone	header	string		false	"IANA timezone of dates and deadlines, e.g. Europe/Moscow (timezone from config by default)"
+//	@Param			tz			query	string		false	"Timezone if X-Timezone header is not set"
+//	@Router			/projects/{pid}/tasks [post]
+//	@Success		201	{integer}	Id
+//	@Failure		400	{object}	Problem
+//	@Failure		404	{object}	Problem
+//	@Failure		409	{object}	Problem
+//	@Failure		422	{object}	Problem
+//	@Failure		500	{object}	Problem
+func (s *Server) handleCreateProjectTask(w http.ResponseWriter, r *http.Request) {
+	pid, ok := s.pathProject(w, r)
+	if !ok {
+		return
+	}
+	s.createTask(w, r, &pid)
+}
+
+// MoveTask godoc
+//
+//	@Summary		Move task to project
+//	@Description	Moves task with id from id path param to project with id from pid path param. Neither task nor project can be archived.
+//	@Description	Task is removed from project by setting its project_id to null
+//	@Tags			Projects
+//	@Accept			json
+//	@Produce		json
+//	@Param			pid			path	int		true	"Project id"
+//	@Param			id			path	int		true	"Task id"
+//	@Param			If-Match	header	string	false	"ETag of task or its version in quotes, required if enabled in config"
+//	@Router			/projects/{pid}/tasks/{id} [put]
+//	@Success		200
+//	@Failure		400	{object}	Problem
+//	@Failure		403	{object}	Problem
+//	@Failure		404	{object}	Problem
+//	@Failure		409	{object}	Problem
+//	@Failure		412	{object}	Problem
+//	@Failure		428	{object}	Problem
+//	@Failure		500	{object}	Problem
+func (s *Server) handleMoveTask(w http.ResponseWriter, r *http.Request) {
+	pid, ok := s.pathProject(w, r)
+	if !ok {
+		return
+	}
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		s.respond(w, r, http.StatusBadRequest, nil, badParam("id", err))
+		return
+	}
+	if !s.authorize(w, r, id, service.OperationUpdate) {
+		return
+	}
+	version, err := s.ifMatchVersion(r)
+	if err != nil {
+		s.respond(w, r, http.StatusBadRequest, nil, err)
+		return
+	}
+	if err := s.Service.Patch(r.Context(), id, version, models.TaskPatch{ProjectId: &pid}); err != nil {
+		s.respond(w, r, http.StatusInternalServerError, nil, err)
+		return
+	}
+	s.respond(w, r, http.StatusOK, nil, nil)
+}
+
+// Returns id of existing project from pid path param, responds with error if it is invalid or project does not exist
+func (s *Server) pathProject(w http.ResponseWriter, r *http.Request) (int, bool) {
+	pid, err := strconv.Atoi(chi.URLParam(r, "pid"))
+	if err != nil {
+		s.respond(w, r, http.StatusBadRequest, nil, badParam("pid", err))
+		return 0, false
+	}
+	if _, err = s.Service.GetProject(r.Context(), pid); err != nil {
+		s.respond(w, r, http.StatusInternalServerError, nil, err)
+		return 0, false
+	}
+	return pid, true
+}
@@ -0,0 +1,43 @@
+package server
+
+import (
+	"context"
+	"time"
+)
+
+const defaultPurgeInterval = time.Hour
+
+// RunPurger permanently deletes tasks which are in trash longer than retention from config,
+// once at start and then periodically until ctx is done. Does nothing if retention is not set
+func (s *Server) RunPurger(ctx context.Context) {
+	retention := s.Config.Trash.Retention
+	if retention <= 0 {
+		return
+	}
+	interval := s.Config.Trash.PurgeInterval
+	if interval <= 0 {
+		interval = defaultPurgeInterval
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		s.purgeTrash(ctx, retention)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *Server) purgeTrash(ctx context.Context, retention time.Duration) {
+	purged, err := s.Service.PurgeTrash(ctx, retention)
+	if err != nil {
+		s.Logger.Error().Msgf("Purge of trash failed: %s", err.Error())
+		return
+	}
+	if purged > 0 {
+		s.Logger.Info().Msgf("Purged %d tasks deleted more than %s ago", purged, retention)
+	}
+}
@@ -6,9 +6,12 @@ import (
 	"fmt"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/O-Tempora/SberIT/internal/models"
+	"github.com/O-Tempora/SberIT/internal/service"
 	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
 
 	_ "github.com/O-Tempora/SberIT/docs"
 	httpSwagger "github.com/swaggo/http-swagger/v2"
@@ -18,58 +21,163 @@ import (
 func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	s.Router.ServeHTTP(w, r)
 }
+
+// Errors are written as problem+json. Domain errors of service override passed code with their own status, see statusFromError
 func (s *Server) respond(w http.ResponseWriter, r *http.Request, code int, data interface{}, err error) {
-	w.WriteHeader(code)
 	if err != nil {
-		response := map[string]string{"error": err.Error()}
-		json.NewEncoder(w).Encode(response)
+		code = statusFromError(err, code)
+		w.Header().Set("Content-Type", problemContentType)
+		w.WriteHeader(code)
+		json.NewEncoder(w).Encode(newProblem(r, code, err))
 		s.Logger.Error().Msgf("Resonse: method  %s, URL  %s, code  %d %s, error  %s",
 			r.Method, r.URL, code, http.StatusText(code), err.Error())
 		return
 	}
 
+	var body []byte
 	if data != nil {
-		json.NewEncoder(w).Encode(data)
+		if body, err = json.Marshal(data); err != nil {
+			s.respond(w, r, http.StatusInternalServerError, nil, err)
+			return
+		}
+		body = append(body, '\n')
+	}
+
+	// Successful reads get ETag (if handler has not set one) and are not sent again if client has them cached
+	if r.Method == http.MethodGet && code == http.StatusOK {
+		etag := w.Header().Get("ETag")
+		if etag == "" {
+			etag = bodyETag(body)
+			w.Header().Set("ETag", etag)
+		}
+		if ifNoneMatch(r, etag) {
+			code = http.StatusNotModified
+			body = nil
+		}
 	}
+
+	w.WriteHeader(code)
+	w.Write(body)
 	s.Logger.Info().Msgf("Response: method  %s, URL  %s, Code  %d %s",
 		r.Method, r.URL, code, http.StatusText(code))
 }
 
 func (s *Server) InitRouter() {
+	s.Router.Use(middleware.RequestID, withAudit)
+
 	s.Router.Get("/swagger/*", httpSwagger.Handler(
 		httpSwagger.URL(fmt.Sprintf("http://localhost:%d/swagger/doc.json", s.Config.Port)), //The url pointing to API definition
 	))
 
+	s.Router.Get("/health", s.handleHealth)
+	s.Router.Post("/users", s.handleRegister)
+	s.Router.Post("/login", s.handleLogin)
+	s.Router.Post("/token", s.handleIssueToken)
+	s.Router.Post("/token/refresh", s.handleRefreshToken)
+
+	s.Router.Route("/api-keys", func(r chi.Router) {
+		r.Use(s.authenticate)
+		r.Get("/", s.handleGetAPIKeys)
+		r.Post("/", s.handleCreateAPIKey)
+		r.Delete("/{id}", s.handleRevokeAPIKey)
+	})
+
 	s.Router.Route("/tasks", func(r chi.Router) {
+		r.Use(s.authenticate, s.authorizeScopes)
 		r.Get("/{id}", s.handleGet)
+		r.Get("/trash", s.handleGetTrash)
+		r.Get("/ready", s.handleGetReady)
+		r.Get("/agenda", s.handleGetAgenda)
+		r.Post("/{id}/restore", s.handleRestore)
+		r.Get("/{id}/history", s.handleGetHistory)
+		r.Get("/{id}/tags", s.handleGetTaskTags)
+		r.Put("/{id}/tags/{tag}", s.handleAttachTag)
+		r.Delete("/{id}/tags/{tag}", s.handleDetachTag)
+		r.Get("/{id}/checklist", s.handleGetChecklist)
+		r.Post("/{id}/checklist", s.handleAddChecklistItem)
+		r.Put("/{id}/checklist/{item}", s.handleUpdateChecklistItem)
+		r.Delete("/{id}/checklist/{item}", s.handleDeleteChecklistItem)
+		r.Get("/{id}/dependencies", s.handleGetDependencies)
+		r.Put("/{id}/dependencies/{blocker}", s.handleAddBlocker)
+		r.Delete("/{id}/dependencies/{blocker}", s.handleRemoveBlocker)
+		r.Get("/{id}/shares", s.handleGetShares)
+		r.Put("/{id}/shares/{username}", s.handleShareTask)
+		r.Delete("/{id}/shares/{username}", s.handleUnshareTask)
 		r.Get("/", s.handleGetList)
 		r.Get("/byDate/{year}-{month}-{day}", s.handleGetByDate)
+		r.Get("/range", s.handleGetRange)
+		r.Get("/week/{week}", s.handleGetWeek)
+		r.Get("/month/{month}", s.handleGetMonth)
 		r.Post("/", s.handleCreateTask)
 		r.Put("/{id}", s.handleUpdate)
+		r.Patch("/{id}", s.handlePatch)
 		r.Delete("/{id}", s.handleDelete)
 	})
+
+	s.Router.Route("/projects", func(r chi.Router) {
+		r.Use(s.authenticate, s.authorizeScopes)
+		r.Get("/", s.handleGetProjects)
+		r.Post("/", s.handleCreateProject)
+		r.Get("/{pid}", s.handleGetProject)
+		r.Put("/{pid}", s.handleUpdateProject)
+		r.Post("/{pid}/archive", s.handleArchiveProject)
+		r.Post("/{pid}/unarchive", s.handleUnarchiveProject)
+		r.Route("/{pid}/tasks", func(r chi.Router) {
+			r.Get("/", s.handleGetProjectTasks)
+			r.Post("/", s.handleCreateProjectTask)
+			r.Put("/{id}", s.handleMoveTask)
+		})
+	})
+
+	s.Router.Route("/tags", func(r chi.Router) {
+		r.Use(s.authenticate, s.authorizeScopes)
+		r.Get("/", s.handleGetTags)
+		r.Post("/", s.handleCreateTag)
+		r.Put("/{id}", s.handleUpdateTag)
+		r.Delete("/{id}", s.handleDeleteTag)
+	})
 }
 
 // CreateTask godoc
 //
 //	@Summary		Create task
-//	@Description	Creates task with fields in body param and returns inserted id if successfull
+//	@Description	Creates task with fields in body param and returns inserted id if successfull. Date only deadline means the end of that day in timezone of request. Task is validated: header is required, header and description lengths are limited, deadline is required and can not be in the past, id must be omitted
 //	@Tags			Create
 //	@Accept			json
 //	@Produce		json
-//	@Param			task	body	models.Task	true	"Task data"
+//	@Param			task		body	models.Task	true	"Task data"
+//	@Param			X-Timezone	header	string		false	"IANA timezone of dates and deadlines, e.g. Europe/Moscow (timezone from config by default)"
+//	@Param			tz			query	string		false	"Timezone if X-Timezone header is not set"
 //	@Router			/tasks [post]
-//	@Success		200	{integer}		Id
-//	@Failure		400	{string}	error
-//	@Failure		500	{string}	error
+//	@Success		200	{integer}	Id
+//	@Failure		400	{object}	Problem
+//	@Failure		422	{object}	Problem
+//	@Failure		500	{object}	Problem
 func (s *Server) handleCreateTask(w http.ResponseWriter, r *http.Request) {
+	s.createTask(w, r, nil)
+}
+
+// Creates task from request body, in project with projectId if it is set
+func (s *Server) createTask(w http.ResponseWriter, r *http.Request, projectId *int) {
 	w.Header().Set("Content-Type", "application/json")
-	req := models.Task{}
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+	loc, err := s.requestLocation(r)
+	if err != nil {
 		s.respond(w, r, http.StatusBadRequest, nil, err)
 		return
 	}
-	id, err := s.Service.Create(req)
+	req, err := decodeTask(r.Body, loc)
+	if err != nil {
+		s.respond(w, r, http.StatusBadRequest, nil, err)
+		return
+	}
+	if projectId != nil {
+		if req.ProjectId != nil && *req.ProjectId != *projectId {
+			s.respond(w, r, http.StatusBadRequest, nil, &service.ValidationError{Field: "project_id", Message: "must not differ from project in path"})
+			return
+		}
+		req.ProjectId = projectId
+	}
+	id, err := s.Service.Create(r.Context(), req)
 	if err != nil {
 		s.respond(w, r, http.StatusInternalServerError, nil, err)
 		return
@@ -80,108 +188,230 @@ func (s *Server) handleCreateTask(w http.ResponseWriter, r *http.Request) {
 // GetList godoc
 //
 //	@Summary		Get task list
-//	@Description	Returns list of tasks with optional pagination (page + take) and optional filter by status (done)
+//	@Description	Returns list of tasks matching all set filters, ordered by fields of sort and then by id.
+//	@Description	Sort is comma separated list of fields (id, header, deadline, done, priority), "-" prefix sets descending order, e.g. sort=deadline,-id.
+//	@Description	If cursor or limit is set, returns page of cursor pagination with cursors of adjacent pages, which are also sent in Link header.
+//	@Description	Pagination with page + take is deprecated: it returns array of tasks and sets Deprecation header.
+//	@Description	Without pagination parameters returns array of all tasks.
+//	@Description	Number of all tasks matching filter is sent in X-Total-Count header. With envelope=true array is wrapped in server.TaskList with total, page, page_size and has_more
 //	@Tags			GetList
 //	@Accept			json
 //	@Produce		json
-//	@Param			done	query	bool	false	"Task status"
-//	@Param			page	query	int		false	"Page number"
-//	@Param			take	query	int		false	"Page size"
+//	@Param			done			query	bool		false	"Task status"
+//	@Param			deadline_from	query	string		false	"Earliest deadline (YYYY-MM-DD), inclusive"
+//	@Param			deadline_to		query	string		false	"Latest deadline (YYYY-MM-DD), inclusive"
+//	@Param			overdue			query	bool		false	"Task is not done and its deadline has passed"
+//	@Param			q				query	string		false	"Case insensitive text in header or description"
+//	@Param			tag				query	[]string	false	"Names of tags, task has any of them (or all with tag_match=all)"	collectionFormat(multi)
+//	@Param			tag_match		query	string		false	"Whether task has any or all of tags"								Enums(any, all)	default(any)
+//	@Param			sort			query	string		false	"Order of tasks, cursor pagination supports only id"
+//	@Param			cursor			query	string		false	"Cursor of page from next_cursor or prev_cursor of previous response"
+//	@Param			limit			query	int			false	"Page size of cursor pagination (1-100, 20 by default)"
+//	@Param			page			query	int			false	"Page number (deprecated)"
+//	@Param			take			query	int			false	"Page size (deprecated, 1-100)"
+//	@Param			envelope		query	bool		false	"Wrap array of tasks in TaskList"
+//	@Param			If-None-Match	header	string		false	"ETag of cached response"
+//	@Param			X-Timezone		header	string		false	"IANA timezone of dates and deadlines, e.g. Europe/Moscow (timezone from config by default)"
+//	@Param			tz				query	string		false	"Timezone if X-Timezone header is not set"
 //	@Router			/tasks [get]
-//	@Success		200	{array}		models.Task
-//	@Failure		400	{string}	error
-//	@Failure		500	{string}	error
+//	@Success		200	{object}	TaskPage
+//	@Header			200	{string}	ETag			"Hash of response"
+//	@Header			200	{string}	Link			"Urls of next and previous pages"
+//	@Header			200	{integer}	X-Total-Count	"Number of tasks matching filter"
+//	@Success		304
+//	@Failure		400	{object}	Problem
+//	@Failure		500	{object}	Problem
 func (s *Server) handleGetList(w http.ResponseWriter, r *http.Request) {
+	s.respondTaskList(w, r, nil)
+}
+
+// Responds with tasks matching query parameters of request, only with tasks of project with projectId if it is set.
+// Archived tasks are listed only in project
+func (s *Server) respondTaskList(w http.ResponseWriter, r *http.Request, projectId *int) {
 	w.Header().Set("Content-Type", "application/json")
-	var err error
-	var tasks []models.Task
-	var done *bool
+	query := r.URL.Query()
 
-	// See if "done" parameter was set (optional)
-	if r.URL.Query().Get("done") != "" {
-		buf_done, err := strconv.ParseBool(r.URL.Query().Get("done"))
-		if err != nil {
+	loc, err := s.requestLocation(r)
+	if err != nil {
+		s.respond(w, r, http.StatusBadRequest, nil, err)
+		return
+	}
+	filter, err := parseTaskFilter(query, loc)
+	if err != nil {
+		s.respond(w, r, http.StatusBadRequest, nil, err)
+		return
+	}
+	if projectId != nil {
+		filter.ProjectId = projectId
+		filter.IncludeArchived = true
+	}
+
+	// Cursor pagination
+	if query.Has("cursor") || query.Has("limit") {
+		cursor, cursorErr := decodeCursor(query.Get("cursor"))
+		limit, limitErr := parseLimit(query, "limit", defaultLimit)
+		var sortErr error
+		if !service.OrderedById(filter.Sort) {
+			sortErr = badParam("sort", errors.New("cursor pagination supports only order by id"))
+		}
+		if err = errors.Join(cursorErr, limitErr, sortErr); err != nil {
 			s.respond(w, r, http.StatusBadRequest, nil, err)
 			return
 		}
-		done = &buf_done
+		page, err := s.Service.GetListWithCursor(r.Context(), cursor, limit, filter)
+		if err != nil {
+			s.respond(w, r, http.StatusInternalServerError, nil, err)
+			return
+		}
+		inLocation(page.Tasks, loc)
+		res, links := newTaskPage(r, page, limit)
+		if links != "" {
+			w.Header().Set("Link", links)
+		}
+		w.Header().Set(totalCountHeader, strconv.Itoa(page.Total))
+		s.respond(w, r, http.StatusOK, res, nil)
+		return
+	}
+
+	envelope, err := wantsEnvelope(query)
+	if err != nil {
+		s.respond(w, r, http.StatusBadRequest, nil, err)
+		return
 	}
 
-	// Pagination parameters were not set, so selecting tasks only by status
-	if r.URL.Query().Get("page") == "" && r.URL.Query().Get("take") == "" {
-		tasks, err = s.Service.GetList(done)
+	// Pagination parameters were not set, so selecting all tasks matching filter
+	if query.Get("page") == "" && query.Get("take") == "" {
+		tasks, err := s.Service.GetList(r.Context(), filter)
 		if err != nil {
 			s.respond(w, r, http.StatusInternalServerError, nil, err)
 			return
 		}
-		s.respond(w, r, http.StatusOK, tasks, nil)
+		inLocation(tasks, loc)
+		s.respond(w, r, http.StatusOK, newTaskList(w, wholeList(tasks), envelope), nil)
 		return
 	}
 
-	// Any of pagination parameters were set
-	page, pageErr := strconv.Atoi(r.URL.Query().Get("page"))
-	take, takeErr := strconv.Atoi(r.URL.Query().Get("take"))
-	if err = errors.Join(takeErr, pageErr); err != nil {
+	// Any of deprecated pagination parameters were set
+	w.Header().Set("Deprecation", "true")
+	page, pageErr := strconv.Atoi(query.Get("page"))
+	if pageErr == nil && page < 1 {
+		pageErr = errors.New("must be positive")
+	}
+	take, takeErr := parseLimit(query, "take", 0)
+	if takeErr == nil && take == 0 {
+		takeErr = badParam("take", errors.New("is required"))
+	}
+	if err = errors.Join(badParam("page", pageErr), takeErr); err != nil {
 		s.respond(w, r, http.StatusBadRequest, nil, err)
 		return
 	}
 
-	tasks, err = s.Service.GetListWithPagination(page, take, done)
+	res, err := s.Service.GetListWithPagination(r.Context(), page, take, filter)
 	if err != nil {
 		s.respond(w, r, http.StatusInternalServerError, nil, err)
 		return
 	}
-	s.respond(w, r, http.StatusOK, tasks, nil)
-	return
+	inLocation(res.Tasks, loc)
+	s.respond(w, r, http.StatusOK, newTaskList(w, res, envelope), nil)
 }
 
 // GetTask godoc
 //
 //	@Summary		Get task by id
-//	@Description	Returns task with id from id path vparam. Returns error if no task with such id exists
+//	@Description	Returns task with id from id path vparam. Returns error if no task with such id exists.
+//	@Description	With expand=children task has tree of its subtasks in children.
+//	@Description	ETag is version of task with hash of response, as progress and role of task change without its version. If-Match of changes takes this ETag
 //	@Tags			Get
 //	@Accept			json
 //	@Produce		json
-//	@Param			id	path	int	true	"Task id"
+//	@Param			id				path	int		true	"Task id"
+//	@Param			expand			query	string	false	"Embed subtasks at any depth"	Enums(children)
+//	@Param			If-None-Match	header	string	false	"ETag of cached response"
+//	@Param			X-Timezone		header	string	false	"IANA timezone of dates and deadlines, e.g. Europe/Moscow (timezone from config by default)"
+//	@Param			tz				query	string	false	"Timezone if X-Timezone header is not set"
 //	@Router			/tasks/{id} [get]
 //	@Success		200	{object}	models.Task
-//	@Failure		400	{string}	error
-//	@Failure		500	{string}	error
+//	@Header			200	{string}	ETag	"Version of task and hash of response, e.g. \"3-af63bd4c8601b7be\""
+//	@Success		304
+//	@Failure		400	{object}	Problem
+//	@Failure		403	{object}	Problem
+//	@Failure		404	{object}	Problem
+//	@Failure		500	{object}	Problem
 func (s *Server) handleGet(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		s.respond(w, r, http.StatusBadRequest, nil, badParam("id", err))
+		return
+	}
+	if !s.authorize(w, r, id, service.OperationRead) {
+		return
+	}
+	loc, err := s.requestLocation(r)
 	if err != nil {
 		s.respond(w, r, http.StatusBadRequest, nil, err)
 		return
 	}
-	task, err := s.Service.Get(id)
+	expand := r.URL.Query().Get("expand")
+	if expand != "" && expand != "children" {
+		s.respond(w, r, http.StatusBadRequest, nil, badParam("expand", fmt.Errorf("unknown value %q, allowed: children", expand)))
+		return
+	}
+
+	var task *models.Task
+	if expand == "" {
+		task, err = s.Service.Get(r.Context(), id)
+	} else {
+		task, err = s.Service.GetWithChildren(r.Context(), id)
+	}
+	if err != nil {
+		s.respond(w, r, http.StatusInternalServerError, nil, err)
+		return
+	}
+	tasks := []models.Task{*task}
+	inLocation(tasks, loc)
+	etag, err := taskETag(tasks[0])
 	if err != nil {
 		s.respond(w, r, http.StatusInternalServerError, nil, err)
 		return
 	}
-	s.respond(w, r, http.StatusOK, task, nil)
+	w.Header().Set("ETag", etag)
+	s.respond(w, r, http.StatusOK, tasks[0], nil)
 }
 
 // DeleteTask godoc
 //
 //	@Summary		Delete task by id
-//	@Description	Deletes task by id from id path param
+//	@Description	Moves task with id from id path param to trash. Tasks in trash are not listed and can be restored until they are purged after retention period from config
 //	@Tags			Delete
 //	@Accept			json
 //	@Produce		json
-//	@Param			id	path	int	true	"Task id"
+//	@Param			id			path	int		true	"Task id"
+//	@Param			If-Match	header	string	false	"ETag of task or its version in quotes, required if enabled in config"
 //	@Router			/tasks/{id} [delete]
 //	@Success		200
-//	@Failure		400	{string}	error
-//	@Failure		500	{string}	error
+//	@Failure		400	{object}	Problem
+//	@Failure		403	{object}	Problem
+//	@Failure		404	{object}	Problem
+//	@Failure		412	{object}	Problem
+//	@Failure		428	{object}	Problem
+//	@Failure		500	{object}	Problem
 func (s *Server) handleDelete(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		s.respond(w, r, http.StatusBadRequest, nil, badParam("id", err))
+		return
+	}
+	if !s.authorize(w, r, id, service.OperationDelete) {
+		return
+	}
+	version, err := s.ifMatchVersion(r)
 	if err != nil {
 		s.respond(w, r, http.StatusBadRequest, nil, err)
 		return
 	}
-	if err := s.Service.Delete(id); err != nil {
+	if err := s.Service.Delete(r.Context(), id, version); err != nil {
 		s.respond(w, r, http.StatusInternalServerError, nil, err)
 		return
 	}
@@ -191,29 +421,131 @@ func (s *Server) handleDelete(w http.ResponseWriter, r *http.Request) {
 // UpdateTask godoc
 //
 //	@Summary		Update task
-//	@Description	Updates task using data from body and with id from path param. If some fields of body struct are omitted, they will be overwritten by default values
+//	@Description	Updates task using data from body and with id from path param. If some fields of body struct are omitted, they will be overwritten by default values. Task is validated the same way as on create, id may be omitted or equal to id from path. Use PATCH to change only some fields
 //	@Tags			Update
 //	@Accept			json
 //	@Produce		json
-//	@Param			id		path	int			true	"Task id"
-//	@Param			task	body	models.Task	true	"Task data"
+//	@Param			id			path	int			true	"Task id"
+//	@Param			task		body	models.Task	true	"Task data"
+//	@Param			If-Match	header	string		false	"ETag of task or its version in quotes, required if enabled in config"
+//	@Param			X-Timezone	header	string		false	"IANA timezone of dates and deadlines, e.g. Europe/Moscow (timezone from config by default)"
+//	@Param			tz			query	string		false	"Timezone if X-Timezone header is not set"
 //	@Router			/tasks/{id} [put]
 //	@Success		200
-//	@Failure		400	{string}	error
-//	@Failure		500	{string}	error
+//	@Failure		400	{object}	Problem
+//	@Failure		403	{object}	Problem
+//	@Failure		404	{object}	Problem
+//	@Failure		422	{object}	Problem
+//	@Failure		412	{object}	Problem
+//	@Failure		428	{object}	Problem
+//	@Failure		500	{object}	Problem
 func (s *Server) handleUpdate(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		s.respond(w, r, http.StatusBadRequest, nil, badParam("id", err))
+		return
+	}
+	if !s.authorize(w, r, id, service.OperationUpdate) {
+		return
+	}
+	version, err := s.ifMatchVersion(r)
 	if err != nil {
 		s.respond(w, r, http.StatusBadRequest, nil, err)
 		return
 	}
-	req := models.Task{}
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+	loc, err := s.requestLocation(r)
+	if err != nil {
+		s.respond(w, r, http.StatusBadRequest, nil, err)
+		return
+	}
+	req, err := decodeTask(r.Body, loc)
+	if err != nil {
 		s.respond(w, r, http.StatusBadRequest, nil, err)
 		return
 	}
-	if err := s.Service.Update(id, req); err != nil {
+	if err := s.Service.Update(r.Context(), id, version, req); err != nil {
+		s.respond(w, r, http.StatusInternalServerError, nil, err)
+		return
+	}
+	s.respond(w, r, http.StatusOK, nil, nil)
+}
+
+// PatchTask godoc
+//
+//	@Summary		Patch task
+//	@Description	Updates only fields present in body of task with id from path param.
+//	@Description	With "application/merge-patch+json" (or "application/json") body is RFC 7396 JSON Merge Patch: null resets field to default value.
+//	@Description	With "application/json-patch+json" body is RFC 6902 JSON Patch, array of server.PatchOperation with paths /header, /description, /deadline, /done and operations add, replace, remove, test.
+//	@Description	Task is changed only if it still has version which test operations were evaluated against, otherwise 412 is returned.
+//	@Description	Only changed fields are validated
+//	@Tags			Update
+//	@Accept			application/merge-patch+json
+//	@Accept			application/json-patch+json
+//	@Produce		json
+//	@Param			id			path	int					true	"Task id"
+//	@Param			patch		body	models.TaskPatch	true	"Fields to change"
+//	@Param			If-Match	header	string				false	"ETag of task or its version in quotes, required if enabled in config"
+//	@Param			X-Timezone	header	string				false	"IANA timezone of dates and deadlines, e.g. Europe/Moscow (timezone from config by default)"
+//	@Param			tz			query	string				false	"Timezone if X-Timezone header is not set"
+//	@Router			/tasks/{id} [patch]
+//	@Success		200
+//	@Failure		400	{object}	Problem
+//	@Failure		403	{object}	Problem
+//	@Failure		404	{object}	Problem
+//	@Failure		409	{object}	Problem
+//	@Failure		415	{object}	Problem
+//	@Failure		422	{object}	Problem
+//	@Failure		412	{object}	Problem
+//	@Failure		428	{object}	Problem
+//	@Failure		500	{object}	Problem
+func (s *Server) handlePatch(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		s.respond(w, r, http.StatusBadRequest, nil, badParam("id", err))
+		return
+	}
+	if !s.authorize(w, r, id, service.OperationUpdate) {
+		return
+	}
+	version, err := s.ifMatchVersion(r)
+	if err != nil {
+		s.respond(w, r, http.StatusBadRequest, nil, err)
+		return
+	}
+	loc, err := s.requestLocation(r)
+	if err != nil {
+		s.respond(w, r, http.StatusBadRequest, nil, err)
+		return
+	}
+	// Task is read once for all test operations
+	var tested *models.Task
+	patch, err := decodePatch(r.Header.Get("Content-Type"), r.Body, id, loc, func() (*models.Task, error) {
+		if tested != nil {
+			return tested, nil
+		}
+		task, err := s.Service.Get(r.Context(), id)
+		tested = task
+		return task, err
+	})
+	if errors.Is(err, errUnsupportedPatch) {
+		s.respond(w, r, http.StatusUnsupportedMediaType, nil, err)
+		return
+	}
+	if err != nil {
+		s.respond(w, r, http.StatusBadRequest, nil, err)
+		return
+	}
+	// Patch is applied only to the version of task which passed tests
+	if tested != nil {
+		if version != 0 && version != tested.Version {
+			s.respond(w, r, http.StatusInternalServerError, nil, &service.VersionMismatchError{Id: id, Expected: version})
+			return
+		}
+		version = tested.Version
+	}
+	if err := s.Service.Patch(r.Context(), id, version, patch); err != nil {
 		s.respond(w, r, http.StatusInternalServerError, nil, err)
 		return
 	}
@@ -223,18 +555,27 @@ func (s *Server) handleUpdate(w http.ResponseWriter, r *http.Request) {
 // GetByDate godoc
 //
 //	@Summary		Get tasks by date
-//	@Description	Returns tasks by date from path params and optional filter by status (done)
+//	@Description	Returns tasks by date from path params and optional filter by status (done).
+//	@Description	Unless done=true, virtual future occurrences of open recurring tasks follow them ordered by deadline, they have id 0 and id of their task in occurrence_of.
+//	@Description	Number of tasks is sent in X-Total-Count header. With envelope=true array is wrapped in server.TaskList
 //	@Tags			GetList
 //	@Accept			json
 //	@Produce		json
-//	@Param			year	path	int		true	"Year"
-//	@Param			month	path	int		true	"Month"
-//	@Param			day		path	int		true	"Day"
-//	@Param			done	query	bool	false	"Task status"
+//	@Param			year			path	int		true	"Year"
+//	@Param			month			path	int		true	"Month"
+//	@Param			day				path	int		true	"Day"
+//	@Param			done			query	bool	false	"Task status"
+//	@Param			envelope		query	bool	false	"Wrap array of tasks in TaskList"
+//	@Param			If-None-Match	header	string	false	"ETag of cached response"
+//	@Param			X-Timezone		header	string	false	"IANA timezone of dates and deadlines, e.g. Europe/Moscow (timezone from config by default)"
+//	@Param			tz				query	string	false	"Timezone if X-Timezone header is not set"
 //	@Router			/tasks/byDate/{year}-{month}-{day} [get]
 //	@Success		200	{array}		models.Task
-//	@Failure		400	{string}	error
-//	@Failure		500	{string}	error
+//	@Header			200	{string}	ETag			"Hash of response"
+//	@Header			200	{integer}	X-Total-Count	"Number of tasks"
+//	@Success		304
+//	@Failure		400	{object}	Problem
+//	@Failure		500	{object}	Problem
 func (s *Server) handleGetByDate(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 
@@ -242,7 +583,17 @@ func (s *Server) handleGetByDate(w http.ResponseWriter, r *http.Request) {
 	var done bool
 	var tasks []models.Task
 
-	date, err := getDateFromURL(r)
+	loc, err := s.requestLocation(r)
+	if err != nil {
+		s.respond(w, r, http.StatusBadRequest, nil, err)
+		return
+	}
+	date, err := getDateFromURL(r, loc)
+	if err != nil {
+		s.respond(w, r, http.StatusBadRequest, nil, err)
+		return
+	}
+	envelope, err := wantsEnvelope(r.URL.Query())
 	if err != nil {
 		s.respond(w, r, http.StatusBadRequest, nil, err)
 		return
@@ -250,24 +601,232 @@ func (s *Server) handleGetByDate(w http.ResponseWriter, r *http.Request) {
 
 	// if status was not set - get all by date
 	if r.URL.Query().Get("done") == "" {
-		tasks, err = s.Service.GetByDateAndStatus(*date, false, false)
+		tasks, err = s.Service.GetByDateAndStatus(r.Context(), *date, false, false)
 		if err != nil {
 			s.respond(w, r, http.StatusInternalServerError, nil, err)
 			return
 		}
-		s.respond(w, r, http.StatusOK, tasks, nil)
+		inLocation(tasks, loc)
+		s.respond(w, r, http.StatusOK, newTaskList(w, wholeList(tasks), envelope), nil)
 		return
 	}
 	// if status was set - get all by date and status
 	done, err = strconv.ParseBool(r.URL.Query().Get("done"))
 	if err != nil {
+		s.respond(w, r, http.StatusBadRequest, nil, badParam("done", err))
+		return
+	}
+	tasks, err = s.Service.GetByDateAndStatus(r.Context(), *date, done, true)
+	if err != nil {
+		s.respond(w, r, http.StatusInternalServerError, nil, err)
+		return
+	}
+	inLocation(tasks, loc)
+	s.respond(w, r, http.StatusOK, newTaskList(w, wholeList(tasks), envelope), nil)
+}
+
+// GetRange godoc
+//
+//	@Summary		Get tasks by date range
+//	@Description	Returns tasks with deadline from first to last date inclusive grouped by day, with optional filter by status (done).
+//	@Description	Every day of range is present, range can not be longer than 366 days. Virtual occurrences of recurring tasks are included as in /tasks/byDate
+//	@Tags			Calendar
+//	@Accept			json
+//	@Produce		json
+//	@Param			from			query	string	true	"First date (YYYY-MM-DD)"
+//	@Param			to				query	string	true	"Last date (YYYY-MM-DD)"
+//	@Param			done			query	bool	false	"Task status"
+//	@Param			If-None-Match	header	string	false	"ETag of cached response"
+//	@Param			X-Timezone		header	string	false	"IANA timezone of dates and deadlines, e.g. Europe/Moscow (timezone from config by default)"
+//	@Param			tz				query	string	false	"Timezone if X-Timezone header is not set"
+//	@Router			/tasks/range [get]
+//	@Success		200	{array}		CalendarDay
+//	@Header			200	{string}	ETag	"Hash of response"
+//	@Success		304
+//	@Failure		400	{object}	Problem
+//	@Failure		500	{object}	Problem
+func (s *Server) handleGetRange(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	query := r.URL.Query()
+	from, fromErr := parseISODate("from", query.Get("from"))
+	to, toErr := parseISODate("to", query.Get("to"))
+	if err := errors.Join(fromErr, toErr); err != nil {
 		s.respond(w, r, http.StatusBadRequest, nil, err)
 		return
 	}
-	tasks, err = s.Service.GetByDateAndStatus(*date, done, true)
+	if to.Before(from) {
+		s.respond(w, r, http.StatusBadRequest, nil, badParam("to", errors.New("must not be earlier than from")))
+		return
+	}
+	if to.Sub(from) >= maxCalendarDays*24*time.Hour {
+		s.respond(w, r, http.StatusBadRequest, nil, badParam("to", fmt.Errorf("range must not be longer than %d days", maxCalendarDays)))
+		return
+	}
+	s.respondCalendar(w, r, from, to)
+}
+
+// GetWeek godoc
+//
+//	@Summary		Get tasks by week
+//	@Description	Returns tasks with deadline in ISO 8601 week (from monday to sunday) grouped by day, with optional filter by status (done).
+//	@Description	Virtual occurrences of recurring tasks are included as in /tasks/byDate
+//	@Tags			Calendar
+//	@Accept			json
+//	@Produce		json
+//	@Param			week			path	string	true	"ISO week (YYYY-Www), e.g. 2024-W05"
+//	@Param			done			query	bool	false	"Task status"
+//	@Param			If-None-Match	header	string	false	"ETag of cached response"
+//	@Param			X-Timezone		header	string	false	"IANA timezone of dates and deadlines, e.g. Europe/Moscow (timezone from config by default)"
+//	@Param			tz				query	string	false	"Timezone if X-Timezone header is not set"
+//	@Router			/tasks/week/{week} [get]
+//	@Success		200	{array}		CalendarDay
+//	@Header			200	{string}	ETag	"Hash of response"
+//	@Success		304
+//	@Failure		400	{object}	Problem
+//	@Failure		500	{object}	Problem
+func (s *Server) handleGetWeek(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	first, last, err := parseISOWeek("week", chi.URLParam(r, "week"))
+	if err != nil {
+		s.respond(w, r, http.StatusBadRequest, nil, err)
+		return
+	}
+	s.respondCalendar(w, r, first, last)
+}
+
+// GetMonth godoc
+//
+//	@Summary		Get tasks by month
+//	@Description	Returns tasks with deadline in month grouped by day, with optional filter by status (done).
+//	@Description	Virtual occurrences of recurring tasks are included as in /tasks/byDate
+//	@Tags			Calendar
+//	@Accept			json
+//	@Produce		json
+//	@Param			month			path	string	true	"Month (YYYY-MM), e.g. 2024-02"
+//	@Param			done			query	bool	false	"Task status"
+//	@Param			If-None-Match	header	string	false	"ETag of cached response"
+//	@Param			X-Timezone		header	string	false	"IANA timezone of dates and deadlines, e.g. Europe/Moscow (timezone from config by default)"
+//	@Param			tz				query	string	false	"Timezone if X-Timezone header is not set"
+//	@Router			/tasks/month/{month} [get]
+//	@Success		200	{array}		CalendarDay
+//	@Header			200	{string}	ETag	"Hash of response"
+//	@Success		304
+//	@Failure		400	{object}	Problem
+//	@Failure		500	{object}	Problem
+func (s *Server) handleGetMonth(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	first, last, err := parseISOMonth("month", chi.URLParam(r, "month"))
+	if err != nil {
+		s.respond(w, r, http.StatusBadRequest, nil, err)
+		return
+	}
+	s.respondCalendar(w, r, first, last)
+}
+
+// GetTrash godoc
+//
+//	@Summary		Get deleted tasks
+//	@Description	Returns tasks in trash with time of deletion, recently deleted first
+//	@Tags			Trash
+//	@Accept			json
+//	@Produce		json
+//	@Param			X-Timezone		header	string	false	"IANA timezone of dates and deadlines, e.g. Europe/Moscow (timezone from config by default)"
+//	@Param			tz				query	string	false	"Timezone if X-Timezone header is not set"
+//	@Param			If-None-Match	header	string	false	"ETag of cached response"
+//	@Router			/tasks/trash [get]
+//	@Success		200	{array}		models.Task
+//	@Header			200	{string}	ETag	"Hash of response"
+//	@Success		304
+//	@Failure		400	{object}	Problem
+//	@Failure		500	{object}	Problem
+func (s *Server) handleGetTrash(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	loc, err := s.requestLocation(r)
+	if err != nil {
+		s.respond(w, r, http.StatusBadRequest, nil, err)
+		return
+	}
+	tasks, err := s.Service.GetTrash(r.Context())
 	if err != nil {
 		s.respond(w, r, http.StatusInternalServerError, nil, err)
 		return
 	}
+	if tasks == nil {
+		tasks = []models.Task{}
+	}
+	inLocation(tasks, loc)
 	s.respond(w, r, http.StatusOK, tasks, nil)
 }
+
+// RestoreTask godoc
+//
+//	@Summary		Restore deleted task
+//	@Description	Moves task with id from id path param back from trash
+//	@Tags			Trash
+//	@Accept			json
+//	@Produce		json
+//	@Param			id	path	int	true	"Task id"
+//	@Router			/tasks/{id}/restore [post]
+//	@Success		200
+//	@Failure		400	{object}	Problem
+//	@Failure		403	{object}	Problem
+//	@Failure		404	{object}	Problem
+//	@Failure		500	{object}	Problem
+func (s *Server) handleRestore(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		s.respond(w, r, http.StatusBadRequest, nil, badParam("id", err))
+		return
+	}
+	if !s.authorize(w, r, id, service.OperationDelete) {
+		return
+	}
+	if err := s.Service.Restore(r.Context(), id); err != nil {
+		s.respond(w, r, http.StatusInternalServerError, nil, err)
+		return
+	}
+	s.respond(w, r, http.StatusOK, nil, nil)
+}
+
+// GetHistory godoc
+//
+//	@Summary		Get history of task
+//	@Description	Returns changes of task with id from id path param, oldest first: who made them, when, in which request and which fields were changed.
+//	@Description	History is kept for tasks in trash and after they are purged. Users task is shared with see its history until it is purged, then only its owner
+//	@Tags			History
+//	@Accept			json
+//	@Produce		json
+//	@Param			id				path	int		true	"Task id"
+//	@Param			If-None-Match	header	string	false	"ETag of cached response"
+//	@Param			X-Timezone		header	string	false	"IANA timezone of times of changes, e.g. Europe/Moscow (timezone from config by default)"
+//	@Param			tz				query	string	false	"Timezone if X-Timezone header is not set"
+//	@Router			/tasks/{id}/history [get]
+//	@Success		200	{array}		models.TaskEvent
+//	@Header			200	{string}	ETag	"Hash of response"
+//	@Success		304
+//	@Failure		400	{object}	Problem
+//	@Failure		404	{object}	Problem
+//	@Failure		500	{object}	Problem
+func (s *Server) handleGetHistory(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		s.respond(w, r, http.StatusBadRequest, nil, badParam("id", err))
+		return
+	}
+	loc, err := s.requestLocation(r)
+	if err != nil {
+		s.respond(w, r, http.StatusBadRequest, nil, err)
+		return
+	}
+	events, err := s.Service.GetHistory(r.Context(), id)
+	if err != nil {
+		s.respond(w, r, http.StatusInternalServerError, nil, err)
+		return
+	}
+	for i := range events {
+		events[i].CreatedAt = events[i].CreatedAt.In(loc)
+	}
+	s.respond(w, r, http.StatusOK, events, nil)
+}
@@ -0,0 +1,1636 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/O-Tempora/SberIT/config"
+	"github.com/O-Tempora/SberIT/internal/models"
+	"github.com/O-Tempora/SberIT/internal/service"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// User which authenticates test requests, registered by newTestServer
+var testUser = models.Credentials{Username: "tester", Password: "password"}
+
+func newTestServer() *Server {
+	s := InitServer(config.Config{
+		Port:  8000,
+		Users: config.Users{PasswordCost: bcrypt.MinCost},
+		Auth:  config.Auth{Secret: "test secret", Issuer: "test"},
+	}).
+		WithRepository(service.NewMemoryRepository()).
+		WithTokens()
+	s.Logger = zerolog.New(io.Discard)
+	s.InitRouter()
+	s.Service.Register(context.Background(), testUser)
+	return s
+}
+
+// Returns context of test user, which is the first registered user
+func testContext() context.Context {
+	return service.WithPrincipal(context.Background(), service.Principal{UserId: 1, Username: testUser.Username})
+}
+
+func doRequest(s *Server, method, url string, body interface{}) *httptest.ResponseRecorder {
+	var buf bytes.Buffer
+	if body != nil {
+		json.NewEncoder(&buf).Encode(body)
+	}
+	req := httptest.NewRequest(method, url, &buf)
+	req.SetBasicAuth(testUser.Username, testUser.Password)
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, req)
+	return rec
+}
+
+func doRawRequest(s *Server, method, url, contentType, body string) *httptest.ResponseRecorder {
+	return doRequestWithHeaders(s, method, url, body, map[string]string{"Content-Type": contentType})
+}
+
+func doRequestWithHeaders(s *Server, method, url, body string, headers map[string]string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, url, bytes.NewBufferString(body))
+	req.SetBasicAuth(testUser.Username, testUser.Password)
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestHandleCreateAndGet(t *testing.T) {
+	s := newTestServer()
+	deadline := time.Now().Add(48 * time.Hour)
+
+	rec := doRequest(s, http.MethodPost, "/tasks/", models.Task{
+		Header:      "Header",
+		Description: "Description",
+		Deadline:    deadline,
+	})
+	if !assert.Equal(t, http.StatusCreated, rec.Code) {
+		return
+	}
+	var id int
+	if assert.Nil(t, json.NewDecoder(rec.Body).Decode(&id)) {
+		assert.Equal(t, 1, id)
+	}
+
+	rec = doRequest(s, http.MethodGet, "/tasks/1", nil)
+	var task models.Task
+	if assert.Equal(t, http.StatusOK, rec.Code) && assert.Nil(t, json.NewDecoder(rec.Body).Decode(&task)) {
+		assert.Equal(t, 1, task.Id)
+		assert.Equal(t, "Header", task.Header)
+		assert.Equal(t, "Description", task.Description)
+		assert.Equal(t, deadline.Day(), task.Deadline.Day())
+	}
+}
+
+func TestHandleGetList(t *testing.T) {
+	s := newTestServer()
+	deadline := time.Now().Add(48 * time.Hour)
+	for _, done := range []bool{false, true, true} {
+		s.Service.Create(testContext(), models.Task{Header: "Header", Deadline: deadline, Done: done})
+	}
+
+	var test_cases = []struct {
+		url             string
+		code            int
+		expected_length int
+		expected_total  string
+	}{
+		{url: "/tasks/", code: http.StatusOK, expected_length: 3, expected_total: "3"},
+		{url: "/tasks/?done=true", code: http.StatusOK, expected_length: 2, expected_total: "2"},
+		{url: "/tasks/?done=false", code: http.StatusOK, expected_length: 1, expected_total: "1"},
+		{url: "/tasks/?page=1&take=2", code: http.StatusOK, expected_length: 2, expected_total: "3"},
+		{url: "/tasks/?page=2&take=2&done=true", code: http.StatusOK, expected_length: 0, expected_total: "2"},
+		{url: "/tasks/?done=maybe", code: http.StatusBadRequest},
+		{url: "/tasks/?envelope=maybe", code: http.StatusBadRequest},
+		{url: "/tasks/?page=1", code: http.StatusBadRequest},
+		{url: "/tasks/?page=0&take=2", code: http.StatusBadRequest},
+		{url: "/tasks/?page=1&take=1000", code: http.StatusBadRequest},
+	}
+
+	for _, tc := range test_cases {
+		rec := doRequest(s, http.MethodGet, tc.url, nil)
+		if assert.Equal(t, tc.code, rec.Code, tc.url) && tc.code == http.StatusOK {
+			assert.Equal(t, tc.expected_total, rec.Header().Get("X-Total-Count"), tc.url)
+			var tasks []models.Task
+			if assert.Nil(t, json.NewDecoder(rec.Body).Decode(&tasks)) {
+				assert.Equal(t, tc.expected_length, len(tasks), tc.url)
+			}
+		}
+	}
+}
+
+func TestHandleGetListEnvelope(t *testing.T) {
+	s := newTestServer()
+	deadline := time.Now().Add(48 * time.Hour)
+	for _, done := range []bool{false, true, true} {
+		s.Service.Create(testContext(), models.Task{Header: "Header", Deadline: deadline, Done: done})
+	}
+
+	var test_cases = []struct {
+		url      string
+		expected TaskList
+	}{
+		{
+			url:      "/tasks/?envelope=true",
+			expected: TaskList{Total: 3, Page: 1, PageSize: 3},
+		},
+		{
+			url:      "/tasks/?envelope=true&page=1&take=2",
+			expected: TaskList{Total: 3, Page: 1, PageSize: 2, HasMore: true},
+		},
+		{
+			url:      "/tasks/?envelope=true&page=2&take=2",
+			expected: TaskList{Total: 3, Page: 2, PageSize: 2},
+		},
+		{
+			url:      "/tasks/?envelope=true&page=1&take=1&done=true",
+			expected: TaskList{Total: 2, Page: 1, PageSize: 1, HasMore: true},
+		},
+		{
+			url:      "/tasks/?envelope=true&page=3&take=1&done=true",
+			expected: TaskList{Total: 2, Page: 3, PageSize: 1},
+		},
+	}
+
+	for _, tc := range test_cases {
+		rec := doRequest(s, http.MethodGet, tc.url, nil)
+		var res TaskList
+		if assert.Equal(t, http.StatusOK, rec.Code, tc.url) && assert.Nil(t, json.NewDecoder(rec.Body).Decode(&res)) {
+			assert.NotNil(t, res.Items, tc.url)
+			res.Items = nil
+			assert.Equal(t, tc.expected, res, tc.url)
+		}
+	}
+}
+
+func TestHandleGetListFiltered(t *testing.T) {
+	s := newTestServer()
+	soon := time.Now().Add(48 * time.Hour)
+	later := time.Now().Add(96 * time.Hour)
+	s.Service.Create(testContext(), models.Task{Header: "Buy milk", Deadline: later})
+	s.Service.Create(testContext(), models.Task{Header: "Write report", Description: "Quarterly", Deadline: soon})
+	s.Service.Create(testContext(), models.Task{Header: "Buy bread", Deadline: soon, Done: true})
+	// Validation does not allow past deadlines, so overdue task is stored bypassing service
+	s.Service.Repo.Create(testContext(), models.Task{Header: "Call plumber", Deadline: time.Now().Add(-48 * time.Hour)})
+
+	var test_cases = []struct {
+		url          string
+		code         int
+		expected_ids []int
+	}{
+		{url: "/tasks/?q=buy&sort=-id", code: http.StatusOK, expected_ids: []int{3, 1}},
+		{url: "/tasks/?q=QUARTER", code: http.StatusOK, expected_ids: []int{2}},
+		{url: "/tasks/?sort=deadline,-id", code: http.StatusOK, expected_ids: []int{4, 3, 2, 1}},
+		{url: "/tasks/?sort=-done,header", code: http.StatusOK, expected_ids: []int{3, 1, 4, 2}},
+		{url: "/tasks/?deadline_from=" + later.Format("2006-01-02"), code: http.StatusOK, expected_ids: []int{1}},
+		{url: "/tasks/?deadline_to=" + soon.Format("2006-01-02") + "&done=false", code: http.StatusOK, expected_ids: []int{2, 4}},
+		{url: "/tasks/?overdue=true", code: http.StatusOK, expected_ids: []int{4}},
+		{url: "/tasks/?overdue=false&q=buy&done=false", code: http.StatusOK, expected_ids: []int{1}},
+		{url: "/tasks/?page=1&take=1&sort=-id", code: http.StatusOK, expected_ids: []int{4}},
+		{url: "/tasks/?sort=name", code: http.StatusBadRequest},
+		{url: "/tasks/?sort=id%3Bdrop", code: http.StatusBadRequest},
+		{url: "/tasks/?overdue=yes", code: http.StatusBadRequest},
+		{url: "/tasks/?deadline_from=2023-13-01", code: http.StatusBadRequest},
+		{url: "/tasks/?deadline_from=2023-12-05&deadline_to=2023-12-04", code: http.StatusBadRequest},
+		{url: "/tasks/?sort=-id&limit=2", code: http.StatusBadRequest},
+	}
+
+	for _, tc := range test_cases {
+		rec := doRequest(s, http.MethodGet, tc.url, nil)
+		if assert.Equal(t, tc.code, rec.Code, tc.url) && tc.code == http.StatusOK {
+			var tasks []models.Task
+			if assert.Nil(t, json.NewDecoder(rec.Body).Decode(&tasks)) {
+				var ids []int
+				for _, task := range tasks {
+					ids = append(ids, task.Id)
+				}
+				assert.Equal(t, tc.expected_ids, ids, tc.url)
+			}
+		}
+	}
+
+	var page TaskPage
+	rec := doRequest(s, http.MethodGet, "/tasks/?sort=id&limit=2&q=buy", nil)
+	if assert.Equal(t, http.StatusOK, rec.Code) && assert.Nil(t, json.NewDecoder(rec.Body).Decode(&page)) {
+		assert.Equal(t, 2, page.Total)
+		assert.Equal(t, 2, len(page.Items))
+	}
+}
+
+func TestHandleGetByDate(t *testing.T) {
+	s := newTestServer()
+	deadline := time.Now().Add(48 * time.Hour)
+	s.Service.Create(testContext(), models.Task{Header: "Header", Deadline: deadline, Done: true})
+	s.Service.Create(testContext(), models.Task{Header: "Header", Deadline: deadline.Add(24 * time.Hour)})
+
+	rec := doRequest(s, http.MethodGet, "/tasks/byDate/"+deadline.Format("2006-01-02"), nil)
+	var tasks []models.Task
+	if assert.Equal(t, http.StatusOK, rec.Code) && assert.Nil(t, json.NewDecoder(rec.Body).Decode(&tasks)) {
+		assert.Equal(t, 1, len(tasks))
+	}
+
+	rec = doRequest(s, http.MethodGet, "/tasks/byDate/"+deadline.Format("2006-01-02")+"?done=false", nil)
+	tasks = nil
+	if assert.Equal(t, http.StatusOK, rec.Code) && assert.Nil(t, json.NewDecoder(rec.Body).Decode(&tasks)) {
+		assert.Equal(t, 0, len(tasks))
+	}
+
+	for _, url := range []string{"/tasks/byDate/2023-13-01", "/tasks/byDate/2023-00-10", "/tasks/byDate/2023-02-29", "/tasks/byDate/2023-04-31", "/tasks/byDate/2023-04-0"} {
+		rec = doRequest(s, http.MethodGet, url, nil)
+		assert.Equal(t, http.StatusBadRequest, rec.Code, url)
+	}
+}
+
+func TestHandleCalendar(t *testing.T) {
+	s := newTestServer()
+	// Deadlines are in the past, so tasks are stored bypassing validation of service
+	for _, date := range []string{"2024-02-28", "2024-02-29", "2024-03-04"} {
+		deadline, _ := time.Parse("2006-01-02", date)
+		s.Service.Repo.Create(testContext(), models.Task{Header: "Header", Deadline: deadline, Done: date == "2024-02-29"})
+	}
+
+	var test_cases = []struct {
+		url            string
+		code           int
+		expected_dates []string
+		expected_ids   [][]int
+	}{
+		{
+			url:            "/tasks/range?from=2024-02-28&to=2024-03-01",
+			code:           http.StatusOK,
+			expected_dates: []string{"2024-02-28", "2024-02-29", "2024-03-01"},
+			expected_ids:   [][]int{{1}, {2}, {}},
+		},
+		{
+			url:            "/tasks/range?from=2024-02-28&to=2024-02-29&done=false",
+			code:           http.StatusOK,
+			expected_dates: []string{"2024-02-28", "2024-02-29"},
+			expected_ids:   [][]int{{1}, {}},
+		},
+		{
+			url:            "/tasks/week/2024-W10",
+			code:           http.StatusOK,
+			expected_dates: []string{"2024-03-04", "2024-03-05", "2024-03-06", "2024-03-07", "2024-03-08", "2024-03-09", "2024-03-10"},
+			expected_ids:   [][]int{{3}, {}, {}, {}, {}, {}, {}},
+		},
+		{url: "/tasks/range?from=2024-02-28", code: http.StatusBadRequest},
+		{url: "/tasks/range?from=2024-03-01&to=2024-02-28", code: http.StatusBadRequest},
+		{url: "/tasks/range?from=2023-02-29&to=2023-03-01", code: http.StatusBadRequest},
+		{url: "/tasks/range?from=2024-1-01&to=2024-01-02", code: http.StatusBadRequest},
+		{url: "/tasks/range?from=2024-01-01&to=2025-01-01", code: http.StatusBadRequest},
+		{url: "/tasks/range?from=2024-01-01&to=2024-01-02&done=maybe", code: http.StatusBadRequest},
+		{url: "/tasks/week/2024-W54", code: http.StatusBadRequest},
+		{url: "/tasks/week/2021-W53", code: http.StatusBadRequest},
+		{url: "/tasks/week/2024-W00", code: http.StatusBadRequest},
+		{url: "/tasks/week/2024-W1", code: http.StatusBadRequest},
+		{url: "/tasks/month/2024-13", code: http.StatusBadRequest},
+		{url: "/tasks/month/2024-00", code: http.StatusBadRequest},
+		{url: "/tasks/month/2024-2", code: http.StatusBadRequest},
+	}
+
+	for _, tc := range test_cases {
+		rec := doRequest(s, http.MethodGet, tc.url, nil)
+		var days []CalendarDay
+		if assert.Equal(t, tc.code, rec.Code, tc.url) && tc.code == http.StatusOK && assert.Nil(t, json.NewDecoder(rec.Body).Decode(&days)) {
+			var dates []string
+			var ids [][]int
+			for _, day := range days {
+				dates = append(dates, day.Date)
+				dayIds := []int{}
+				for _, task := range day.Tasks {
+					dayIds = append(dayIds, task.Id)
+				}
+				ids = append(ids, dayIds)
+			}
+			assert.Equal(t, tc.expected_dates, dates, tc.url)
+			assert.Equal(t, tc.expected_ids, ids, tc.url)
+		}
+	}
+
+	var days []CalendarDay
+	rec := doRequest(s, http.MethodGet, "/tasks/month/2024-02", nil)
+	if assert.Equal(t, http.StatusOK, rec.Code) && assert.Nil(t, json.NewDecoder(rec.Body).Decode(&days)) && assert.Equal(t, 29, len(days)) {
+		assert.Equal(t, "2024-02-01", days[0].Date)
+		assert.Equal(t, 1, len(days[27].Tasks))
+		assert.Equal(t, 1, len(days[28].Tasks))
+	}
+}
+
+func TestParseISOWeek(t *testing.T) {
+	var test_cases = []struct {
+		value    string
+		monday   string
+		is_error bool
+	}{
+		{value: "2024-W01", monday: "2024-01-01"},
+		{value: "2021-W01", monday: "2021-01-04"},
+		{value: "2020-W53", monday: "2020-12-28"},
+		{value: "2026-W01", monday: "2025-12-29"},
+		{value: "2026-W53", monday: "2026-12-28"},
+		{value: "2023-W53", is_error: true},
+		{value: "2024W01", is_error: true},
+		{value: "24-W01", is_error: true},
+	}
+
+	for _, tc := range test_cases {
+		monday, sunday, err := parseISOWeek("week", tc.value)
+		if tc.is_error {
+			assert.NotNil(t, err, tc.value)
+		} else if assert.Nil(t, err, tc.value) {
+			assert.Equal(t, tc.monday, monday.Format("2006-01-02"), tc.value)
+			assert.Equal(t, time.Sunday, sunday.Weekday(), tc.value)
+		}
+	}
+}
+
+func TestHandleUpdateAndDelete(t *testing.T) {
+	s := newTestServer()
+	id, _ := s.Service.Create(testContext(), models.Task{Header: "Header", Deadline: time.Now().Add(48 * time.Hour)})
+
+	rec := doRequest(s, http.MethodPut, "/tasks/1", models.Task{
+		Header:   "Updated",
+		Deadline: time.Now().Add(72 * time.Hour),
+		Done:     true,
+	})
+	if assert.Equal(t, http.StatusOK, rec.Code) {
+		task, err := s.Service.Get(testContext(), id)
+		if assert.Nil(t, err) {
+			assert.Equal(t, "Updated", task.Header)
+			assert.True(t, task.Done)
+		}
+	}
+
+	rec = doRequest(s, http.MethodDelete, "/tasks/1", nil)
+	if assert.Equal(t, http.StatusOK, rec.Code) {
+		tasks, err := s.Service.GetList(testContext(), service.TaskFilter{})
+		if assert.Nil(t, err) {
+			assert.Equal(t, 0, len(tasks))
+		}
+	}
+}
+
+func TestHandleErrorStatus(t *testing.T) {
+	s := newTestServer()
+	s.Service.Create(testContext(), models.Task{Header: "Header", Deadline: time.Now().Add(48 * time.Hour)})
+
+	var test_cases = []struct {
+		method string
+		url    string
+		body   interface{}
+		code   int
+	}{
+		{method: http.MethodGet, url: "/tasks/999", code: http.StatusNotFound},
+		{method: http.MethodGet, url: "/tasks/abc", code: http.StatusBadRequest},
+		{method: http.MethodDelete, url: "/tasks/999", code: http.StatusNotFound},
+		{
+			method: http.MethodPut,
+			url:    "/tasks/999",
+			body:   models.Task{Header: "Header", Deadline: time.Now().Add(48 * time.Hour)},
+			code:   http.StatusNotFound,
+		},
+		{
+			method: http.MethodPut,
+			url:    "/tasks/1",
+			body:   models.Task{Header: "Header", Deadline: time.Now().Add(-48 * time.Hour)},
+			code:   http.StatusUnprocessableEntity,
+		},
+	}
+
+	for _, tc := range test_cases {
+		rec := doRequest(s, tc.method, tc.url, tc.body)
+		assert.Equal(t, tc.code, rec.Code, tc.method+" "+tc.url)
+	}
+}
+
+func TestHandleErrorProblem(t *testing.T) {
+	s := newTestServer()
+	s.Service.Create(testContext(), models.Task{Header: "Header", Deadline: time.Now().Add(48 * time.Hour)})
+
+	var test_cases = []struct {
+		method   string
+		url      string
+		body     interface{}
+		expected Problem
+	}{
+		{
+			method: http.MethodPut,
+			url:    "/tasks/1",
+			body:   models.Task{Header: "Header", Deadline: time.Now().Add(-48 * time.Hour)},
+			expected: Problem{
+				Type:     problemTypeValidation,
+				Title:    "Unprocessable Entity",
+				Status:   http.StatusUnprocessableEntity,
+				Instance: "/tasks/1",
+				Errors:   []FieldError{{Field: "deadline", Message: "task deadline can not be in the past"}},
+			},
+		},
+		{
+			method: http.MethodGet,
+			url:    "/tasks/?page=x&take=y",
+			expected: Problem{
+				Type:     problemTypeBadRequest,
+				Title:    "Bad Request",
+				Status:   http.StatusBadRequest,
+				Instance: "/tasks/",
+				Errors: []FieldError{
+					{Field: "page", Message: `"x": invalid syntax`},
+					{Field: "take", Message: `"y": invalid syntax`},
+				},
+			},
+		},
+		{
+			method: http.MethodGet,
+			url:    "/tasks/999",
+			expected: Problem{
+				Type:     problemTypeNotFound,
+				Title:    "Not Found",
+				Status:   http.StatusNotFound,
+				Instance: "/tasks/999",
+			},
+		},
+	}
+
+	for _, tc := range test_cases {
+		rec := doRequest(s, tc.method, tc.url, tc.body)
+		assert.Equal(t, tc.expected.Status, rec.Code)
+		assert.Equal(t, problemContentType, rec.Header().Get("Content-Type"))
+
+		var actual Problem
+		if assert.Nil(t, json.NewDecoder(rec.Body).Decode(&actual)) {
+			assert.NotEmpty(t, actual.Detail)
+			actual.Detail = ""
+			assert.Equal(t, tc.expected, actual)
+		}
+	}
+}
+
+func TestHandlePatch(t *testing.T) {
+	s := newTestServer()
+	deadline := time.Now().Add(48 * time.Hour)
+	s.Service.Create(testContext(), models.Task{Header: "Header", Description: "Description", Deadline: deadline})
+
+	var test_cases = []struct {
+		contentType string
+		body        string
+		code        int
+		expected    models.Task
+	}{
+		{
+			contentType: mergePatchContentType,
+			body:        `{"done": true}`,
+			code:        http.StatusOK,
+			expected:    models.Task{Header: "Header", Description: "Description", Done: true},
+		},
+		{
+			contentType: mergePatchContentType,
+			body:        `{"description": null, "id": 1}`,
+			code:        http.StatusOK,
+			expected:    models.Task{Header: "Header", Done: true},
+		},
+		{
+			contentType: mergePatchContentType,
+			body:        `{"header": null, "priority": 1}`,
+			code:        http.StatusUnprocessableEntity,
+			expected:    models.Task{Header: "Header", Done: true},
+		},
+		{
+			contentType: jsonPatchContentType,
+			body:        `[{"op": "test", "path": "/done", "value": true}, {"op": "replace", "path": "/header", "value": "New"}]`,
+			code:        http.StatusOK,
+			expected:    models.Task{Header: "New", Done: true},
+		},
+		{
+			contentType: jsonPatchContentType,
+			body:        `[{"op": "test", "path": "/done", "value": false}, {"op": "remove", "path": "/done"}]`,
+			code:        http.StatusConflict,
+			expected:    models.Task{Header: "New", Done: true},
+		},
+		{
+			contentType: jsonPatchContentType,
+			body:        `[{"op": "move", "path": "/done"}]`,
+			code:        http.StatusUnprocessableEntity,
+			expected:    models.Task{Header: "New", Done: true},
+		},
+		{
+			contentType: "text/plain",
+			body:        `done`,
+			code:        http.StatusUnsupportedMediaType,
+			expected:    models.Task{Header: "New", Done: true},
+		},
+	}
+
+	for _, tc := range test_cases {
+		rec := doRawRequest(s, http.MethodPatch, "/tasks/1", tc.contentType, tc.body)
+		assert.Equal(t, tc.code, rec.Code, tc.body)
+		task, err := s.Service.Get(testContext(), 1)
+		if assert.Nil(t, err) {
+			assert.Equal(t, tc.expected.Header, task.Header, tc.body)
+			assert.Equal(t, tc.expected.Description, task.Description, tc.body)
+			assert.Equal(t, tc.expected.Done, task.Done, tc.body)
+		}
+	}
+
+	// Tests pass only against version from If-Match
+	body := `[{"op": "test", "path": "/done", "value": true}, {"op": "replace", "path": "/header", "value": "Newer"}]`
+	headers := map[string]string{"Content-Type": jsonPatchContentType, "If-Match": `"3"`}
+	rec := doRequestWithHeaders(s, http.MethodPatch, "/tasks/1", body, headers)
+	assert.Equal(t, http.StatusPreconditionFailed, rec.Code)
+	headers["If-Match"] = `"4"`
+	rec = doRequestWithHeaders(s, http.MethodPatch, "/tasks/1", body, headers)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	task, err := s.Service.Get(testContext(), 1)
+	if assert.Nil(t, err) {
+		assert.Equal(t, "Newer", task.Header)
+		assert.Equal(t, 5, task.Version)
+	}
+
+	rec = doRawRequest(s, http.MethodPatch, "/tasks/2", mergePatchContentType, `{"done": true}`)
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+func TestHandleETag(t *testing.T) {
+	s := newTestServer()
+	s.Config.RequireIfMatch = true
+	s.Service.Create(testContext(), models.Task{Header: "Header", Deadline: time.Now().Add(48 * time.Hour)})
+
+	rec := doRequest(s, http.MethodGet, "/tasks/1", nil)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	taskETag := rec.Header().Get("ETag")
+	assert.Regexp(t, `^"1-[0-9a-f]+"$`, taskETag)
+
+	rec = doRequestWithHeaders(s, http.MethodGet, "/tasks/1", "", map[string]string{"If-None-Match": taskETag})
+	assert.Equal(t, http.StatusNotModified, rec.Code)
+	assert.Empty(t, rec.Body.String())
+
+	// Progress changes with subtasks, not with version of task
+	parent := 1
+	s.Service.Create(testContext(), models.Task{Header: "Subtask", Deadline: time.Now().Add(48 * time.Hour), Done: true, ParentId: &parent})
+	rec = doRequestWithHeaders(s, http.MethodGet, "/tasks/1", "", map[string]string{"If-None-Match": taskETag})
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	rec = doRequest(s, http.MethodGet, "/tasks/", nil)
+	listETag := rec.Header().Get("ETag")
+	assert.NotEmpty(t, listETag)
+	rec = doRequestWithHeaders(s, http.MethodGet, "/tasks/", "", map[string]string{"If-None-Match": listETag})
+	assert.Equal(t, http.StatusNotModified, rec.Code)
+
+	patch := `{"done": true}`
+	var test_cases = []struct {
+		method  string
+		ifMatch string
+		code    int
+	}{
+		{method: http.MethodPatch, code: http.StatusPreconditionRequired},
+		{method: http.MethodPatch, ifMatch: "1", code: http.StatusBadRequest},
+		{method: http.MethodPatch, ifMatch: "W/" + taskETag, code: http.StatusBadRequest},
+		{method: http.MethodPatch, ifMatch: `"2"`, code: http.StatusPreconditionFailed},
+		// ETag returned by GET is sent back, then it is stale
+		{method: http.MethodPatch, ifMatch: taskETag, code: http.StatusOK},
+		{method: http.MethodPatch, ifMatch: taskETag, code: http.StatusPreconditionFailed},
+		{method: http.MethodDelete, ifMatch: `"1"`, code: http.StatusPreconditionFailed},
+	}
+	for _, tc := range test_cases {
+		headers := map[string]string{"Content-Type": mergePatchContentType}
+		if tc.ifMatch != "" {
+			headers["If-Match"] = tc.ifMatch
+		}
+		rec := doRequestWithHeaders(s, tc.method, "/tasks/1", patch, headers)
+		assert.Equal(t, tc.code, rec.Code, tc.method+" "+tc.ifMatch)
+	}
+	rec = doRequest(s, http.MethodGet, "/tasks/1", nil)
+	taskETag = rec.Header().Get("ETag")
+	assert.Regexp(t, `^"2-[0-9a-f]+"$`, taskETag)
+	rec = doRequestWithHeaders(s, http.MethodDelete, "/tasks/1", "", map[string]string{"If-Match": taskETag})
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	// List ETag changes with content
+	rec = doRequestWithHeaders(s, http.MethodGet, "/tasks/", "", map[string]string{"If-None-Match": listETag})
+	assert.Equal(t, http.StatusOK, rec.Code)
+}
+
+func TestHandleGetListWithCursor(t *testing.T) {
+	s := newTestServer()
+	deadline := time.Now().Add(48 * time.Hour)
+	for i := 0; i < 5; i++ {
+		s.Service.Create(testContext(), models.Task{Header: "Header", Deadline: deadline, Done: i%2 == 0})
+	}
+
+	var page TaskPage
+	rec := doRequest(s, http.MethodGet, "/tasks/?limit=2", nil)
+	if assert.Equal(t, http.StatusOK, rec.Code) && assert.Nil(t, json.NewDecoder(rec.Body).Decode(&page)) {
+		assert.Equal(t, 2, len(page.Items))
+		assert.Nil(t, page.PrevCursor)
+		if assert.NotNil(t, page.NextCursor) {
+			assert.Equal(t, `</tasks/?cursor=`+*page.NextCursor+`&limit=2>; rel="next"`, rec.Header().Get("Link"))
+		}
+	}
+
+	var ids []int
+	for page.NextCursor != nil {
+		rec = doRequest(s, http.MethodGet, "/tasks/?limit=2&cursor="+*page.NextCursor, nil)
+		page = TaskPage{}
+		if !assert.Equal(t, http.StatusOK, rec.Code) || !assert.Nil(t, json.NewDecoder(rec.Body).Decode(&page)) {
+			return
+		}
+		for _, task := range page.Items {
+			ids = append(ids, task.Id)
+		}
+	}
+	assert.Equal(t, []int{3, 4, 5}, ids)
+
+	if assert.NotNil(t, page.PrevCursor) {
+		rec = doRequest(s, http.MethodGet, "/tasks/?limit=2&cursor="+*page.PrevCursor, nil)
+		page = TaskPage{}
+		if assert.Equal(t, http.StatusOK, rec.Code) && assert.Nil(t, json.NewDecoder(rec.Body).Decode(&page)) {
+			assert.Equal(t, 3, page.Items[0].Id)
+			assert.Equal(t, 4, page.Items[1].Id)
+		}
+	}
+
+	rec = doRequest(s, http.MethodGet, "/tasks/?done=true&limit=10", nil)
+	page = TaskPage{}
+	if assert.Equal(t, http.StatusOK, rec.Code) && assert.Nil(t, json.NewDecoder(rec.Body).Decode(&page)) {
+		assert.Equal(t, 3, len(page.Items))
+		assert.Equal(t, 3, page.Total)
+		assert.Equal(t, 10, page.PageSize)
+		assert.False(t, page.HasMore)
+		assert.Equal(t, "3", rec.Header().Get("X-Total-Count"))
+		assert.Nil(t, page.NextCursor)
+		assert.Empty(t, rec.Header().Get("Link"))
+	}
+
+	rec = doRequest(s, http.MethodGet, "/tasks/?cursor=garbage", nil)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	rec = doRequest(s, http.MethodGet, "/tasks/?limit=0", nil)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	rec = doRequest(s, http.MethodGet, "/tasks/?page=1&take=2", nil)
+	assert.Equal(t, "true", rec.Header().Get("Deprecation"))
+}
+
+func TestHandleTimezone(t *testing.T) {
+	s := newTestServer()
+	moscow, _ := time.LoadLocation("Europe/Moscow")
+	tokyo, _ := time.LoadLocation("Asia/Tokyo")
+	date := time.Now().In(moscow).AddDate(0, 0, 3)
+	day := date.Format("2006-01-02")
+	endOfDay := time.Date(date.Year(), date.Month(), date.Day(), 23, 59, 59, 0, moscow)
+
+	// Date only deadline is the end of that day in timezone of request
+	rec := doRequestWithHeaders(s, http.MethodPost, "/tasks/", `{"header": "Header", "deadline": "`+day+`"}`,
+		map[string]string{"X-Timezone": "Europe/Moscow"})
+	if !assert.Equal(t, http.StatusCreated, rec.Code) {
+		return
+	}
+
+	var test_cases = []struct {
+		url      string
+		timezone string
+		expected string
+	}{
+		{url: "/tasks/1", timezone: "Europe/Moscow", expected: endOfDay.Format(time.RFC3339)},
+		{url: "/tasks/1", expected: endOfDay.UTC().Format(time.RFC3339)},
+		{url: "/tasks/1?tz=Asia/Tokyo", expected: endOfDay.In(tokyo).Format(time.RFC3339)},
+	}
+	for _, tc := range test_cases {
+		rec = doRequestWithHeaders(s, http.MethodGet, tc.url, "", map[string]string{"X-Timezone": tc.timezone})
+		var task map[string]interface{}
+		if assert.Equal(t, http.StatusOK, rec.Code, tc.url) && assert.Nil(t, json.NewDecoder(rec.Body).Decode(&task)) {
+			assert.Equal(t, tc.expected, task["deadline"], tc.url)
+		}
+	}
+
+	// The end of day in Moscow is the next day in Tokyo
+	tokyoDate := endOfDay.In(tokyo)
+	nextDay := tokyoDate.Format("2006-01-02")
+	for _, tc := range []struct {
+		url    string
+		length int
+	}{
+		{url: "/tasks/byDate/" + day + "?tz=Europe/Moscow", length: 1},
+		{url: "/tasks/byDate/" + day + "?tz=Asia/Tokyo", length: 0},
+		{url: "/tasks/byDate/" + nextDay + "?tz=Asia/Tokyo", length: 1},
+		{url: "/tasks/?deadline_from=" + nextDay + "&tz=Asia/Tokyo", length: 1},
+		{url: "/tasks/?deadline_to=" + day + "&tz=Asia/Tokyo", length: 0},
+	} {
+		rec = doRequest(s, http.MethodGet, tc.url, nil)
+		var tasks []models.Task
+		if assert.Equal(t, http.StatusOK, rec.Code, tc.url) && assert.Nil(t, json.NewDecoder(rec.Body).Decode(&tasks)) {
+			assert.Equal(t, tc.length, len(tasks), tc.url)
+		}
+	}
+
+	// Deadline of today is valid until the end of day in timezone of request
+	kiritimati, _ := time.LoadLocation("Pacific/Kiritimati")
+	today := time.Now().In(kiritimati).Format("2006-01-02")
+	rec = doRequestWithHeaders(s, http.MethodPost, "/tasks/", `{"header": "Header", "deadline": "`+today+`"}`,
+		map[string]string{"X-Timezone": "Pacific/Kiritimati"})
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	rec = doRequestWithHeaders(s, http.MethodPost, "/tasks/", `{"header": "Header", "deadline": "`+time.Now().Add(-time.Minute).Format(time.RFC3339)+`"}`, nil)
+	assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+
+	rec = doRawRequest(s, http.MethodPatch, "/tasks/1?tz=Asia/Tokyo", mergePatchContentType, `{"deadline": "`+nextDay+`"}`)
+	if assert.Equal(t, http.StatusOK, rec.Code) {
+		task, _ := s.Service.Get(testContext(), 1)
+		assert.True(t, time.Date(tokyoDate.Year(), tokyoDate.Month(), tokyoDate.Day(), 23, 59, 59, 0, tokyo).Equal(task.Deadline))
+	}
+	rec = doRawRequest(s, http.MethodPatch, "/tasks/1?tz=Asia/Tokyo", jsonPatchContentType,
+		`[{"op": "test", "path": "/deadline", "value": "`+nextDay+`"}, {"op": "replace", "path": "/done", "value": true}]`)
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	for _, url := range []string{"/tasks/1?tz=Mars/Olympus", "/tasks/?tz=Nowhere", "/tasks/range?from=2024-01-01&to=2024-01-02&tz=Nowhere"} {
+		rec = doRequest(s, http.MethodGet, url, nil)
+		assert.Equal(t, http.StatusBadRequest, rec.Code, url)
+	}
+	rec = doRequestWithHeaders(s, http.MethodPost, "/tasks/", `{"header": "Header", "deadline": "tomorrow"}`, nil)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+func TestHandleTrash(t *testing.T) {
+	s := newTestServer()
+	deadline := time.Now().Add(48 * time.Hour)
+	s.Service.Create(testContext(), models.Task{Header: "Header", Deadline: deadline})
+	s.Service.Create(testContext(), models.Task{Header: "Header", Deadline: deadline})
+
+	rec := doRequest(s, http.MethodDelete, "/tasks/1", nil)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	rec = doRequest(s, http.MethodGet, "/tasks/1", nil)
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+
+	var tasks []models.Task
+	rec = doRequest(s, http.MethodGet, "/tasks/trash", nil)
+	if assert.Equal(t, http.StatusOK, rec.Code) && assert.Nil(t, json.NewDecoder(rec.Body).Decode(&tasks)) && assert.Equal(t, 1, len(tasks)) {
+		assert.Equal(t, 1, tasks[0].Id)
+		assert.NotNil(t, tasks[0].DeletedAt)
+	}
+	tasks = nil
+	rec = doRequest(s, http.MethodGet, "/tasks/", nil)
+	if assert.Equal(t, http.StatusOK, rec.Code) && assert.Nil(t, json.NewDecoder(rec.Body).Decode(&tasks)) {
+		assert.Equal(t, 1, len(tasks))
+	}
+
+	rec = doRequest(s, http.MethodPost, "/tasks/1/restore", nil)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	rec = doRequest(s, http.MethodPost, "/tasks/1/restore", nil)
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	rec = doRequest(s, http.MethodPost, "/tasks/abc/restore", nil)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	var task map[string]interface{}
+	rec = doRequest(s, http.MethodGet, "/tasks/1", nil)
+	if assert.Equal(t, http.StatusOK, rec.Code) && assert.Nil(t, json.NewDecoder(rec.Body).Decode(&task)) {
+		assert.NotContains(t, task, "deleted_at")
+		assert.Equal(t, float64(3), task["version"])
+	}
+
+	// Negative retention purges all tasks in trash
+	doRequest(s, http.MethodDelete, "/tasks/2", nil)
+	s.purgeTrash(context.Background(), -time.Hour)
+	rec = doRequest(s, http.MethodGet, "/tasks/trash", nil)
+	assert.Equal(t, "[]\n", rec.Body.String())
+}
+
+func TestHandleGetHistory(t *testing.T) {
+	s := newTestServer()
+	s.Service.Create(testContext(), models.Task{Header: "Header", Deadline: time.Now().Add(48 * time.Hour)})
+
+	rec := doRequestWithHeaders(s, http.MethodPatch, "/tasks/1", `{"done": true}`, map[string]string{
+		"Content-Type": "application/merge-patch+json",
+		"X-Request-Id": "req-1",
+	})
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "req-1", rec.Header().Get("X-Request-Id"))
+	rec = doRequest(s, http.MethodDelete, "/tasks/1", nil)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.NotEmpty(t, rec.Header().Get("X-Request-Id"))
+
+	var events []models.TaskEvent
+	rec = doRequestWithHeaders(s, http.MethodGet, "/tasks/1/history", "", map[string]string{"X-Timezone": "Europe/Moscow"})
+	if assert.Equal(t, http.StatusOK, rec.Code) && assert.Nil(t, json.NewDecoder(rec.Body).Decode(&events)) && assert.Equal(t, 3, len(events)) {
+		assert.Equal(t, "create", events[0].Action)
+		assert.Equal(t, "system", events[0].Actor)
+		assert.Equal(t, "update", events[1].Action)
+		assert.Equal(t, testUser.Username, events[1].Actor)
+		assert.Equal(t, "req-1", events[1].RequestId)
+		assert.Equal(t, []models.FieldChange{{Field: "done", Old: false, New: true}}, events[1].Changes)
+		assert.Equal(t, "delete", events[2].Action)
+		_, offset := events[2].CreatedAt.Zone()
+		assert.Equal(t, 3*60*60, offset)
+	}
+
+	// History outlives purged task
+	purged, err := s.Service.PurgeTrash(context.Background(), 0)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, purged)
+	rec = doRequest(s, http.MethodGet, "/tasks/1/history", nil)
+	if assert.Equal(t, http.StatusOK, rec.Code) && assert.Nil(t, json.NewDecoder(rec.Body).Decode(&events)) && assert.Equal(t, 4, len(events)) {
+		assert.Equal(t, "purge", events[3].Action)
+	}
+	s.Service.Register(context.Background(), models.Credentials{Username: "stranger", Password: "stranger password"})
+	req := httptest.NewRequest(http.MethodGet, "/tasks/1/history", nil)
+	req.SetBasicAuth("stranger", "stranger password")
+	rec = httptest.NewRecorder()
+	s.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+
+	rec = doRequest(s, http.MethodGet, "/tasks/2/history", nil)
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	rec = doRequest(s, http.MethodGet, "/tasks/abc/history", nil)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+func TestHandleTags(t *testing.T) {
+	s := newTestServer()
+	deadline := time.Now().Add(48 * time.Hour)
+	// Tags are created by test user
+	owner := 1
+	s.Service.Create(testContext(), models.Task{Header: "Header", Deadline: deadline})
+	s.Service.Create(testContext(), models.Task{Header: "Header", Deadline: deadline})
+
+	rec := doRequest(s, http.MethodPost, "/tags/", models.Tag{Name: "work", Color: "#00ff00"})
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	rec = doRequest(s, http.MethodPost, "/tags/", models.Tag{Name: "urgent"})
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	rec = doRequest(s, http.MethodPost, "/tags/", models.Tag{Name: "work"})
+	assert.Equal(t, http.StatusConflict, rec.Code)
+	rec = doRequest(s, http.MethodPost, "/tags/", models.Tag{Name: "home", Color: "green"})
+	assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+
+	var test_cases = []struct {
+		method string
+		url    string
+		code   int
+	}{
+		{http.MethodPut, "/tasks/1/tags/1", http.StatusOK},
+		{http.MethodPut, "/tasks/2/tags/1", http.StatusOK},
+		{http.MethodPut, "/tasks/2/tags/2", http.StatusOK},
+		{http.MethodPut, "/tasks/2/tags/3", http.StatusNotFound},
+		{http.MethodPut, "/tasks/3/tags/1", http.StatusNotFound},
+		{http.MethodPut, "/tasks/1/tags/abc", http.StatusBadRequest},
+		{http.MethodDelete, "/tasks/1/tags/2", http.StatusOK},
+	}
+	for _, tc := range test_cases {
+		rec = doRequest(s, tc.method, tc.url, nil)
+		assert.Equal(t, tc.code, rec.Code, tc.url)
+	}
+
+	var task models.Task
+	rec = doRequest(s, http.MethodGet, "/tasks/2", nil)
+	if assert.Equal(t, http.StatusOK, rec.Code) && assert.Nil(t, json.NewDecoder(rec.Body).Decode(&task)) {
+		assert.Equal(t, []models.Tag{{Id: 2, Name: "urgent", OwnerId: &owner}, {Id: 1, Name: "work", Color: "#00ff00", OwnerId: &owner}}, task.Tags)
+		assert.Equal(t, 3, task.Version)
+	}
+	var tags []models.Tag
+	rec = doRequest(s, http.MethodGet, "/tasks/1/tags", nil)
+	if assert.Equal(t, http.StatusOK, rec.Code) && assert.Nil(t, json.NewDecoder(rec.Body).Decode(&tags)) {
+		assert.Equal(t, []models.Tag{{Id: 1, Name: "work", Color: "#00ff00", OwnerId: &owner}}, tags)
+	}
+
+	var filters = []struct {
+		url   string
+		code  int
+		count int
+	}{
+		{"/tasks/?tag=work&tag=urgent", http.StatusOK, 2},
+		{"/tasks/?tag=work&tag=urgent&tag_match=all", http.StatusOK, 1},
+		{"/tasks/?tag=home", http.StatusOK, 0},
+		{"/tasks/?tag=work&tag_match=some", http.StatusBadRequest, 0},
+	}
+	for _, tc := range filters {
+		rec = doRequest(s, http.MethodGet, tc.url, nil)
+		var tasks []models.Task
+		if assert.Equal(t, tc.code, rec.Code, tc.url) && tc.code == http.StatusOK && assert.Nil(t, json.NewDecoder(rec.Body).Decode(&tasks)) {
+			assert.Equal(t, tc.count, len(tasks), tc.url)
+		}
+	}
+
+	rec = doRequest(s, http.MethodPut, "/tags/2", models.Tag{Name: "asap"})
+	assert.Equal(t, http.StatusOK, rec.Code)
+	rec = doRequest(s, http.MethodDelete, "/tags/1", nil)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	tags = nil
+	rec = doRequest(s, http.MethodGet, "/tags/", nil)
+	if assert.Equal(t, http.StatusOK, rec.Code) && assert.Nil(t, json.NewDecoder(rec.Body).Decode(&tags)) {
+		assert.Equal(t, []models.Tag{{Id: 2, Name: "asap", OwnerId: &owner}}, tags)
+	}
+}
+
+func TestHandleProjects(t *testing.T) {
+	s := newTestServer()
+	deadline := time.Now().Add(48 * time.Hour)
+	// Projects are created by test user
+	owner := 1
+	s.Service.Create(testContext(), models.Task{Header: "Header", Deadline: deadline})
+
+	rec := doRequest(s, http.MethodPost, "/projects/", models.Project{Name: "Sprint"})
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	rec = doRequest(s, http.MethodPost, "/projects/", models.Project{Name: "  "})
+	assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+
+	var test_cases = []struct {
+		method string
+		url    string
+		body   interface{}
+		code   int
+	}{
+		{http.MethodPost, "/projects/1/tasks", models.Task{Header: "Header", Deadline: deadline}, http.StatusCreated},
+		{http.MethodPost, "/projects/1/tasks", models.Task{Header: "Header", Deadline: deadline, ProjectId: new(int)}, http.StatusUnprocessableEntity},
+		{http.MethodPost, "/projects/2/tasks", models.Task{Header: "Header", Deadline: deadline}, http.StatusNotFound},
+		{http.MethodPost, "/projects/abc/tasks", models.Task{Header: "Header", Deadline: deadline}, http.StatusBadRequest},
+		{http.MethodPut, "/projects/1/tasks/1", nil, http.StatusOK},
+		{http.MethodPut, "/projects/1/tasks/3", nil, http.StatusNotFound},
+		{http.MethodPut, "/projects/2/tasks/1", nil, http.StatusNotFound},
+		{http.MethodPut, "/projects/1", models.Project{Name: "Sprint 2"}, http.StatusOK},
+		{http.MethodGet, "/projects/2", nil, http.StatusNotFound},
+	}
+	for _, tc := range test_cases {
+		rec = doRequest(s, tc.method, tc.url, tc.body)
+		assert.Equal(t, tc.code, rec.Code, tc.url)
+	}
+
+	rec = doRequest(s, http.MethodPatch, "/tasks/2", map[string]interface{}{"done": true})
+	assert.Equal(t, http.StatusOK, rec.Code)
+	var project models.Project
+	rec = doRequest(s, http.MethodGet, "/projects/1", nil)
+	if assert.Equal(t, http.StatusOK, rec.Code) && assert.Nil(t, json.NewDecoder(rec.Body).Decode(&project)) {
+		assert.Equal(t, models.Project{Id: 1, Name: "Sprint 2", OwnerId: &owner, OpenTasks: 1, DoneTasks: 1}, project)
+	}
+
+	rec = doRequest(s, http.MethodPost, "/projects/1/archive", nil)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	rec = doRequest(s, http.MethodPatch, "/tasks/1", map[string]interface{}{"done": true})
+	assert.Equal(t, http.StatusConflict, rec.Code)
+	rec = doRequest(s, http.MethodPost, "/projects/1/tasks", models.Task{Header: "Header", Deadline: deadline})
+	assert.Equal(t, http.StatusConflict, rec.Code)
+
+	var lists = []struct {
+		url   string
+		count int
+	}{
+		{"/tasks/", 0},
+		{"/projects/1/tasks", 2},
+		{"/projects/1/tasks?done=false", 1},
+		{"/projects/", 0},
+		{"/projects/?archived=true", 1},
+	}
+	for _, tc := range lists {
+		rec = doRequest(s, http.MethodGet, tc.url, nil)
+		var items []interface{}
+		if assert.Equal(t, http.StatusOK, rec.Code, tc.url) && assert.Nil(t, json.NewDecoder(rec.Body).Decode(&items)) {
+			assert.Equal(t, tc.count, len(items), tc.url)
+		}
+	}
+
+	rec = doRequest(s, http.MethodPost, "/projects/1/unarchive", nil)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	rec = doRequest(s, http.MethodPatch, "/tasks/1", map[string]interface{}{"project_id": nil})
+	assert.Equal(t, http.StatusOK, rec.Code)
+	var task models.Task
+	rec = doRequest(s, http.MethodGet, "/tasks/1", nil)
+	if assert.Equal(t, http.StatusOK, rec.Code) && assert.Nil(t, json.NewDecoder(rec.Body).Decode(&task)) {
+		assert.Nil(t, task.ProjectId)
+		assert.Nil(t, task.ArchivedAt)
+	}
+}
+
+func TestHandleSubtasks(t *testing.T) {
+	s := newTestServer()
+	s.Service.Subtasks.BlockParentDone = true
+	deadline := time.Now().Add(48 * time.Hour)
+	parent := 1
+
+	var test_cases = []struct {
+		method string
+		url    string
+		body   interface{}
+		code   int
+	}{
+		{http.MethodPost, "/tasks/", models.Task{Header: "Parent", Deadline: deadline}, http.StatusCreated},
+		{http.MethodPost, "/tasks/", models.Task{Header: "Child", Deadline: deadline, ParentId: &parent}, http.StatusCreated},
+		{http.MethodPatch, "/tasks/1", map[string]interface{}{"parent_id": 2}, http.StatusUnprocessableEntity},
+		{http.MethodPatch, "/tasks/1", map[string]interface{}{"done": true}, http.StatusConflict},
+		{http.MethodPost, "/tasks/1/checklist", models.ChecklistItem{Text: "Check"}, http.StatusCreated},
+		{http.MethodPost, "/tasks/1/checklist", models.ChecklistItem{Text: ""}, http.StatusUnprocessableEntity},
+		{http.MethodPost, "/tasks/3/checklist", models.ChecklistItem{Text: "Check"}, http.StatusNotFound},
+		{http.MethodPut, "/tasks/1/checklist/1", models.ChecklistItem{Text: "Check", Done: true}, http.StatusOK},
+		{http.MethodPut, "/tasks/1/checklist/2", models.ChecklistItem{Text: "Check"}, http.StatusNotFound},
+		{http.MethodPut, "/tasks/1/checklist/abc", models.ChecklistItem{Text: "Check"}, http.StatusBadRequest},
+		{http.MethodGet, "/tasks/1?expand=parents", nil, http.StatusBadRequest},
+	}
+	for _, tc := range test_cases {
+		rec := doRequest(s, tc.method, tc.url, tc.body)
+		assert.Equal(t, tc.code, rec.Code, tc.method+" "+tc.url)
+	}
+
+	var items []models.ChecklistItem
+	rec := doRequest(s, http.MethodGet, "/tasks/1/checklist", nil)
+	if assert.Equal(t, http.StatusOK, rec.Code) && assert.Nil(t, json.NewDecoder(rec.Body).Decode(&items)) {
+		assert.Equal(t, []models.ChecklistItem{{Id: 1, Text: "Check", Done: true}}, items)
+	}
+
+	var task models.Task
+	rec = doRequest(s, http.MethodGet, "/tasks/1?expand=children", nil)
+	if assert.Equal(t, http.StatusOK, rec.Code) && assert.Nil(t, json.NewDecoder(rec.Body).Decode(&task)) {
+		assert.Equal(t, 50, task.Progress)
+		if assert.Equal(t, 1, len(task.Children)) {
+			assert.Equal(t, 2, task.Children[0].Id)
+			assert.Equal(t, &parent, task.Children[0].ParentId)
+		}
+		assert.NotEqual(t, `"3"`, rec.Header().Get("ETag"))
+	}
+
+	rec = doRequest(s, http.MethodPatch, "/tasks/2", map[string]interface{}{"done": true})
+	assert.Equal(t, http.StatusOK, rec.Code)
+	rec = doRequest(s, http.MethodPatch, "/tasks/1", map[string]interface{}{"done": true})
+	assert.Equal(t, http.StatusOK, rec.Code)
+	rec = doRequest(s, http.MethodDelete, "/tasks/1/checklist/1", nil)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	rec = doRequest(s, http.MethodGet, "/tasks/1", nil)
+	if assert.Equal(t, http.StatusOK, rec.Code) && assert.Nil(t, json.NewDecoder(rec.Body).Decode(&task)) {
+		assert.Equal(t, 100, task.Progress)
+		assert.Empty(t, task.Checklist)
+		assert.Equal(t, 5, task.Version)
+	}
+}
+
+func TestHandleDependencies(t *testing.T) {
+	s := newTestServer()
+	s.Service.Dependencies.BlockDone = true
+	for i := 1; i <= 3; i++ {
+		s.Service.Create(testContext(), models.Task{Header: "Header", Deadline: time.Now().Add(time.Duration(i) * time.Hour)})
+	}
+
+	var test_cases = []struct {
+		method string
+		url    string
+		code   int
+	}{
+		{http.MethodPut, "/tasks/2/dependencies/1", http.StatusOK},
+		{http.MethodPut, "/tasks/3/dependencies/2", http.StatusOK},
+		{http.MethodPut, "/tasks/1/dependencies/3", http.StatusConflict},
+		{http.MethodPut, "/tasks/1/dependencies/4", http.StatusNotFound},
+		{http.MethodPut, "/tasks/1/dependencies/abc", http.StatusBadRequest},
+		{http.MethodDelete, "/tasks/3/dependencies/1", http.StatusOK},
+		{http.MethodGet, "/tasks/4/dependencies", http.StatusNotFound},
+		{http.MethodGet, "/tasks/ready?all=maybe", http.StatusBadRequest},
+	}
+	for _, tc := range test_cases {
+		rec := doRequest(s, tc.method, tc.url, nil)
+		assert.Equal(t, tc.code, rec.Code, tc.method+" "+tc.url)
+	}
+
+	var deps DependencyList
+	rec := doRequest(s, http.MethodGet, "/tasks/2/dependencies", nil)
+	if assert.Equal(t, http.StatusOK, rec.Code) && assert.Nil(t, json.NewDecoder(rec.Body).Decode(&deps)) &&
+		assert.Equal(t, 1, len(deps.BlockedBy)) && assert.Equal(t, 1, len(deps.Blocking)) {
+		assert.Equal(t, 1, deps.BlockedBy[0].Id)
+		assert.Equal(t, 3, deps.Blocking[0].Id)
+	}
+
+	var ready = []struct {
+		url string
+		ids []int
+	}{
+		{"/tasks/ready", []int{1}},
+		{"/tasks/ready?all=true", []int{1, 2, 3}},
+	}
+	for _, tc := range ready {
+		var tasks []models.Task
+		rec = doRequest(s, http.MethodGet, tc.url, nil)
+		if assert.Equal(t, http.StatusOK, rec.Code) && assert.Nil(t, json.NewDecoder(rec.Body).Decode(&tasks)) {
+			var ids []int
+			for _, task := range tasks {
+				ids = append(ids, task.Id)
+			}
+			assert.Equal(t, tc.ids, ids, tc.url)
+		}
+	}
+
+	rec = doRequest(s, http.MethodPatch, "/tasks/2", map[string]interface{}{"done": true})
+	assert.Equal(t, http.StatusConflict, rec.Code)
+	rec = doRequest(s, http.MethodPatch, "/tasks/1", map[string]interface{}{"done": true})
+	assert.Equal(t, http.StatusOK, rec.Code)
+	rec = doRequest(s, http.MethodPatch, "/tasks/2", map[string]interface{}{"done": true})
+	assert.Equal(t, http.StatusOK, rec.Code)
+	var task models.Task
+	rec = doRequest(s, http.MethodGet, "/tasks/3", nil)
+	if assert.Equal(t, http.StatusOK, rec.Code) && assert.Nil(t, json.NewDecoder(rec.Body).Decode(&task)) {
+		assert.Equal(t, []int{2}, task.BlockedBy)
+	}
+}
+
+func TestHandleRecurrence(t *testing.T) {
+	s := newTestServer()
+
+	rec := doRawRequest(s, http.MethodPost, "/tasks/", "application/json",
+		`{"header": "Weekly report", "deadline": "2031-03-07T18:00:00Z", "recurrence": "FREQ=WEEKLY;BYDAY=FR"}`)
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	rec = doRawRequest(s, http.MethodPost, "/tasks/", "application/json",
+		`{"header": "Report", "deadline": "2031-03-07T18:00:00Z", "recurrence": "FREQ=WEEKLY;BYDAY=XX"}`)
+	assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+	rec = doRequest(s, http.MethodPatch, "/tasks/1", map[string]interface{}{"recurrence": "WEEKLY"})
+	assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+
+	var test_cases = []struct {
+		url     string
+		count   int
+		virtual bool
+	}{
+		{"/tasks/byDate/2031-03-07", 1, false},
+		{"/tasks/byDate/2031-03-14", 1, true},
+		{"/tasks/byDate/2031-03-14?done=false", 1, true},
+		{"/tasks/byDate/2031-03-14?done=true", 0, false},
+		{"/tasks/byDate/2031-03-15", 0, false},
+	}
+	for _, tc := range test_cases {
+		var tasks []models.Task
+		rec = doRequest(s, http.MethodGet, tc.url, nil)
+		if !assert.Equal(t, http.StatusOK, rec.Code) || !assert.Nil(t, json.NewDecoder(rec.Body).Decode(&tasks)) ||
+			!assert.Equal(t, tc.count, len(tasks), tc.url) || !tc.virtual {
+			continue
+		}
+		assert.Equal(t, 0, tasks[0].Id)
+		if assert.NotNil(t, tasks[0].OccurrenceOf) {
+			assert.Equal(t, 1, *tasks[0].OccurrenceOf)
+		}
+	}
+
+	rec = doRequest(s, http.MethodPatch, "/tasks/1", map[string]interface{}{"done": true})
+	assert.Equal(t, http.StatusOK, rec.Code)
+	var task models.Task
+	rec = doRequest(s, http.MethodGet, "/tasks/2", nil)
+	if assert.Equal(t, http.StatusOK, rec.Code) && assert.Nil(t, json.NewDecoder(rec.Body).Decode(&task)) {
+		assert.Equal(t, "Weekly report", task.Header)
+		assert.Equal(t, "FREQ=WEEKLY;BYDAY=FR", task.Recurrence)
+		assert.True(t, time.Date(2031, 3, 14, 18, 0, 0, 0, time.UTC).Equal(task.Deadline))
+	}
+}
+
+func TestHandleAgenda(t *testing.T) {
+	s := newTestServer()
+	s.Service.Agenda.PriorityWeight = 10
+	s.Service.Create(testContext(), models.Task{Header: "Header", Deadline: time.Now().Add(time.Hour)})
+	s.Service.Create(testContext(), models.Task{Header: "Header", Deadline: time.Now().Add(48 * time.Hour)})
+
+	rec := doRequest(s, http.MethodPatch, "/tasks/2", map[string]interface{}{"priority": 1, "effort": 30})
+	assert.Equal(t, http.StatusOK, rec.Code)
+	rec = doRequest(s, http.MethodPatch, "/tasks/2", map[string]interface{}{"priority": 7})
+	assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+
+	var tasks []AgendaTask
+	rec = doRequest(s, http.MethodGet, "/tasks/agenda", nil)
+	if assert.Equal(t, http.StatusOK, rec.Code) && assert.Nil(t, json.NewDecoder(rec.Body).Decode(&tasks)) && assert.Equal(t, 2, len(tasks)) {
+		assert.Equal(t, 2, tasks[0].Id)
+		assert.Equal(t, 1, tasks[0].Priority)
+		if assert.NotNil(t, tasks[0].Effort) {
+			assert.Equal(t, 30, *tasks[0].Effort)
+		}
+		assert.Greater(t, tasks[0].Score, tasks[1].Score)
+	}
+
+	for _, url := range []string{"/tasks/agenda?done=maybe", "/tasks/agenda?sort=id"} {
+		rec = doRequest(s, http.MethodGet, url, nil)
+		assert.Equal(t, http.StatusBadRequest, rec.Code, url)
+	}
+}
+
+func TestHandleUsers(t *testing.T) {
+	s := newTestServer()
+
+	var test_cases = []struct {
+		method string
+		url    string
+		body   string
+		code   int
+	}{
+		{http.MethodPost, "/users", `{"username": "alice", "password": "alice password"}`, http.StatusCreated},
+		{http.MethodPost, "/users", `{"username": "alice", "password": "other password"}`, http.StatusConflict},
+		{http.MethodPost, "/users", `{"username": "al", "password": "short"}`, http.StatusUnprocessableEntity},
+		{http.MethodPost, "/users", `{"username": `, http.StatusBadRequest},
+		{http.MethodPost, "/login", `{"username": "alice", "password": "alice password"}`, http.StatusOK},
+		{http.MethodPost, "/login", `{"username": "alice", "password": "wrong password"}`, http.StatusUnauthorized},
+		{http.MethodPost, "/login", `{"username": "nobody", "password": "alice password"}`, http.StatusUnauthorized},
+	}
+	for _, tc := range test_cases {
+		rec := doRawRequest(s, tc.method, tc.url, "application/json", tc.body)
+		assert.Equal(t, tc.code, rec.Code, tc.body)
+	}
+
+	rec := doRawRequest(s, http.MethodPost, "/login", "application/json", `{"username": "alice", "password": "alice password"}`)
+	var user models.User
+	if assert.Nil(t, json.NewDecoder(rec.Body).Decode(&user)) {
+		assert.Equal(t, 2, user.Id)
+		assert.Equal(t, "alice", user.Username)
+		assert.NotContains(t, rec.Body.String(), "password")
+	}
+
+	// Tasks of other user are forbidden unless they are shared
+	s.Service.Create(testContext(), models.Task{Header: "Header", Deadline: time.Now().Add(48 * time.Hour)})
+	req := httptest.NewRequest(http.MethodGet, "/tasks/1", nil)
+	req.SetBasicAuth("alice", "alice password")
+	rec = httptest.NewRecorder()
+	s.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+	rec = doRequest(s, http.MethodGet, "/tasks/1", nil)
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	for _, auth := range []string{"", "Basic " + "YWxpY2U6d3Jvbmc=", "Bearer token"} {
+		req := httptest.NewRequest(http.MethodGet, "/tasks/", nil)
+		if auth != "" {
+			req.Header.Set("Authorization", auth)
+		}
+		rec := httptest.NewRecorder()
+		s.ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusUnauthorized, rec.Code, auth)
+		assert.Equal(t, []string{bearerChallenge, basicChallenge}, rec.Header().Values("WWW-Authenticate"))
+		var problem Problem
+		if assert.Nil(t, json.NewDecoder(rec.Body).Decode(&problem)) {
+			assert.Equal(t, problemTypeUnauthorized, problem.Type)
+		}
+	}
+}
+
+func TestHandleTokens(t *testing.T) {
+	s := newTestServer()
+	bearer := func(method, url, body, token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, url, bytes.NewBufferString(body))
+		req.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+		s.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := doRawRequest(s, http.MethodPost, "/token", "application/json", `{"username": "tester", "password": "wrong password"}`)
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	rec = doRequest(s, http.MethodPost, "/token", testUser)
+	var tokens TokenPair
+	if !assert.Equal(t, http.StatusOK, rec.Code) || !assert.Nil(t, json.NewDecoder(rec.Body).Decode(&tokens)) {
+		return
+	}
+	assert.Equal(t, "no-store", rec.Header().Get("Cache-Control"))
+	assert.Equal(t, "Bearer", tokens.TokenType)
+	assert.Equal(t, 15*60, tokens.ExpiresIn)
+
+	rec = bearer(http.MethodPost, "/tasks", `{"header": "Header", "deadline": "2100-01-01"}`, tokens.AccessToken)
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	task, err := s.Service.Get(testContext(), 1)
+	if assert.Nil(t, err) && assert.NotNil(t, task.OwnerId) {
+		assert.Equal(t, 1, *task.OwnerId)
+	}
+	rec = bearer(http.MethodGet, "/tasks/1/history", "", tokens.AccessToken)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"actor":"tester"`)
+
+	user := models.User{Id: 1, Username: testUser.Username}
+	expired, _ := s.tokens.sign(user, tokenUseAccess, -time.Minute)
+	other, _ := (&tokenKeys{config: s.Config.Auth, method: jwt.SigningMethodHS256, signing: []byte("other secret")}).sign(user, tokenUseAccess, time.Minute)
+	unsigned, _ := jwt.NewWithClaims(jwt.SigningMethodNone, tokenClaims{Username: "tester", Use: tokenUseAccess}).
+		SignedString(jwt.UnsafeAllowNoneSignatureType)
+	var test_cases = []struct {
+		name  string
+		token string
+	}{
+		{"refresh_token", tokens.RefreshToken},
+		{"expired", expired},
+		{"other_key", other},
+		{"unsigned", unsigned},
+		{"malformed", "abc"},
+	}
+	for _, tc := range test_cases {
+		rec = bearer(http.MethodGet, "/tasks/", "", tc.token)
+		assert.Equal(t, http.StatusUnauthorized, rec.Code, tc.name)
+	}
+
+	rec = doRequest(s, http.MethodPost, "/token/refresh", RefreshRequest{RefreshToken: tokens.AccessToken})
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	rec = doRequest(s, http.MethodPost, "/token/refresh", RefreshRequest{RefreshToken: tokens.RefreshToken})
+	var refreshed TokenPair
+	if assert.Equal(t, http.StatusOK, rec.Code) && assert.Nil(t, json.NewDecoder(rec.Body).Decode(&refreshed)) {
+		rec = bearer(http.MethodGet, "/tasks/1", "", refreshed.AccessToken)
+		assert.Equal(t, http.StatusOK, rec.Code)
+	}
+	gone, _ := s.tokens.sign(models.User{Id: 5, Username: "gone"}, tokenUseRefresh, time.Minute)
+	rec = doRequest(s, http.MethodPost, "/token/refresh", RefreshRequest{RefreshToken: gone})
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+
+	// Health and documentation do not require authentication
+	for _, url := range []string{"/health", "/swagger/index.html"} {
+		rec := httptest.NewRecorder()
+		s.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, url, nil))
+		assert.Equal(t, http.StatusOK, rec.Code, url)
+	}
+}
+
+func TestTokenKeys(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name string, data []byte) string {
+		path := filepath.Join(dir, name)
+		assert.Nil(t, os.WriteFile(path, data, 0600))
+		return path
+	}
+	private, err := rsa.GenerateKey(rand.Reader, 2048)
+	if !assert.Nil(t, err) {
+		return
+	}
+	privateFile := write("private.pem", pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(private)}))
+	public, _ := x509.MarshalPKIXPublicKey(&private.PublicKey)
+	publicFile := write("public.pem", pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: public}))
+	jwks, _ := json.Marshal(map[string]interface{}{"keys": []jsonWebKey{
+		{Kty: "RSA", Kid: "rsa", Use: "sig", Alg: "RS256",
+			N: base64.RawURLEncoding.EncodeToString(private.N.Bytes()),
+			E: base64.RawURLEncoding.EncodeToString(big.NewInt(int64(private.E)).Bytes())},
+		{Kty: "oct", Kid: "hmac", K: base64.RawURLEncoding.EncodeToString([]byte("jwks secret"))},
+		{Kty: "RSA", Kid: "encryption", Use: "enc", N: "AQAB", E: "AQAB"},
+	}})
+	jwksFile := write("jwks.json", jwks)
+
+	user := models.User{Id: 1, Username: "tester"}
+	issuer, err := newTokenKeys(config.Auth{Algorithm: "RS256", PrivateKeyFile: privateFile, KeyId: "rsa"})
+	if !assert.Nil(t, err) {
+		return
+	}
+	tokens, err := issuer.issue(user)
+	if !assert.Nil(t, err) {
+		return
+	}
+	hmac, _ := (&tokenKeys{method: jwt.SigningMethodHS256, signing: []byte("jwks secret"), config: config.Auth{KeyId: "hmac"}}).
+		sign(user, tokenUseAccess, time.Minute)
+
+	var test_cases = []struct {
+		name   string
+		config config.Auth
+		token  string
+		err    bool
+	}{
+		{"private_key", config.Auth{Algorithm: "RS256", PrivateKeyFile: privateFile}, tokens.AccessToken, false},
+		{"public_key", config.Auth{Algorithm: "RS256", PublicKeyFile: publicFile, KeyId: "rsa"}, tokens.AccessToken, false},
+		{"jwks_rsa", config.Auth{Algorithm: "RS256", JWKSFile: jwksFile}, tokens.AccessToken, false},
+		{"jwks_hmac", config.Auth{Algorithm: "HS256", JWKSFile: jwksFile}, hmac, false},
+		{"jwks_unknown_kid", config.Auth{Algorithm: "HS256", Secret: "secret", KeyId: "other"}, hmac, true},
+		{"wrong_algorithm", config.Auth{Algorithm: "HS256", JWKSFile: jwksFile}, tokens.AccessToken, true},
+		{"wrong_issuer", config.Auth{Algorithm: "RS256", PublicKeyFile: publicFile, Issuer: "other"}, tokens.AccessToken, true},
+	}
+	for _, tc := range test_cases {
+		keys, err := newTokenKeys(tc.config)
+		if !assert.Nil(t, err, tc.name) {
+			continue
+		}
+		principal, err := keys.verify(tc.token, tokenUseAccess)
+		if tc.err {
+			assert.ErrorIs(t, err, service.ErrUnauthorized, tc.name)
+			continue
+		}
+		if assert.Nil(t, err, tc.name) {
+			assert.Equal(t, service.Principal{UserId: 1, Username: "tester"}, principal, tc.name)
+		}
+	}
+
+	// Server which only verifies tokens can not issue them
+	keys, err := newTokenKeys(config.Auth{Algorithm: "RS256", JWKSFile: jwksFile})
+	if assert.Nil(t, err) {
+		_, err = keys.issue(user)
+		assert.ErrorIs(t, err, errTokenIssuance)
+	}
+	for _, cf := range []config.Auth{
+		{Algorithm: "ES256", Secret: "secret"},
+		{Algorithm: "RS256", Secret: "secret"},
+		{Algorithm: "RS256", PrivateKeyFile: publicFile},
+		{Algorithm: "HS256", JWKSFile: filepath.Join(dir, "missing.json")},
+	} {
+		_, err := newTokenKeys(cf)
+		assert.NotNil(t, err, cf)
+	}
+}
+
+func TestCredentialCache(t *testing.T) {
+	cache := newCredentialCache()
+	now := time.Now()
+	principal := service.Principal{UserId: 1, Username: "tester"}
+	cache.put("tester", "password", principal, now)
+
+	var test_cases = []struct {
+		name     string
+		username string
+		password string
+		at       time.Time
+		ok       bool
+	}{
+		{"cached", "tester", "password", now.Add(time.Second), true},
+		{"wrong_password", "tester", "other", now, false},
+		{"other_user", "other", "password", now, false},
+		{"shifted_separator", "testerp", "assword", now, false},
+		{"expired", "tester", "password", now.Add(credentialsTTL), false},
+	}
+	for _, tc := range test_cases {
+		got, ok := cache.get(tc.username, tc.password, tc.at)
+		assert.Equal(t, tc.ok, ok, tc.name)
+		if tc.ok {
+			assert.Equal(t, principal, got, tc.name)
+		}
+	}
+
+	// Full cache drops expired credentials first
+	for i := 1; i < maxCachedCredentials; i++ {
+		cache.put(fmt.Sprint("user", i), "password", principal, now.Add(-credentialsTTL))
+	}
+	cache.put("other", "password", principal, now)
+	assert.Len(t, cache.entries, 2)
+	_, ok := cache.get("tester", "password", now)
+	assert.True(t, ok)
+}
+
+func TestHandleAPIKeys(t *testing.T) {
+	s := newTestServer()
+	withKey := func(method, url, body, key string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, url, bytes.NewBufferString(body))
+		req.Header.Set(apiKeyHeader, key)
+		rec := httptest.NewRecorder()
+		s.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := doRequest(s, http.MethodPost, "/api-keys", models.APIKey{Name: "CI", Scopes: []string{"tasks:admin"}})
+	assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+	keys := map[string]models.APIKey{}
+	for _, scope := range []string{service.ScopeTasksRead, service.ScopeTasksWrite} {
+		rec = doRequest(s, http.MethodPost, "/api-keys", models.APIKey{Name: scope, Scopes: []string{scope}})
+		var key models.APIKey
+		if assert.Equal(t, http.StatusCreated, rec.Code) && assert.Nil(t, json.NewDecoder(rec.Body).Decode(&key)) {
+			assert.NotEmpty(t, key.Key)
+			assert.NotContains(t, rec.Body.String(), "key_hash")
+			keys[scope] = key
+		}
+	}
+	reader, writer := keys[service.ScopeTasksRead].Key, keys[service.ScopeTasksWrite].Key
+
+	var test_cases = []struct {
+		method string
+		url    string
+		body   string
+		key    string
+		code   int
+	}{
+		{http.MethodPost, "/tasks", `{"header": "Header", "deadline": "2100-01-01"}`, reader, http.StatusForbidden},
+		{http.MethodPost, "/tasks", `{"header": "Header", "deadline": "2100-01-01"}`, writer, http.StatusCreated},
+		{http.MethodGet, "/tasks/1", "", reader, http.StatusOK},
+		{http.MethodGet, "/tasks/1", "", writer, http.StatusForbidden},
+		{http.MethodDelete, "/tasks/1", "", reader, http.StatusForbidden},
+		{http.MethodGet, "/tags/", "", reader, http.StatusOK},
+		{http.MethodPost, "/projects", `{"name": "Project"}`, reader, http.StatusForbidden},
+		{http.MethodGet, "/tasks/1", "", "tdl_unknown", http.StatusUnauthorized},
+		{http.MethodGet, "/api-keys", "", reader, http.StatusForbidden},
+		{http.MethodPost, "/api-keys", `{"name": "Escalated", "scopes": ["tasks:write"]}`, reader, http.StatusForbidden},
+	}
+	for _, tc := range test_cases {
+		rec := withKey(tc.method, tc.url, tc.body, tc.key)
+		assert.Equal(t, tc.code, rec.Code, tc.method+" "+tc.url)
+	}
+
+	// Tasks created with key belong to its user
+	rec = doRequest(s, http.MethodGet, "/tasks/1", nil)
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	rec = doRequest(s, http.MethodGet, "/api-keys", nil)
+	var listed []models.APIKey
+	if assert.Equal(t, http.StatusOK, rec.Code) && assert.Nil(t, json.NewDecoder(rec.Body).Decode(&listed)) && assert.Equal(t, 2, len(listed)) {
+		assert.Empty(t, listed[0].Key)
+		assert.Equal(t, keys[service.ScopeTasksRead].Prefix, listed[0].Prefix)
+		assert.NotNil(t, listed[0].LastUsedAt)
+	}
+
+	rec = doRequest(s, http.MethodDelete, fmt.Sprintf("/api-keys/%d", keys[service.ScopeTasksRead].Id), nil)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	rec = doRequest(s, http.MethodDelete, "/api-keys/100", nil)
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	rec = withKey(http.MethodGet, "/tasks/1", "", reader)
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	var problem Problem
+	if assert.Nil(t, json.NewDecoder(rec.Body).Decode(&problem)) {
+		assert.Equal(t, problemTypeUnauthorized, problem.Type)
+	}
+	rec = withKey(http.MethodGet, "/tasks/1", "", writer)
+	if assert.Nil(t, json.NewDecoder(rec.Body).Decode(&problem)) {
+		assert.Equal(t, problemTypeForbidden, problem.Type)
+		assert.Equal(t, "api key has no scope tasks:read", problem.Detail)
+	}
+}
+
+func TestHandleShares(t *testing.T) {
+	s := newTestServer()
+	for _, username := range []string{"viewer", "editor", "stranger"} {
+		s.Service.Register(context.Background(), models.Credentials{Username: username, Password: username + " password"})
+	}
+	asUser := func(username, method, url, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, url, bytes.NewBufferString(body))
+		if username == testUser.Username {
+			req.SetBasicAuth(username, testUser.Password)
+		} else {
+			req.SetBasicAuth(username, username+" password")
+		}
+		rec := httptest.NewRecorder()
+		s.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := doRequest(s, http.MethodPost, "/tasks/", models.Task{Header: "Header", Deadline: time.Now().Add(48 * time.Hour)})
+	assert.Equal(t, http.StatusCreated, rec.Code)
+
+	var test_cases = []struct {
+		username string
+		method   string
+		url      string
+		body     string
+		code     int
+	}{
+		{"viewer", http.MethodGet, "/tasks/1", "", http.StatusForbidden},
+		{testUser.Username, http.MethodPut, "/tasks/1/shares/viewer", `{"role": "owner"}`, http.StatusUnprocessableEntity},
+		{testUser.Username, http.MethodPut, "/tasks/1/shares/nobody", `{"role": "viewer"}`, http.StatusNotFound},
+		{testUser.Username, http.MethodPut, "/tasks/1/shares/viewer", `{"role": `, http.StatusBadRequest},
+		{testUser.Username, http.MethodPut, "/tasks/2/shares/viewer", `{"role": "viewer"}`, http.StatusNotFound},
+		{testUser.Username, http.MethodPut, "/tasks/1/shares/viewer", `{"role": "viewer"}`, http.StatusOK},
+		{testUser.Username, http.MethodPut, "/tasks/1/shares/editor", `{"role": "editor"}`, http.StatusOK},
+		{"viewer", http.MethodGet, "/tasks/1", "", http.StatusOK},
+		{"viewer", http.MethodGet, "/tasks/1/history", "", http.StatusOK},
+		{"viewer", http.MethodGet, "/tasks/1/shares", "", http.StatusOK},
+		{"viewer", http.MethodPatch, "/tasks/1", `{"header": "Viewed"}`, http.StatusForbidden},
+		{"viewer", http.MethodPost, "/tasks/1/checklist", `{"text": "Item"}`, http.StatusForbidden},
+		{"viewer", http.MethodDelete, "/tasks/1", "", http.StatusForbidden},
+		{"editor", http.MethodPatch, "/tasks/1", `{"header": "Edited"}`, http.StatusOK},
+		{"editor", http.MethodPut, "/tasks/1/shares/stranger", `{"role": "editor"}`, http.StatusForbidden},
+		{"editor", http.MethodDelete, "/tasks/1", "", http.StatusForbidden},
+		{"stranger", http.MethodGet, "/tasks/1/shares", "", http.StatusForbidden},
+		{"stranger", http.MethodPut, "/tasks/1", `{"header": "Stolen", "deadline": "2100-01-01"}`, http.StatusForbidden},
+		{"stranger", http.MethodGet, "/tasks/2", "", http.StatusNotFound},
+	}
+	for _, tc := range test_cases {
+		rec := asUser(tc.username, tc.method, tc.url, tc.body)
+		assert.Equal(t, tc.code, rec.Code, tc.username+" "+tc.method+" "+tc.url)
+	}
+
+	rec = asUser("viewer", http.MethodPatch, "/tasks/1", `{"header": "Viewed"}`)
+	var problem Problem
+	if assert.Nil(t, json.NewDecoder(rec.Body).Decode(&problem)) {
+		assert.Equal(t, problemTypeForbidden, problem.Type)
+		assert.Equal(t, "role viewer does not allow update of task with id 1", problem.Detail)
+	}
+
+	// Lists include shared tasks with role of user on them
+	for username, role := range map[string]string{testUser.Username: service.RoleOwner, "editor": service.RoleEditor, "viewer": service.RoleViewer} {
+		var tasks []models.Task
+		rec = asUser(username, http.MethodGet, "/tasks/", "")
+		if assert.Equal(t, http.StatusOK, rec.Code) && assert.Nil(t, json.NewDecoder(rec.Body).Decode(&tasks)) && assert.Equal(t, 1, len(tasks), username) {
+			assert.Equal(t, "Edited", tasks[0].Header)
+			assert.Equal(t, role, tasks[0].Role)
+		}
+	}
+	var tasks []models.Task
+	rec = asUser("stranger", http.MethodGet, "/tasks/", "")
+	if assert.Nil(t, json.NewDecoder(rec.Body).Decode(&tasks)) {
+		assert.Empty(t, tasks)
+	}
+
+	// Role differs between users, so does ETag of task
+	rec = doRequest(s, http.MethodGet, "/tasks/1", nil)
+	req := httptest.NewRequest(http.MethodGet, "/tasks/1", nil)
+	req.SetBasicAuth("viewer", "viewer password")
+	req.Header.Set("If-None-Match", rec.Header().Get("ETag"))
+	rec = httptest.NewRecorder()
+	s.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	var shares []models.TaskShare
+	rec = doRequest(s, http.MethodGet, "/tasks/1/shares", nil)
+	if assert.Equal(t, http.StatusOK, rec.Code) && assert.Nil(t, json.NewDecoder(rec.Body).Decode(&shares)) && assert.Equal(t, 2, len(shares)) {
+		assert.Equal(t, "editor", shares[0].Username)
+		assert.Equal(t, service.RoleEditor, shares[0].Role)
+	}
+
+	rec = doRequest(s, http.MethodDelete, "/tasks/1/shares/viewer", nil)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	rec = doRequest(s, http.MethodDelete, "/tasks/1/shares/viewer", nil)
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	rec = asUser("viewer", http.MethodGet, "/tasks/1", "")
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+}
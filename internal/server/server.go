@@ -9,11 +9,13 @@ import (
 	"time"
 
 	"github.com/O-Tempora/SberIT/config"
+	"github.com/O-Tempora/SberIT/internal/migrations"
 	"github.com/O-Tempora/SberIT/internal/service"
 	"github.com/go-chi/chi/v5"
 	"github.com/jmoiron/sqlx"
 	_ "github.com/lib/pq"
 	"github.com/rs/zerolog"
+	_ "modernc.org/sqlite"
 )
 
 type Server struct {
@@ -22,40 +24,78 @@ type Server struct {
 	Logger  zerolog.Logger
 	Router  *chi.Mux
 	Service service.Service
+	// Keys of bearer tokens loaded by WithTokens, which fails startup if there are none
+	tokens *tokenKeys
+	// Recently verified Basic credentials
+	credentials *credentialCache
 }
 
 func InitServer(cf config.Config) *Server {
 	s := &Server{
-		Config: cf,
-		Logger: zerolog.New(os.Stdout),
-		Router: chi.NewRouter(),
+		Config:      cf,
+		Logger:      zerolog.New(os.Stdout),
+		Router:      chi.NewRouter(),
+		credentials: newCredentialCache(),
 	}
 	return s
 }
 
-func (s *Server) WithDb(host, name string, port int) *Server {
-	db, err := openDb(host, name, port)
+// WithStorage sets up storage selected by config driver
+func (s *Server) WithStorage() *Server {
+	if s.Config.Driver == "memory" {
+		return s.WithRepository(service.NewMemoryRepository())
+	}
+	return s.WithDb()
+}
+
+func (s *Server) WithDb() *Server {
+	db, err := OpenDb(s.Config)
 	if err != nil {
 		log.Fatal(err.Error())
 	}
 	s.Db = db
 
-	_, err = db.Exec(`create table tasks(
-		id serial4 PRIMARY KEY NOT NULL,
-		header text,
-		description text,
-		deadline date,
-		done bool
-	)`)
+	migrator, err := migrations.New(db)
+	if err != nil {
+		log.Fatal(err.Error())
+	}
+	applied, err := migrator.Up()
 	if err != nil {
 		log.Fatal(err.Error())
 	}
+	for _, mg := range applied {
+		s.Logger.Info().Msgf("Applied migration %04d_%s", mg.Version, mg.Name)
+	}
+	return s.WithRepository(service.NewSQLRepository(db))
+}
+
+// WithRepository sets storage used by server's service, e.g. service.NewMemoryRepository() to run without database
+func (s *Server) WithRepository(repo service.TaskRepository) *Server {
 	s.Service = service.Service{
-		Db: db,
+		Repo:         repo,
+		Validation:   s.Config.Validation,
+		Subtasks:     s.Config.Subtasks,
+		Dependencies: s.Config.Dependencies,
+		Agenda:       s.Config.Agenda,
+		Users:        s.Config.Users,
+	}
+	// Invalid timezone is reported by requests, occurrences are computed in UTC then
+	if loc, err := time.LoadLocation(s.Config.Timezone); err == nil {
+		s.Service.Location = loc
 	}
 	return s
 }
 
+// WithTokens loads keys of bearer tokens from config
+func (s *Server) WithTokens() *Server {
+	keys, err := newTokenKeys(s.Config.Auth)
+	if err != nil {
+		log.Fatal(err.Error())
+	}
+	s.tokens = keys
+	return s
+}
+
 func (s *Server) WithLogger(srcs io.Writer) *Server {
 	logger := zerolog.New(zerolog.ConsoleWriter{
 		Out:        srcs,
@@ -73,12 +113,26 @@ func (s *Server) WithLogger(srcs io.Writer) *Server {
 	return s
 }
 
-func openDb(host, name string, port int) (*sqlx.DB, error) {
-	connStr := fmt.Sprintf("postgres://postgres:postgres@%s:%d/%s?sslmode=disable", host, port, name)
-	db, err := sqlx.Open(
-		"postgres",
-		connStr,
-	)
+// OpenDb connects to database of config driver (postgres if not set)
+func OpenDb(cf config.Config) (*sqlx.DB, error) {
+	var db *sqlx.DB
+	var err error
+
+	switch cf.Driver {
+	case "", "postgres":
+		connStr := fmt.Sprintf("postgres://postgres:postgres@%s:%d/%s?sslmode=disable", cf.DbHost, cf.DbPort, cf.DbBase)
+		db, err = sqlx.Open("postgres", connStr)
+	case "sqlite":
+		// Foreign keys are off in sqlite by default, busy timeout lets concurrent writers wait instead of failing
+		db, err = sqlx.Open("sqlite", cf.DbPath+"?_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)")
+		if err == nil {
+			// Sqlite allows only one writer at a time
+			db.SetMaxOpenConns(1)
+		}
+	default:
+		return nil, fmt.Errorf("unknown database driver %q", cf.Driver)
+	}
+
 	if err != nil {
 		return nil, err
 	}
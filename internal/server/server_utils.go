@@ -1,26 +1,91 @@
 package server
 
 import (
+	"errors"
+	"fmt"
 	"net/http"
 	"strconv"
 	"time"
 
+	"github.com/O-Tempora/SberIT/internal/service"
 	"github.com/go-chi/chi/v5"
 )
 
-func getDateFromURL(r *http.Request) (*time.Time, error) {
+// paramError means that path or query parameter of request has invalid value
+type paramError struct {
+	param string
+	err   error
+}
+
+func (e *paramError) Error() string {
+	return fmt.Sprintf("invalid parameter %s: %s", e.param, e.message())
+}
+
+func (e *paramError) Unwrap() error {
+	return e.err
+}
+
+// Message without strconv function names
+func (e *paramError) message() string {
+	var numErr *strconv.NumError
+	if errors.As(e.err, &numErr) {
+		return fmt.Sprintf("%q: %s", numErr.Num, numErr.Err.Error())
+	}
+	return e.err.Error()
+}
+
+// Wraps error of parsing parameter. Returns nil if err is nil
+func badParam(param string, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &paramError{param: param, err: err}
+}
+
+// Returns midnight in loc of date from path params, out of range month or day is an error
+func getDateFromURL(r *http.Request, loc *time.Location) (*time.Time, error) {
 	year, err := strconv.Atoi(chi.URLParam(r, "year"))
 	if err != nil {
-		return nil, err
+		return nil, badParam("year", err)
 	}
 	month, err := strconv.Atoi(chi.URLParam(r, "month"))
 	if err != nil {
-		return nil, err
+		return nil, badParam("month", err)
 	}
 	day, err := strconv.Atoi(chi.URLParam(r, "day"))
 	if err != nil {
-		return nil, err
+		return nil, badParam("day", err)
+	}
+	if month < 1 || month > 12 {
+		return nil, badParam("month", errors.New("must be between 1 and 12"))
+	}
+	// time.Date normalizes out of range values, e.g. February 30 becomes March 1 or 2
+	date := time.Date(year, time.Month(month), day, 0, 0, 0, 0, loc)
+	if day < 1 || date.Month() != time.Month(month) {
+		daysInMonth := time.Date(year, time.Month(month)+1, 0, 0, 0, 0, 0, loc).Day()
+		return nil, badParam("day", fmt.Errorf("must be between 1 and %d", daysInMonth))
 	}
-	date := time.Date(year, time.Month(month), day, 0, 0, 0, 0, time.Local)
 	return &date, nil
 }
+
+// Maps domain errors of service to http status, other errors keep fallback status
+func statusFromError(err error, fallback int) int {
+	switch {
+	case errors.Is(err, service.ErrUnauthorized):
+		return http.StatusUnauthorized
+	case errors.Is(err, service.ErrForbidden):
+		return http.StatusForbidden
+	case errors.Is(err, service.ErrNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, service.ErrValidation):
+		return http.StatusUnprocessableEntity
+	case errors.Is(err, service.ErrConflict):
+		return http.StatusConflict
+	case errors.Is(err, service.ErrPreconditionFailed):
+		return http.StatusPreconditionFailed
+	case errors.Is(err, errPreconditionRequired):
+		return http.StatusPreconditionRequired
+	default:
+		return fallback
+	}
+}
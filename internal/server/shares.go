@@ -0,0 +1,135 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/O-Tempora/SberIT/internal/service"
+	"github.com/go-chi/chi/v5"
+)
+
+// ShareRequest is role granted to user on shared task
+type ShareRequest struct {
+	Role string `json:"role" enums:"viewer,editor" example:"viewer"`
+}
+
+// GetShares godoc
+//
+//	@Summary		Get shares of task
+//	@Description	Returns users task with id from id path param is shared with and their roles, ordered by username. Requires read access to task
+//	@Tags			Shares
+//	@Accept			json
+//	@Produce		json
+//	@Param			id				path	int		true	"Task id"
+//	@Param			If-None-Match	header	string	false	"ETag of cached response"
+//	@Router			/tasks/{id}/shares [get]
+//	@Success		200	{array}		models.TaskShare
+//	@Header			200	{string}	ETag	"Hash of response"
+//	@Success		304
+//	@Failure		400	{object}	Problem
+//	@Failure		401	{object}	Problem
+//	@Failure		403	{object}	Problem
+//	@Failure		404	{object}	Problem
+//	@Failure		500	{object}	Problem
+func (s *Server) handleGetShares(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		s.respond(w, r, http.StatusBadRequest, nil, badParam("id", err))
+		return
+	}
+	if !s.authorize(w, r, id, service.OperationRead) {
+		return
+	}
+	shares, err := s.Service.GetShares(r.Context(), id)
+	if err != nil {
+		s.respond(w, r, http.StatusInternalServerError, nil, err)
+		return
+	}
+	s.respond(w, r, http.StatusOK, shares, nil)
+}
+
+// ShareTask godoc
+//
+//	@Summary		Share task with user
+//	@Description	Grants user with username from path param role viewer or editor on task with id from id path param, replacing role user had before.
+//	@Description	Viewer can read task, editor can also change it, its tags, checklist and dependencies. Only owner of task can share it, delete and restore it
+//	@Tags			Shares
+//	@Accept			json
+//	@Produce		json
+//	@Param			id			path	int				true	"Task id"
+//	@Param			username	path	string			true	"Username"
+//	@Param			share		body	ShareRequest	true	"Role of user"
+//	@Router			/tasks/{id}/shares/{username} [put]
+//	@Success		200
+//	@Failure		400	{object}	Problem
+//	@Failure		401	{object}	Problem
+//	@Failure		403	{object}	Problem
+//	@Failure		404	{object}	Problem
+//	@Failure		422	{object}	Problem
+//	@Failure		500	{object}	Problem
+func (s *Server) handleShareTask(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		s.respond(w, r, http.StatusBadRequest, nil, badParam("id", err))
+		return
+	}
+	if !s.authorize(w, r, id, service.OperationShare) {
+		return
+	}
+	var req ShareRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.respond(w, r, http.StatusBadRequest, nil, err)
+		return
+	}
+	if err := s.Service.ShareTask(r.Context(), id, chi.URLParam(r, "username"), req.Role); err != nil {
+		s.respond(w, r, http.StatusInternalServerError, nil, err)
+		return
+	}
+	s.respond(w, r, http.StatusOK, nil, nil)
+}
+
+// UnshareTask godoc
+//
+//	@Summary		Stop sharing task with user
+//	@Description	Revokes role of user with username from path param on task with id from id path param. Only owner of task can do it
+//	@Tags			Shares
+//	@Accept			json
+//	@Produce		json
+//	@Param			id			path	int		true	"Task id"
+//	@Param			username	path	string	true	"Username"
+//	@Router			/tasks/{id}/shares/{username} [delete]
+//	@Success		200
+//	@Failure		400	{object}	Problem
+//	@Failure		401	{object}	Problem
+//	@Failure		403	{object}	Problem
+//	@Failure		404	{object}	Problem
+//	@Failure		500	{object}	Problem
+func (s *Server) handleUnshareTask(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		s.respond(w, r, http.StatusBadRequest, nil, badParam("id", err))
+		return
+	}
+	if !s.authorize(w, r, id, service.OperationShare) {
+		return
+	}
+	if err := s.Service.UnshareTask(r.Context(), id, chi.URLParam(r, "username")); err != nil {
+		s.respond(w, r, http.StatusInternalServerError, nil, err)
+		return
+	}
+	s.respond(w, r, http.StatusOK, nil, nil)
+}
+
+// Consults policy whether principal of request may do operation on task with id and responds with 403,
+// or 404 if task does not exist, when it may not. Reports whether handler may go on
+func (s *Server) authorize(w http.ResponseWriter, r *http.Request, id int, operation string) bool {
+	if err := s.Service.Authorize(r.Context(), id, operation); err != nil {
+		s.respond(w, r, http.StatusInternalServerError, nil, err)
+		return false
+	}
+	return true
+}
@@ -0,0 +1,238 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/O-Tempora/SberIT/internal/models"
+	"github.com/O-Tempora/SberIT/internal/service"
+	"github.com/go-chi/chi/v5"
+)
+
+// GetTags godoc
+//
+//	@Summary		Get tags
+//	@Description	Returns all tags ordered by name
+//	@Tags			Tags
+//	@Accept			json
+//	@Produce		json
+//	@Param			If-None-Match	header	string	false	"ETag of cached response"
+//	@Router			/tags [get]
+//	@Success		200	{array}		models.Tag
+//	@Header			200	{string}	ETag	"Hash of response"
+//	@Success		304
+//	@Failure		500	{object}	Problem
+func (s *Server) handleGetTags(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	tags, err := s.Service.GetTags(r.Context())
+	if err != nil {
+		s.respond(w, r, http.StatusInternalServerError, nil, err)
+		return
+	}
+	s.respond(w, r, http.StatusOK, tags, nil)
+}
+
+// CreateTag godoc
+//
+//	@Summary		Create tag
+//	@Description	Creates tag and returns its id. Name is required, unique and at most 50 characters long, colour is optional hex colour #rrggbb
+//	@Tags			Tags
+//	@Accept			json
+//	@Produce		json
+//	@Param			tag	body	models.Tag	true	"Tag data"
+//	@Router			/tags [post]
+//	@Success		201	{integer}	Id
+//	@Failure		400	{object}	Problem
+//	@Failure		409	{object}	Problem
+//	@Failure		422	{object}	Problem
+//	@Failure		500	{object}	Problem
+func (s *Server) handleCreateTag(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	var req models.Tag
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.respond(w, r, http.StatusBadRequest, nil, err)
+		return
+	}
+	id, err := s.Service.CreateTag(r.Context(), req)
+	if err != nil {
+		s.respond(w, r, http.StatusInternalServerError, nil, err)
+		return
+	}
+	s.respond(w, r, http.StatusCreated, id, nil)
+}
+
+// UpdateTag godoc
+//
+//	@Summary		Update tag
+//	@Description	Renames tag with id from path param and sets its colour, tag is validated the same way as on create. Tasks with the tag show new name and colour. Only user who created tag changes it
+//	@Tags			Tags
+//	@Accept			json
+//	@Produce		json
+//	@Param			id	path	int			true	"Tag id"
+//	@Param			tag	body	models.Tag	true	"Tag data"
+//	@Router			/tags/{id} [put]
+//	@Success		200
+//	@Failure		400	{object}	Problem
+//	@Failure		403	{object}	Problem
+//	@Failure		404	{object}	Problem
+//	@Failure		409	{object}	Problem
+//	@Failure		422	{object}	Problem
+//	@Failure		500	{object}	Problem
+func (s *Server) handleUpdateTag(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		s.respond(w, r, http.StatusBadRequest, nil, badParam("id", err))
+		return
+	}
+	var req models.Tag
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.respond(w, r, http.StatusBadRequest, nil, err)
+		return
+	}
+	if err := s.Service.UpdateTag(r.Context(), id, req); err != nil {
+		s.respond(w, r, http.StatusInternalServerError, nil, err)
+		return
+	}
+	s.respond(w, r, http.StatusOK, nil, nil)
+}
+
+// DeleteTag godoc
+//
+//	@Summary		Delete tag
+//	@Description	Deletes tag with id from path param and detaches it from all tasks. Only user who created tag deletes it and only if it is not attached to tasks of other users
+//	@Tags			Tags
+//	@Accept			json
+//	@Produce		json
+//	@Param			id	path	int	true	"Tag id"
+//	@Router			/tags/{id} [delete]
+//	@Success		200
+//	@Failure		400	{object}	Problem
+//	@Failure		403	{object}	Problem
+//	@Failure		404	{object}	Problem
+//	@Failure		409	{object}	Problem
+//	@Failure		500	{object}	Problem
+func (s *Server) handleDeleteTag(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		s.respond(w, r, http.StatusBadRequest, nil, badParam("id", err))
+		return
+	}
+	if err := s.Service.DeleteTag(r.Context(), id); err != nil {
+		s.respond(w, r, http.StatusInternalServerError, nil, err)
+		return
+	}
+	s.respond(w, r, http.StatusOK, nil, nil)
+}
+
+// GetTaskTags godoc
+//
+//	@Summary		Get tags of task
+//	@Description	Returns tags attached to task with id from path param ordered by name
+//	@Tags			Tags
+//	@Accept			json
+//	@Produce		json
+//	@Param			id				path	int		true	"Task id"
+//	@Param			If-None-Match	header	string	false	"ETag of cached response"
+//	@Router			/tasks/{id}/tags [get]
+//	@Success		200	{array}		models.Tag
+//	@Header			200	{string}	ETag	"Hash of response"
+//	@Success		304
+//	@Failure		400	{object}	Problem
+//	@Failure		403	{object}	Problem
+//	@Failure		404	{object}	Problem
+//	@Failure		500	{object}	Problem
+func (s *Server) handleGetTaskTags(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		s.respond(w, r, http.StatusBadRequest, nil, badParam("id", err))
+		return
+	}
+	if !s.authorize(w, r, id, service.OperationRead) {
+		return
+	}
+	task, err := s.Service.Get(r.Context(), id)
+	if err != nil {
+		s.respond(w, r, http.StatusInternalServerError, nil, err)
+		return
+	}
+	s.respond(w, r, http.StatusOK, task.Tags, nil)
+}
+
+// AttachTag godoc
+//
+//	@Summary		Attach tag to task
+//	@Description	Attaches tag with id from tag path param to task with id from id path param, does nothing if it is already attached.
+//	@Description	Changes version of task and is recorded in its history
+//	@Tags			Tags
+//	@Accept			json
+//	@Produce		json
+//	@Param			id			path	int		true	"Task id"
+//	@Param			tag			path	int		true	"Tag id"
+//	@Param			If-Match	header	string	false	"ETag of task or its version in quotes, required if enabled in config"
+//	@Router			/tasks/{id}/tags/{tag} [put]
+//	@Success		200
+//	@Failure		400	{object}	Problem
+//	@Failure		403	{object}	Problem
+//	@Failure		404	{object}	Problem
+//	@Failure		412	{object}	Problem
+//	@Failure		428	{object}	Problem
+//	@Failure		500	{object}	Problem
+func (s *Server) handleAttachTag(w http.ResponseWriter, r *http.Request) {
+	s.changeTaskTag(w, r, s.Service.AttachTag)
+}
+
+// DetachTag godoc
+//
+//	@Summary		Detach tag from task
+//	@Description	Detaches tag with id from tag path param from task with id from id path param, does nothing if it is not attached.
+//	@Description	Changes version of task and is recorded in its history
+//	@Tags			Tags
+//	@Accept			json
+//	@Produce		json
+//	@Param			id			path	int		true	"Task id"
+//	@Param			tag			path	int		true	"Tag id"
+//	@Param			If-Match	header	string	false	"ETag of task or its version in quotes, required if enabled in config"
+//	@Router			/tasks/{id}/tags/{tag} [delete]
+//	@Success		200
+//	@Failure		400	{object}	Problem
+//	@Failure		403	{object}	Problem
+//	@Failure		404	{object}	Problem
+//	@Failure		412	{object}	Problem
+//	@Failure		428	{object}	Problem
+//	@Failure		500	{object}	Problem
+func (s *Server) handleDetachTag(w http.ResponseWriter, r *http.Request) {
+	s.changeTaskTag(w, r, s.Service.DetachTag)
+}
+
+// Calls change with task and tag from path and version from If-Match header
+func (s *Server) changeTaskTag(w http.ResponseWriter, r *http.Request, change func(ctx context.Context, id, version, tagId int) error) {
+	w.Header().Set("Content-Type", "application/json")
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		s.respond(w, r, http.StatusBadRequest, nil, badParam("id", err))
+		return
+	}
+	if !s.authorize(w, r, id, service.OperationUpdate) {
+		return
+	}
+	tagId, err := strconv.Atoi(chi.URLParam(r, "tag"))
+	if err != nil {
+		s.respond(w, r, http.StatusBadRequest, nil, badParam("tag", err))
+		return
+	}
+	version, err := s.ifMatchVersion(r)
+	if err != nil {
+		s.respond(w, r, http.StatusBadRequest, nil, err)
+		return
+	}
+	if err := change(r.Context(), id, version, tagId); err != nil {
+		s.respond(w, r, http.StatusInternalServerError, nil, err)
+		return
+	}
+	s.respond(w, r, http.StatusOK, nil, nil)
+}
@@ -0,0 +1,115 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/O-Tempora/SberIT/internal/models"
+
+	// Timezones are resolved even if system has no tz database, e.g. in scratch container
+	_ "time/tzdata"
+)
+
+const timezoneHeader = "X-Timezone"
+
+// Returns timezone of request from X-Timezone header or tz query parameter, timezone from config otherwise
+func (s *Server) requestLocation(r *http.Request) (*time.Location, error) {
+	param, name := timezoneHeader, r.Header.Get(timezoneHeader)
+	if name == "" {
+		param, name = "tz", r.URL.Query().Get("tz")
+	}
+	if name == "" {
+		return time.LoadLocation(s.Config.Timezone)
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, badParam(param, fmt.Errorf("unknown timezone %q", name))
+	}
+	return loc, nil
+}
+
+// Midnight of date's calendar day in loc
+func startOfDay(date time.Time, loc *time.Location) time.Time {
+	y, m, d := date.Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, loc)
+}
+
+// Deadline of request body: RFC 3339 date-time or date only, which means end of that day
+type deadlineValue struct {
+	time     time.Time
+	dateOnly bool
+}
+
+func (d *deadlineValue) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		return nil
+	}
+	var value string
+	if err := json.Unmarshal(data, &value); err != nil {
+		return err
+	}
+	if date, err := time.Parse(dateLayout, value); err == nil {
+		*d = deadlineValue{time: date, dateOnly: true}
+		return nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return errors.New("deadline must be RFC 3339 date-time or date in format YYYY-MM-DD")
+	}
+	*d = deadlineValue{time: t}
+	return nil
+}
+
+// Returns deadline as time, date only deadline is the last second of its day in loc
+func (d deadlineValue) resolve(loc *time.Location) time.Time {
+	if !d.dateOnly {
+		return d.time
+	}
+	y, m, day := d.time.Date()
+	return time.Date(y, m, day, 23, 59, 59, 0, loc)
+}
+
+// Reports whether deadline equals t, date only deadline equals any time of that day in loc
+func (d deadlineValue) matches(t time.Time, loc *time.Location) bool {
+	if d.dateOnly {
+		return d.time.Format(dateLayout) == t.In(loc).Format(dateLayout)
+	}
+	return d.time.Equal(t)
+}
+
+// Decodes task from request body, date only deadline is resolved in loc
+func decodeTask(body io.Reader, loc *time.Location) (models.Task, error) {
+	// Deadline of embedded task is shadowed, so it is decoded by deadlineValue
+	var req struct {
+		models.Task
+		Deadline deadlineValue `json:"deadline"`
+	}
+	if err := json.NewDecoder(body).Decode(&req); err != nil {
+		return models.Task{}, err
+	}
+	task := req.Task
+	task.Deadline = req.Deadline.resolve(loc)
+	return task, nil
+}
+
+// Shows times of tasks in loc
+func inLocation(tasks []models.Task, loc *time.Location) {
+	for i := range tasks {
+		tasks[i].Deadline = tasks[i].Deadline.In(loc)
+		tasks[i].DeletedAt = timeIn(tasks[i].DeletedAt, loc)
+		tasks[i].ArchivedAt = timeIn(tasks[i].ArchivedAt, loc)
+		inLocation(tasks[i].Children, loc)
+	}
+}
+
+func timeIn(t *time.Time, loc *time.Location) *time.Time {
+	if t == nil {
+		return nil
+	}
+	local := t.In(loc)
+	return &local
+}
@@ -0,0 +1,240 @@
+package server
+
+import (
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/O-Tempora/SberIT/config"
+	"github.com/O-Tempora/SberIT/internal/models"
+	"github.com/O-Tempora/SberIT/internal/service"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	defaultAccessTTL  = 15 * time.Minute
+	defaultRefreshTTL = 30 * 24 * time.Hour
+)
+
+// Values of token_use claim, refresh tokens can not authenticate requests and access tokens can not be refreshed
+const (
+	tokenUseAccess  = "access"
+	tokenUseRefresh = "refresh"
+)
+
+var errTokenIssuance = errors.New("issuance of tokens is not configured")
+
+// Claims of tokens. Subject is id of user
+type tokenClaims struct {
+	Username string `json:"username"`
+	Use      string `json:"token_use"`
+	jwt.RegisteredClaims
+}
+
+// TokenPair is issued to user on login and on refresh
+type TokenPair struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+	TokenType    string `json:"token_type" example:"Bearer"`
+	// Lifetime of access token in seconds
+	ExpiresIn int `json:"expires_in" example:"900"`
+}
+
+// RefreshRequest is body of token refresh
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token" validate:"required"`
+}
+
+// Keys which sign and verify tokens
+type tokenKeys struct {
+	config config.Auth
+	method jwt.SigningMethod
+	// []byte for HS256 or *rsa.PrivateKey for RS256, nil if server does not issue tokens
+	signing interface{}
+	// []byte or *rsa.PublicKey by key id, "" for key without id
+	verifying map[string]interface{}
+}
+
+// Loads keys of config. At least one key must verify tokens
+func newTokenKeys(cf config.Auth) (*tokenKeys, error) {
+	keys := &tokenKeys{config: cf, verifying: make(map[string]interface{})}
+	switch cf.Algorithm {
+	case "", "HS256":
+		keys.method = jwt.SigningMethodHS256
+		if cf.Secret != "" {
+			keys.signing = []byte(cf.Secret)
+			keys.verifying[cf.KeyId] = []byte(cf.Secret)
+		}
+	case "RS256":
+		keys.method = jwt.SigningMethodRS256
+		if cf.PrivateKeyFile != "" {
+			pem, err := os.ReadFile(cf.PrivateKeyFile)
+			if err != nil {
+				return nil, err
+			}
+			private, err := jwt.ParseRSAPrivateKeyFromPEM(pem)
+			if err != nil {
+				return nil, fmt.Errorf("private key %s: %w", cf.PrivateKeyFile, err)
+			}
+			keys.signing = private
+			keys.verifying[cf.KeyId] = &private.PublicKey
+		}
+		if cf.PublicKeyFile != "" {
+			pem, err := os.ReadFile(cf.PublicKeyFile)
+			if err != nil {
+				return nil, err
+			}
+			public, err := jwt.ParseRSAPublicKeyFromPEM(pem)
+			if err != nil {
+				return nil, fmt.Errorf("public key %s: %w", cf.PublicKeyFile, err)
+			}
+			keys.verifying[cf.KeyId] = public
+		}
+	default:
+		return nil, fmt.Errorf("unsupported token algorithm %q, must be HS256 or RS256", cf.Algorithm)
+	}
+
+	if cf.JWKSFile != "" {
+		if err := keys.loadJWKS(cf.JWKSFile); err != nil {
+			return nil, fmt.Errorf("jwks %s: %w", cf.JWKSFile, err)
+		}
+	}
+	if len(keys.verifying) == 0 {
+		return nil, fmt.Errorf("no keys to verify %s tokens, set JWT_SECRET environment variable or key files in auth section of config", keys.method.Alg())
+	}
+	return keys, nil
+}
+
+// Key of JSON Web Key Set as described in RFC 7517
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Alg string `json:"alg"`
+	Use string `json:"use"`
+	// Modulus and exponent of RSA key
+	N string `json:"n"`
+	E string `json:"e"`
+	// Symmetric key
+	K string `json:"k"`
+}
+
+// Adds keys of JWKS file which verify tokens of keys algorithm, other keys are skipped
+func (k *tokenKeys) loadJWKS(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var set struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err = json.Unmarshal(data, &set); err != nil {
+		return err
+	}
+	for _, key := range set.Keys {
+		if (key.Use != "" && key.Use != "sig") || (key.Alg != "" && key.Alg != k.method.Alg()) {
+			continue
+		}
+		switch {
+		case key.Kty == "RSA" && k.method == jwt.SigningMethodRS256:
+			n, err := base64.RawURLEncoding.DecodeString(key.N)
+			if err != nil {
+				return fmt.Errorf("key %q: modulus: %w", key.Kid, err)
+			}
+			e, err := base64.RawURLEncoding.DecodeString(key.E)
+			if err != nil {
+				return fmt.Errorf("key %q: exponent: %w", key.Kid, err)
+			}
+			k.verifying[key.Kid] = &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+		case key.Kty == "oct" && k.method == jwt.SigningMethodHS256:
+			secret, err := base64.RawURLEncoding.DecodeString(key.K)
+			if err != nil {
+				return fmt.Errorf("key %q: %w", key.Kid, err)
+			}
+			k.verifying[key.Kid] = secret
+		}
+	}
+	return nil
+}
+
+// Issues access and refresh tokens of user
+func (k *tokenKeys) issue(user models.User) (*TokenPair, error) {
+	if k.signing == nil {
+		return nil, errTokenIssuance
+	}
+	accessTTL, refreshTTL := k.config.AccessTTL, k.config.RefreshTTL
+	if accessTTL <= 0 {
+		accessTTL = defaultAccessTTL
+	}
+	if refreshTTL <= 0 {
+		refreshTTL = defaultRefreshTTL
+	}
+	access, err := k.sign(user, tokenUseAccess, accessTTL)
+	if err != nil {
+		return nil, err
+	}
+	refresh, err := k.sign(user, tokenUseRefresh, refreshTTL)
+	if err != nil {
+		return nil, err
+	}
+	return &TokenPair{AccessToken: access, RefreshToken: refresh, TokenType: "Bearer", ExpiresIn: int(accessTTL.Seconds())}, nil
+}
+
+func (k *tokenKeys) sign(user models.User, use string, ttl time.Duration) (string, error) {
+	now := time.Now()
+	token := jwt.NewWithClaims(k.method, tokenClaims{
+		Username: user.Username,
+		Use:      use,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   strconv.Itoa(user.Id),
+			Issuer:    k.config.Issuer,
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+		},
+	})
+	if k.config.KeyId != "" {
+		token.Header["kid"] = k.config.KeyId
+	}
+	return token.SignedString(k.signing)
+}
+
+// Verifies token of use and returns principal it was issued to. Invalid tokens are AuthenticationError
+func (k *tokenKeys) verify(raw, use string) (service.Principal, error) {
+	options := []jwt.ParserOption{jwt.WithValidMethods([]string{k.method.Alg()}), jwt.WithExpirationRequired()}
+	if k.config.Issuer != "" {
+		options = append(options, jwt.WithIssuer(k.config.Issuer))
+	}
+	var claims tokenClaims
+	_, err := jwt.ParseWithClaims(raw, &claims, k.key, options...)
+	if err != nil {
+		return service.Principal{}, &service.AuthenticationError{Message: "invalid token: " + err.Error()}
+	}
+	id, err := strconv.Atoi(claims.Subject)
+	if err != nil || claims.Use != use {
+		return service.Principal{}, &service.AuthenticationError{Message: fmt.Sprintf("invalid token: must be %s token", use)}
+	}
+	return service.Principal{UserId: id, Username: claims.Username}, nil
+}
+
+// Returns key verifying token by its kid header. Key from config without id verifies tokens with any other kid,
+// token without kid is verified by the only key if there is one
+func (k *tokenKeys) key(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	if key, ok := k.verifying[kid]; ok {
+		return key, nil
+	}
+	if key, ok := k.verifying[""]; ok {
+		return key, nil
+	}
+	if kid == "" && len(k.verifying) == 1 {
+		for _, key := range k.verifying {
+			return key, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown key %q", kid)
+}
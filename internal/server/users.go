@@ -0,0 +1,291 @@
+package server
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/O-Tempora/SberIT/internal/models"
+	"github.com/O-Tempora/SberIT/internal/service"
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+// Sent in WWW-Authenticate header of 401 responses, one header per accepted scheme
+const (
+	bearerChallenge = `Bearer realm="tasks"`
+	basicChallenge  = `Basic realm="tasks", charset="UTF-8"`
+)
+
+var (
+	errMissingCredentials = &service.AuthenticationError{Message: "api key, bearer token or username and password are required"}
+	errUserGone           = &service.AuthenticationError{Message: "user of token does not exist"}
+	errBearerUnsupported  = &service.AuthenticationError{Message: "bearer tokens are not accepted"}
+)
+
+// Register godoc
+//
+//	@Summary		Register user
+//	@Description	Creates user and returns its id. Username is required, unique and consists of 3 to 50 latin letters, digits, dots, dashes or underscores, password must be 8 to 72 bytes long. User authenticates requests to tasks, projects and tags with bearer token from /token or with Basic authorization and sees only own tasks
+//	@Tags			Users
+//	@Accept			json
+//	@Produce		json
+//	@Param			credentials	body	models.Credentials	true	"Username and password"
+//	@Router			/users [post]
+//	@Success		201	{integer}	Id
+//	@Failure		400	{object}	Problem
+//	@Failure		409	{object}	Problem
+//	@Failure		422	{object}	Problem
+//	@Failure		500	{object}	Problem
+func (s *Server) handleRegister(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	var req models.Credentials
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.respond(w, r, http.StatusBadRequest, nil, err)
+		return
+	}
+	id, err := s.Service.Register(r.Context(), req)
+	if err != nil {
+		s.respond(w, r, http.StatusInternalServerError, nil, err)
+		return
+	}
+	s.respond(w, r, http.StatusCreated, id, nil)
+}
+
+// Login godoc
+//
+//	@Summary		Log in
+//	@Description	Checks username and password and returns user
+//	@Tags			Users
+//	@Accept			json
+//	@Produce		json
+//	@Param			credentials	body	models.Credentials	true	"Username and password"
+//	@Router			/login [post]
+//	@Success		200	{object}	models.User
+//	@Failure		400	{object}	Problem
+//	@Failure		401	{object}	Problem
+//	@Failure		500	{object}	Problem
+func (s *Server) handleLogin(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	var req models.Credentials
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.respond(w, r, http.StatusBadRequest, nil, err)
+		return
+	}
+	user, err := s.Service.Login(r.Context(), req)
+	if err != nil {
+		s.respond(w, r, http.StatusInternalServerError, nil, err)
+		return
+	}
+	s.respond(w, r, http.StatusOK, user, nil)
+}
+
+// IssueToken godoc
+//
+//	@Summary		Issue token
+//	@Description	Checks username and password and issues access token, which authenticates requests in Authorization header as Bearer token, and refresh token, which is exchanged for new tokens before access token expires
+//	@Tags			Users
+//	@Accept			json
+//	@Produce		json
+//	@Param			credentials	body	models.Credentials	true	"Username and password"
+//	@Router			/token [post]
+//	@Success		200	{object}	TokenPair
+//	@Failure		400	{object}	Problem
+//	@Failure		401	{object}	Problem
+//	@Failure		501	{object}	Problem
+//	@Failure		500	{object}	Problem
+func (s *Server) handleIssueToken(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if s.tokens == nil {
+		s.respond(w, r, http.StatusNotImplemented, nil, errTokenIssuance)
+		return
+	}
+	var req models.Credentials
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.respond(w, r, http.StatusBadRequest, nil, err)
+		return
+	}
+	user, err := s.Service.Login(r.Context(), req)
+	if err != nil {
+		s.respond(w, r, http.StatusInternalServerError, nil, err)
+		return
+	}
+	s.respondTokens(w, r, *user)
+}
+
+// RefreshToken godoc
+//
+//	@Summary		Refresh token
+//	@Description	Exchanges refresh token for new access and refresh tokens. User of token must still exist
+//	@Tags			Users
+//	@Accept			json
+//	@Produce		json
+//	@Param			token	body	RefreshRequest	true	"Refresh token"
+//	@Router			/token/refresh [post]
+//	@Success		200	{object}	TokenPair
+//	@Failure		400	{object}	Problem
+//	@Failure		401	{object}	Problem
+//	@Failure		501	{object}	Problem
+//	@Failure		500	{object}	Problem
+func (s *Server) handleRefreshToken(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if s.tokens == nil {
+		s.respond(w, r, http.StatusNotImplemented, nil, errTokenIssuance)
+		return
+	}
+	var req RefreshRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.respond(w, r, http.StatusBadRequest, nil, err)
+		return
+	}
+	principal, err := s.tokens.verify(req.RefreshToken, tokenUseRefresh)
+	if err != nil {
+		s.respond(w, r, http.StatusUnauthorized, nil, err)
+		return
+	}
+	user, err := s.Service.GetUser(r.Context(), principal.Username)
+	if errors.Is(err, service.ErrNotFound) || (err == nil && user.Id != principal.UserId) {
+		s.respond(w, r, http.StatusUnauthorized, nil, errUserGone)
+		return
+	}
+	if err != nil {
+		s.respond(w, r, http.StatusInternalServerError, nil, err)
+		return
+	}
+	s.respondTokens(w, r, *user)
+}
+
+// Responds with new tokens of user, which must not be cached
+func (s *Server) respondTokens(w http.ResponseWriter, r *http.Request, user models.User) {
+	tokens, err := s.tokens.issue(user)
+	if errors.Is(err, errTokenIssuance) {
+		s.respond(w, r, http.StatusNotImplemented, nil, err)
+		return
+	}
+	if err != nil {
+		s.respond(w, r, http.StatusInternalServerError, nil, err)
+		return
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	s.respond(w, r, http.StatusOK, tokens, nil)
+}
+
+// Authenticates request with api key, bearer access token or username and password of Basic authorization. User becomes
+// principal, whose tasks are available to the request, and actor of its changes. Requests without valid credentials
+// are rejected with 401
+func (s *Server) authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		principal, err := s.principal(r)
+		if err != nil {
+			if errors.Is(err, service.ErrUnauthorized) {
+				if s.tokens != nil {
+					w.Header().Add("WWW-Authenticate", bearerChallenge)
+				}
+				w.Header().Add("WWW-Authenticate", basicChallenge)
+			}
+			s.respond(w, r, http.StatusInternalServerError, nil, err)
+			return
+		}
+		ctx := service.WithPrincipal(r.Context(), principal)
+		ctx = service.WithAudit(ctx, service.Audit{Actor: principal.Username, RequestId: middleware.GetReqID(ctx)})
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// Returns principal of api key in X-API-Key header or of credentials in Authorization header
+func (s *Server) principal(r *http.Request) (service.Principal, error) {
+	if key := r.Header.Get(apiKeyHeader); key != "" {
+		return s.Service.AuthenticateAPIKey(r.Context(), key)
+	}
+	if scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " "); ok && strings.EqualFold(scheme, "Bearer") {
+		if s.tokens == nil {
+			return service.Principal{}, errBearerUnsupported
+		}
+		return s.tokens.verify(strings.TrimSpace(token), tokenUseAccess)
+	}
+	username, password, ok := r.BasicAuth()
+	if !ok {
+		return service.Principal{}, errMissingCredentials
+	}
+	if principal, ok := s.credentials.get(username, password, time.Now()); ok {
+		return principal, nil
+	}
+	user, err := s.Service.Login(r.Context(), models.Credentials{Username: username, Password: password})
+	if err != nil {
+		return service.Principal{}, err
+	}
+	principal := service.Principal{UserId: user.Id, Username: user.Username}
+	s.credentials.put(username, password, principal, time.Now())
+	return principal, nil
+}
+
+const (
+	// Verified Basic credentials are not checked with bcrypt again for this long
+	credentialsTTL = time.Minute
+	// Expired credentials are dropped when cache grows to this size, all credentials if none are expired
+	maxCachedCredentials = 1000
+)
+
+// Basic credentials verified recently, so that every request of user does not cost bcrypt hashing of its password.
+// Credentials are kept as HMAC with random key of the process, not as they are
+type credentialCache struct {
+	mu      sync.Mutex
+	key     []byte
+	entries map[string]cachedPrincipal
+}
+
+type cachedPrincipal struct {
+	principal service.Principal
+	expires   time.Time
+}
+
+func newCredentialCache() *credentialCache {
+	key := make([]byte, sha256.Size)
+	if _, err := rand.Read(key); err != nil {
+		panic(err)
+	}
+	return &credentialCache{key: key, entries: make(map[string]cachedPrincipal)}
+}
+
+// Returns principal of credentials if they were verified within credentialsTTL before now
+func (c *credentialCache) get(username, password string, now time.Time) (service.Principal, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[c.digest(username, password)]
+	if !ok || !now.Before(entry.expires) {
+		return service.Principal{}, false
+	}
+	return entry.principal, true
+}
+
+// Remembers principal of verified credentials
+func (c *credentialCache) put(username, password string, principal service.Principal, now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if len(c.entries) >= maxCachedCredentials {
+		for digest, entry := range c.entries {
+			if !now.Before(entry.expires) {
+				delete(c.entries, digest)
+			}
+		}
+		if len(c.entries) >= maxCachedCredentials {
+			clear(c.entries)
+		}
+	}
+	c.entries[c.digest(username, password)] = cachedPrincipal{principal: principal, expires: now.Add(credentialsTTL)}
+}
+
+func (c *credentialCache) digest(username, password string) string {
+	mac := hmac.New(sha256.New, c.key)
+	mac.Write([]byte(username))
+	mac.Write([]byte{0})
+	mac.Write([]byte(password))
+	return string(mac.Sum(nil))
+}
@@ -0,0 +1,81 @@
+package service
+
+import (
+	"context"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/O-Tempora/SberIT/config"
+	"github.com/O-Tempora/SberIT/internal/models"
+)
+
+// Weights used if they are not set in config
+const (
+	defaultPriorityWeight = 1
+	defaultDeadlineWeight = 2
+	defaultOverdueWeight  = 3
+	defaultAgendaHorizon  = 7 * 24 * time.Hour
+)
+
+// ScoredTask is a task of agenda with its score, tasks with higher score should be done first
+type ScoredTask struct {
+	models.Task
+	Score float64
+}
+
+// GetAgenda returns tasks matching filter ranked by score, highest first, ties are ordered by deadline and id.
+// Status is filtered the same way as in GetList, but only open tasks are ranked if filter has no status. Sort of filter is ignored
+func (s *Service) GetAgenda(ctx context.Context, filter TaskFilter) ([]ScoredTask, error) {
+	if filter.Done == nil {
+		done := false
+		filter.Done = &done
+	}
+	filter.Sort = []SortField{{Field: "deadline"}}
+	tasks, err := s.Repo.List(ctx, ListFilter{TaskFilter: filter})
+	if err != nil {
+		return nil, err
+	}
+	weights := agendaWeights(s.Agenda)
+	now := time.Now()
+	ranked := make([]ScoredTask, len(tasks))
+	for i, task := range tasks {
+		ranked[i] = ScoredTask{Task: task, Score: taskScore(task, weights, now)}
+	}
+	sort.SliceStable(ranked, func(i, j int) bool {
+		return ranked[i].Score > ranked[j].Score
+	})
+	return ranked, nil
+}
+
+// Returns weights of config with defaults in place of zero values
+func agendaWeights(cf config.Agenda) config.Agenda {
+	if cf.PriorityWeight == 0 {
+		cf.PriorityWeight = defaultPriorityWeight
+	}
+	if cf.DeadlineWeight == 0 {
+		cf.DeadlineWeight = defaultDeadlineWeight
+	}
+	if cf.OverdueWeight == 0 {
+		cf.OverdueWeight = defaultOverdueWeight
+	}
+	if cf.Horizon <= 0 {
+		cf.Horizon = defaultAgendaHorizon
+	}
+	return cf
+}
+
+// Score of task at now rounded to hundredths: priority weight for every priority level, share of deadline weight which grows
+// as deadline approaches within horizon and overdue weight for overdue task. Done task is scored only by priority
+func taskScore(task models.Task, weights config.Agenda, now time.Time) float64 {
+	score := weights.PriorityWeight * float64(task.Priority)
+	left := task.Deadline.Sub(now)
+	switch {
+	case task.Done:
+	case left < 0:
+		score += weights.DeadlineWeight + weights.OverdueWeight
+	case left < weights.Horizon:
+		score += weights.DeadlineWeight * (1 - float64(left)/float64(weights.Horizon))
+	}
+	return math.Round(score*100) / 100
+}
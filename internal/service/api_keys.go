@@ -0,0 +1,149 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/O-Tempora/SberIT/internal/models"
+)
+
+// Scopes of api keys
+const (
+	ScopeTasksRead  = "tasks:read"
+	ScopeTasksWrite = "tasks:write"
+)
+
+const (
+	apiKeyPrefix = "tdl_"
+	// Random bytes of key
+	apiKeyBytes = 32
+	// Length of key prefix shown in lists
+	apiKeyShown   = 10
+	maxAPIKeyName = 100
+	// Last use of key is updated at most once per interval, so requests do not write to storage every time
+	lastUsedInterval = time.Minute
+)
+
+var scopes = []string{ScopeTasksRead, ScopeTasksWrite}
+
+// Allows reports whether principal may do operations of scope. Users may do everything, api keys only what their scopes allow
+func (p Principal) Allows(scope string) bool {
+	return p.Scopes == nil || slices.Contains(p.Scopes, scope)
+}
+
+// CreateAPIKey creates key of principal with name and scopes and returns it with the key itself, which is not stored
+// and can not be shown again. Keys can be created only with credentials of user
+func (s *Service) CreateAPIKey(ctx context.Context, key models.APIKey) (*models.APIKey, error) {
+	principal, err := userPrincipal(ctx)
+	if err != nil {
+		return nil, err
+	}
+	key.Name = strings.TrimSpace(key.Name)
+	key.Scopes = normalizeScopes(key.Scopes)
+	if err = validateAPIKey(key); err != nil {
+		return nil, err
+	}
+
+	random := make([]byte, apiKeyBytes)
+	if _, err = rand.Read(random); err != nil {
+		return nil, err
+	}
+	created := models.APIKey{
+		UserId:    principal.UserId,
+		Name:      key.Name,
+		Scopes:    key.Scopes,
+		Key:       apiKeyPrefix + base64.RawURLEncoding.EncodeToString(random),
+		CreatedAt: normalizeTime(time.Now()),
+	}
+	created.Prefix = created.Key[:apiKeyShown]
+	created.KeyHash = hashAPIKey(created.Key)
+	if created.Id, err = s.Repo.CreateAPIKey(ctx, created); err != nil {
+		return nil, err
+	}
+	created.KeyHash = ""
+	return &created, nil
+}
+
+// GetAPIKeys returns keys of principal, revoked ones too. Keys can be listed only with credentials of user
+func (s *Service) GetAPIKeys(ctx context.Context) ([]models.APIKey, error) {
+	if _, err := userPrincipal(ctx); err != nil {
+		return nil, err
+	}
+	return s.Repo.APIKeys(ctx)
+}
+
+// RevokeAPIKey revokes key of principal with id, so it no longer authenticates requests
+func (s *Service) RevokeAPIKey(ctx context.Context, id int) error {
+	if _, err := userPrincipal(ctx); err != nil {
+		return err
+	}
+	return s.Repo.RevokeAPIKey(ctx, id)
+}
+
+// AuthenticateAPIKey returns principal of key, which is user of key with scopes of key, and records use of key
+func (s *Service) AuthenticateAPIKey(ctx context.Context, raw string) (Principal, error) {
+	key, err := s.Repo.APIKeyByHash(ctx, hashAPIKey(raw))
+	if err != nil {
+		return Principal{}, err
+	}
+	now := time.Now()
+	if key.LastUsedAt == nil || now.Sub(*key.LastUsedAt) >= lastUsedInterval {
+		if err = s.Repo.TouchAPIKey(ctx, key.Id, now); err != nil {
+			return Principal{}, err
+		}
+	}
+	return Principal{UserId: key.UserId, Username: key.Username, Scopes: key.Scopes}, nil
+}
+
+// Returns principal of ctx if it is user authenticated with its credentials
+func userPrincipal(ctx context.Context) (Principal, error) {
+	principal, ok := PrincipalFrom(ctx)
+	if !ok {
+		return principal, &AuthenticationError{Message: "user is not authenticated"}
+	}
+	if principal.Scopes != nil {
+		return principal, errUserRequired
+	}
+	return principal, nil
+}
+
+// Keys have enough entropy to be hashed without salt, so key is found by its hash
+func hashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// Returns scopes sorted without duplicates
+func normalizeScopes(scopes []string) []string {
+	normalized := []string{}
+	for _, scope := range scopes {
+		normalized = append(normalized, strings.TrimSpace(scope))
+	}
+	slices.Sort(normalized)
+	return slices.Compact(normalized)
+}
+
+// Checks name and scopes of key and joins violations into one error
+func validateAPIKey(key models.APIKey) error {
+	var errs []error
+	if key.Name == "" || utf8.RuneCountInString(key.Name) > maxAPIKeyName {
+		errs = append(errs, &ValidationError{Field: "name", Message: "is required and must be at most 100 characters long"})
+	}
+	if len(key.Scopes) == 0 {
+		errs = append(errs, &ValidationError{Field: "scopes", Message: "at least one scope is required"})
+	}
+	for _, scope := range key.Scopes {
+		if !slices.Contains(scopes, scope) {
+			errs = append(errs, &ValidationError{Field: "scopes", Message: "unknown scope " + scope + ", must be tasks:read or tasks:write"})
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,121 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"sort"
+	"time"
+
+	"github.com/O-Tempora/SberIT/internal/models"
+)
+
+// Actions of task events
+const (
+	ActionCreate    = "create"
+	ActionUpdate    = "update"
+	ActionDelete    = "delete"
+	ActionRestore   = "restore"
+	ActionPurge     = "purge"
+	ActionArchive   = "archive"
+	ActionUnarchive = "unarchive"
+)
+
+// Actor of changes made without Audit in context, e.g. by purge of trash
+const systemActor = "system"
+
+// Audit identifies who makes changes of tasks, it is recorded in their history
+type Audit struct {
+	Actor     string
+	RequestId string
+}
+
+type auditKey struct{}
+
+// WithAudit returns ctx which changes of tasks are recorded with
+func WithAudit(ctx context.Context, audit Audit) context.Context {
+	return context.WithValue(ctx, auditKey{}, audit)
+}
+
+// Returns Audit of ctx, changes without it are made by system
+func auditFrom(ctx context.Context) Audit {
+	audit, _ := ctx.Value(auditKey{}).(Audit)
+	if audit.Actor == "" {
+		audit.Actor = systemActor
+	}
+	return audit
+}
+
+// Builds event of change from old to new state of task, nil state means that task did not exist
+func newTaskEvent(ctx context.Context, action string, old, new *models.Task) (models.TaskEvent, error) {
+	audit := auditFrom(ctx)
+	event := models.TaskEvent{
+		Action:    action,
+		Actor:     audit.Actor,
+		RequestId: audit.RequestId,
+		CreatedAt: normalizeTime(time.Now()),
+	}
+	var err error
+	// Role depends on who reads task, it is not part of its state
+	if old != nil {
+		task := *old
+		task.Role = ""
+		event.TaskId = old.Id
+		if event.OldData, err = json.Marshal(task); err != nil {
+			return event, err
+		}
+	}
+	if new != nil {
+		task := *new
+		task.Role = ""
+		event.TaskId = new.Id
+		if event.NewData, err = json.Marshal(task); err != nil {
+			return event, err
+		}
+	}
+	return event, nil
+}
+
+// Fields which change with any other or with subtasks and are not shown in history
+var untrackedFields = map[string]bool{"id": true, "version": true, "progress": true}
+
+// Returns changed fields of task between old and new JSON ordered by name, null JSON is task which does not exist
+func diffTask(old, new []byte) ([]models.FieldChange, error) {
+	var before, after map[string]interface{}
+	if len(old) > 0 {
+		if err := json.Unmarshal(old, &before); err != nil {
+			return nil, err
+		}
+	}
+	if len(new) > 0 {
+		if err := json.Unmarshal(new, &after); err != nil {
+			return nil, err
+		}
+	}
+
+	fields := make(map[string]bool)
+	for field := range before {
+		fields[field] = true
+	}
+	for field := range after {
+		fields[field] = true
+	}
+	changes := []models.FieldChange{}
+	for field := range fields {
+		if untrackedFields[field] || reflect.DeepEqual(before[field], after[field]) || (isEmpty(before[field]) && isEmpty(after[field])) {
+			continue
+		}
+		changes = append(changes, models.FieldChange{Field: field, Old: before[field], New: after[field]})
+	}
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Field < changes[j].Field
+	})
+	return changes, nil
+}
+
+// Missing field, empty list, empty string and zero are the same, e.g. tags, recurrence or priority of events
+// recorded before tasks had them
+func isEmpty(value interface{}) bool {
+	list, ok := value.([]interface{})
+	return value == nil || value == "" || value == float64(0) || (ok && len(list) == 0)
+}
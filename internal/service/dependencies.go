@@ -0,0 +1,139 @@
+package service
+
+import (
+	"context"
+
+	"github.com/O-Tempora/SberIT/internal/models"
+)
+
+// Dependencies are tasks which block task and tasks which are blocked by it
+type Dependencies struct {
+	BlockedBy []models.Task
+	Blocking  []models.Task
+}
+
+// GetDependencies returns tasks out of trash which directly block task with id and which are directly blocked by it, ordered by id
+func (s *Service) GetDependencies(ctx context.Context, id int) (*Dependencies, error) {
+	if _, err := s.Repo.Get(ctx, id); err != nil {
+		return nil, err
+	}
+	blockers, err := s.Repo.List(ctx, ListFilter{TaskFilter: TaskFilter{Blocking: &id, IncludeArchived: true}})
+	if err != nil {
+		return nil, err
+	}
+	blocked, err := s.Repo.List(ctx, ListFilter{TaskFilter: TaskFilter{BlockedBy: &id, IncludeArchived: true}})
+	if err != nil {
+		return nil, err
+	}
+	return &Dependencies{BlockedBy: blockers, Blocking: blocked}, nil
+}
+
+// AddBlocker makes task blocked by task with blockerId, it is recorded as update of task. As Update, it checks version
+// of task unless it is 0. Fails with ConflictError if blocker depends on task, so dependencies can not make a cycle
+func (s *Service) AddBlocker(ctx context.Context, id, version, blockerId int) error {
+	return s.Repo.AddBlocker(ctx, id, version, blockerId)
+}
+
+// RemoveBlocker makes task no longer blocked by task with blockerId the same way as AddBlocker adds it
+func (s *Service) RemoveBlocker(ctx context.Context, id, version, blockerId int) error {
+	return s.Repo.RemoveBlocker(ctx, id, version, blockerId)
+}
+
+// GetReady returns open tasks which can be worked on now, because all their blockers are done (or in trash or archived),
+// ordered by deadline. Open blockers of other users block tasks too, as they do in checkDone.
+// If all is set, other open tasks follow them in topological order: every task comes after its blockers
+func (s *Service) GetReady(ctx context.Context, all bool) ([]models.Task, error) {
+	done := false
+	tasks, err := s.Repo.List(ctx, ListFilter{TaskFilter: TaskFilter{Done: &done, Sort: []SortField{{Field: "deadline"}}}})
+	if err != nil {
+		return nil, err
+	}
+	hidden, err := s.hiddenBlockers(ctx, tasks)
+	if err != nil {
+		return nil, err
+	}
+	layers := topologicalLayers(tasks, hidden)
+	ready := []models.Task{}
+	for i, layer := range layers {
+		if i > 0 && !all {
+			break
+		}
+		ready = append(ready, layer...)
+	}
+	return ready, nil
+}
+
+// Returns numbers of open blockers which are not among tasks by id of task they block, for tasks which have them.
+// They are blockers not available in ctx, which are counted without principal
+func (s *Service) hiddenBlockers(ctx context.Context, tasks []models.Task) (map[int]int, error) {
+	listed := make(map[int]bool)
+	for _, t := range tasks {
+		listed[t.Id] = true
+	}
+	hidden := make(map[int]int)
+	done := false
+	for _, t := range tasks {
+		listedBlockers := 0
+		for _, blocker := range t.BlockedBy {
+			if listed[blocker] {
+				listedBlockers++
+			}
+		}
+		if listedBlockers == len(t.BlockedBy) {
+			continue
+		}
+		open, err := s.Repo.Count(withoutPrincipal(ctx), ListFilter{TaskFilter: TaskFilter{Blocking: &t.Id, Done: &done}})
+		if err != nil {
+			return nil, err
+		}
+		if open > listedBlockers {
+			hidden[t.Id] = open - listedBlockers
+		}
+	}
+	return hidden, nil
+}
+
+// Splits tasks into layers of topological order: tasks of a layer are blocked only by tasks of previous layers
+// or by tasks which are not listed, except for numbers of hidden blockers by id of task, which never get into layers.
+// Tasks keep their order within layer. Tasks which make a cycle or have hidden blockers form the last layer
+func topologicalLayers(tasks []models.Task, hidden map[int]int) [][]models.Task {
+	listed := make(map[int]bool)
+	for _, t := range tasks {
+		listed[t.Id] = true
+	}
+	// Numbers of listed and hidden blockers which are not in layers yet and tasks blocked by every task
+	blockers := make(map[int]int)
+	blocked := make(map[int][]int)
+	for _, t := range tasks {
+		blockers[t.Id] = hidden[t.Id]
+		for _, blocker := range t.BlockedBy {
+			if listed[blocker] {
+				blockers[t.Id]++
+				blocked[blocker] = append(blocked[blocker], t.Id)
+			}
+		}
+	}
+
+	var layers [][]models.Task
+	for remaining := tasks; len(remaining) > 0; {
+		var layer, rest []models.Task
+		for _, t := range remaining {
+			if blockers[t.Id] == 0 {
+				layer = append(layer, t)
+			} else {
+				rest = append(rest, t)
+			}
+		}
+		if len(layer) == 0 {
+			return append(layers, rest)
+		}
+		for _, t := range layer {
+			for _, id := range blocked[t.Id] {
+				blockers[id]--
+			}
+		}
+		layers = append(layers, layer)
+		remaining = rest
+	}
+	return layers
+}
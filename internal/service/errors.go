@@ -1,7 +1,190 @@
 package service
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+)
+
+// Kinds of domain errors. Concrete errors below match them with errors.Is
+var (
+	ErrNotFound   = errors.New("not found")
+	ErrValidation = errors.New("validation failed")
+	ErrConflict   = errors.New("conflict")
+	// Task was modified since version expected by client
+	ErrPreconditionFailed = errors.New("precondition failed")
+	// Credentials of request are missing or wrong
+	ErrUnauthorized = errors.New("unauthorized")
+	// Caller is authenticated but is not allowed to do operation
+	ErrForbidden = errors.New("forbidden")
+)
 
 var (
-	errInvalidDeadline = errors.New("task deadline can not be earlier than today")
+	errInvalidDeadline = &ValidationError{Field: "deadline", Message: "task deadline can not be in the past"}
+	errRecurrenceStart = errors.New("DTSTART is not allowed, occurrences start at deadline")
+	// The same error for unknown user and wrong password, so existence of users is not disclosed
+	errInvalidCredentials = &AuthenticationError{Message: "invalid username or password"}
+	errInvalidAPIKey      = &AuthenticationError{Message: "invalid api key"}
+	errUserRequired       = &ForbiddenError{Message: "operation requires credentials of user, not api key"}
 )
+
+// NotFoundError means that requested resource does not exist
+type NotFoundError struct {
+	Resource string
+	Id       int
+}
+
+func (e *NotFoundError) Error() string {
+	return fmt.Sprintf("%s with id %d not found", e.Resource, e.Id)
+}
+
+func (e *NotFoundError) Is(target error) bool {
+	return target == ErrNotFound
+}
+
+// UserNotFoundError means that there is no user with username
+type UserNotFoundError struct {
+	Username string
+}
+
+func (e *UserNotFoundError) Error() string {
+	return fmt.Sprintf("user %q not found", e.Username)
+}
+
+func (e *UserNotFoundError) Is(target error) bool {
+	return target == ErrNotFound
+}
+
+// ValidationError means that field of request has invalid value
+type ValidationError struct {
+	Field   string
+	Message string
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Field, e.Message)
+}
+
+func (e *ValidationError) Is(target error) bool {
+	return target == ErrValidation
+}
+
+// ConflictError means that request can not be applied to current state of resource
+type ConflictError struct {
+	Message string
+}
+
+func (e *ConflictError) Error() string {
+	return e.Message
+}
+
+func (e *ConflictError) Is(target error) bool {
+	return target == ErrConflict
+}
+
+// VersionMismatchError means that task does not have version expected by client
+type VersionMismatchError struct {
+	Id       int
+	Expected int
+}
+
+func (e *VersionMismatchError) Error() string {
+	return fmt.Sprintf("task with id %d was modified and no longer has version %d", e.Id, e.Expected)
+}
+
+func (e *VersionMismatchError) Is(target error) bool {
+	return target == ErrPreconditionFailed
+}
+
+// AuthenticationError means that caller could not be authenticated
+type AuthenticationError struct {
+	Message string
+}
+
+func (e *AuthenticationError) Error() string {
+	return e.Message
+}
+
+func (e *AuthenticationError) Is(target error) bool {
+	return target == ErrUnauthorized
+}
+
+// ForbiddenError means that caller is not allowed to do operation
+type ForbiddenError struct {
+	Message string
+}
+
+func (e *ForbiddenError) Error() string {
+	return e.Message
+}
+
+func (e *ForbiddenError) Is(target error) bool {
+	return target == ErrForbidden
+}
+
+func taskNotFound(id int) error {
+	return &NotFoundError{Resource: "task", Id: id}
+}
+
+func trashedTaskNotFound(id int) error {
+	return &NotFoundError{Resource: "task in trash", Id: id}
+}
+
+func tagNotFound(id int) error {
+	return &NotFoundError{Resource: "tag", Id: id}
+}
+
+func tagOfOtherUsers(id int) error {
+	return &ConflictError{Message: fmt.Sprintf("tag with id %d is attached to tasks of other users", id)}
+}
+
+func notCreator(resource string, id int) error {
+	return &ForbiddenError{Message: fmt.Sprintf("%s with id %d was created by other user", resource, id)}
+}
+
+func tagExists(name string) error {
+	return &ConflictError{Message: fmt.Sprintf("tag %q already exists", name)}
+}
+
+func projectNotFound(id int) error {
+	return &NotFoundError{Resource: "project", Id: id}
+}
+
+func projectArchived(id int) error {
+	return &ConflictError{Message: fmt.Sprintf("project with id %d is archived", id)}
+}
+
+func taskArchived(id int) error {
+	return &ConflictError{Message: fmt.Sprintf("task with id %d is archived with its project", id)}
+}
+
+func checklistItemNotFound(id int) error {
+	return &NotFoundError{Resource: "checklist item", Id: id}
+}
+
+func openBlockers(id, count int) error {
+	return &ConflictError{Message: fmt.Sprintf("task with id %d is blocked by %d tasks which are not done", id, count)}
+}
+
+func dependencyCycle(id, blockerId int) error {
+	return &ConflictError{Message: fmt.Sprintf("task with id %d can not be blocked by task with id %d, which depends on it", id, blockerId)}
+}
+
+func parentCycle() error {
+	return &ValidationError{Field: "parent_id", Message: "task can not be a subtask of itself or of its subtasks"}
+}
+
+func openSubtasks(id, count int) error {
+	return &ConflictError{Message: fmt.Sprintf("task with id %d has %d subtasks which are not done", id, count)}
+}
+
+func userNotFound(username string) error {
+	return &UserNotFoundError{Username: username}
+}
+
+func userExists(username string) error {
+	return &ConflictError{Message: fmt.Sprintf("user %q already exists", username)}
+}
+
+func apiKeyNotFound(id int) error {
+	return &NotFoundError{Resource: "api key", Id: id}
+}
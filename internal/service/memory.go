@@ -0,0 +1,1066 @@
+package service
+
+import (
+	"context"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/O-Tempora/SberIT/internal/models"
+)
+
+// MemoryRepository keeps tasks in memory. Safe for concurrent use
+type MemoryRepository struct {
+	mu          sync.RWMutex
+	tasks       map[int]models.Task
+	lastId      int
+	events      []models.TaskEvent
+	lastEventId int
+	tags        map[int]models.Tag
+	lastTagId   int
+	// Ids of tags attached to task by id of task
+	taskTags      map[int]map[int]bool
+	projects      map[int]models.Project
+	lastProjectId int
+	// Checklist items ordered by id by id of task
+	checklists      map[int][]models.ChecklistItem
+	lastChecklistId int
+	// Ids of blockers by id of blocked task
+	dependencies map[int]map[int]bool
+	users        map[int]models.User
+	lastUserId   int
+	apiKeys      map[int]models.APIKey
+	lastKeyId    int
+	// Shares by id of user by id of task
+	shares map[int]map[int]models.TaskShare
+}
+
+func NewMemoryRepository() *MemoryRepository {
+	return &MemoryRepository{
+		tasks:        make(map[int]models.Task),
+		tags:         make(map[int]models.Tag),
+		taskTags:     make(map[int]map[int]bool),
+		projects:     make(map[int]models.Project),
+		checklists:   make(map[int][]models.ChecklistItem),
+		dependencies: make(map[int]map[int]bool),
+		users:        make(map[int]models.User),
+		apiKeys:      make(map[int]models.APIKey),
+		shares:       make(map[int]map[int]models.TaskShare),
+	}
+}
+
+func (r *MemoryRepository) Create(ctx context.Context, task models.Task) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.insert(ctx, task, ownerId(ctx), nil)
+}
+
+// Stores new task owned by owner with tags and records its creation. Caller must hold the lock
+func (r *MemoryRepository) insert(ctx context.Context, task models.Task, owner *int, tags []models.Tag) (int, error) {
+	task.Id = r.lastId + 1
+	task.Version = 1
+	task.Deadline = normalizeTime(task.Deadline)
+	task.DeletedAt = nil
+	task.ArchivedAt = nil
+	task.OwnerId = owner
+	if len(tags) > 0 {
+		r.taskTags[task.Id] = make(map[int]bool)
+		for _, tag := range tags {
+			r.taskTags[task.Id][tag.Id] = true
+		}
+	}
+	task = r.withDetails(task)
+	if err := r.record(ctx, ActionCreate, nil, &task); err != nil {
+		delete(r.taskTags, task.Id)
+		return -1, err
+	}
+	r.lastId = task.Id
+	r.store(task)
+	return task.Id, nil
+}
+
+func (r *MemoryRepository) Get(ctx context.Context, id int) (*models.Task, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	task, ok := r.tasks[id]
+	if !ok || task.DeletedAt != nil || !r.available(ctx, task) {
+		return nil, taskNotFound(id)
+	}
+	task = r.withRoles(ctx, []models.Task{r.withDetails(task)})[0]
+	return &task, nil
+}
+
+func (r *MemoryRepository) List(ctx context.Context, filter ListFilter) ([]models.Task, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	tasks := r.withRoles(ctx, r.filter(func(t models.Task) bool {
+		return r.available(ctx, t) && r.matches(filter.TaskFilter, t)
+	}))
+	if filter.Limit > 0 {
+		return keysetPage(tasks, filter.Cursor, filter.Limit), nil
+	}
+	sortTasks(tasks, filter.Sort)
+	if filter.Take <= 0 {
+		return tasks, nil
+	}
+
+	offset := filter.Take * (filter.Page - 1)
+	if offset < 0 {
+		offset = 0
+	}
+	if offset >= len(tasks) {
+		return nil, nil
+	}
+	end := offset + filter.Take
+	if end > len(tasks) {
+		end = len(tasks)
+	}
+	return tasks[offset:end], nil
+}
+
+func (r *MemoryRepository) Count(ctx context.Context, filter ListFilter) (int, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	count := 0
+	counts := r.subtaskCounts()
+	for _, t := range r.tasks {
+		if t.DeletedAt == nil && r.available(ctx, t) && r.matches(filter.TaskFilter, r.detailed(t, counts)) {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (r *MemoryRepository) Update(ctx context.Context, id, version int, task models.Task) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	current, err := r.checkParent(ctx, id, version, task.ParentId)
+	if err != nil {
+		return err
+	}
+	task.Id = id
+	task.Deadline = normalizeTime(task.Deadline)
+	task.DeletedAt = nil
+	task.ArchivedAt = nil
+	task.OwnerId = current.OwnerId
+	task.Version = current.Version + 1
+	return r.save(ctx, ActionUpdate, current, task)
+}
+
+func (r *MemoryRepository) Patch(ctx context.Context, id, version int, patch models.TaskPatch) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	current, err := r.checkParent(ctx, id, version, patch.ParentId)
+	if err != nil {
+		return err
+	}
+	if patch == (models.TaskPatch{}) {
+		return nil
+	}
+	return r.patch(ctx, current, patch)
+}
+
+func (r *MemoryRepository) Complete(ctx context.Context, id, version int, patch models.TaskPatch, next func(task models.Task) (*models.Task, error)) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	current, err := r.checkParent(ctx, id, version, patch.ParentId)
+	if err != nil {
+		return err
+	}
+	occurrence, err := completion(current, &patch, next)
+	if err != nil {
+		return err
+	}
+	if err = r.patch(ctx, current, patch); err != nil || occurrence == nil {
+		return err
+	}
+	occurrenceId, err := r.insert(ctx, *occurrence, current.OwnerId, occurrence.Tags)
+	if err != nil {
+		return err
+	}
+	for userId, share := range r.shares[id] {
+		if r.shares[occurrenceId] == nil {
+			r.shares[occurrenceId] = make(map[int]models.TaskShare)
+		}
+		share.TaskId = occurrenceId
+		r.shares[occurrenceId][userId] = share
+	}
+	return nil
+}
+
+// Changes fields of current task set in patch and increments its version. Caller must hold the lock
+func (r *MemoryRepository) patch(ctx context.Context, current models.Task, patch models.TaskPatch) error {
+	task := current
+	if patch.Header != nil {
+		task.Header = *patch.Header
+	}
+	if patch.Description != nil {
+		task.Description = *patch.Description
+	}
+	if patch.Deadline != nil {
+		task.Deadline = normalizeTime(*patch.Deadline)
+	}
+	if patch.Done != nil {
+		task.Done = *patch.Done
+	}
+	if patch.Priority != nil {
+		task.Priority = *patch.Priority
+	}
+	if patch.Effort != nil {
+		task.Effort = optionalId(patch.Effort)
+	}
+	if patch.ProjectId != nil {
+		task.ProjectId = optionalId(patch.ProjectId)
+	}
+	if patch.ParentId != nil {
+		task.ParentId = optionalId(patch.ParentId)
+	}
+	if patch.Recurrence != nil {
+		task.Recurrence = *patch.Recurrence
+	}
+	task.Version++
+	return r.save(ctx, ActionUpdate, current, task)
+}
+
+func (r *MemoryRepository) Delete(ctx context.Context, id, version int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	current, err := r.checkVersion(ctx, id, version)
+	if err != nil {
+		return err
+	}
+	task := current
+	now := normalizeTime(time.Now())
+	task.DeletedAt = &now
+	task.Version++
+	return r.save(ctx, ActionDelete, current, task)
+}
+
+func (r *MemoryRepository) Trash(ctx context.Context) ([]models.Task, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var tasks []models.Task
+	for _, t := range r.tasks {
+		if t.DeletedAt != nil && r.available(ctx, t) {
+			tasks = append(tasks, r.withDetails(t))
+		}
+	}
+	sort.Slice(tasks, func(i, j int) bool {
+		if !tasks[i].DeletedAt.Equal(*tasks[j].DeletedAt) {
+			return tasks[i].DeletedAt.After(*tasks[j].DeletedAt)
+		}
+		return tasks[i].Id < tasks[j].Id
+	})
+	return r.withRoles(ctx, tasks), nil
+}
+
+func (r *MemoryRepository) Restore(ctx context.Context, id int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	current, ok := r.tasks[id]
+	if !ok || current.DeletedAt == nil || !r.available(ctx, current) {
+		return trashedTaskNotFound(id)
+	}
+	current = r.withDetails(current)
+	task := current
+	task.DeletedAt = nil
+	// Task of archived project is restored archived
+	task.ArchivedAt = nil
+	if task.ProjectId != nil {
+		task.ArchivedAt = r.projects[*task.ProjectId].ArchivedAt
+	}
+	task.Version++
+	return r.save(ctx, ActionRestore, current, task)
+}
+
+func (r *MemoryRepository) Purge(ctx context.Context, before time.Time) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	purged := make(map[int]bool)
+	for id, t := range r.tasks {
+		if t.DeletedAt != nil && t.DeletedAt.Before(before) && owns(ctx, t) {
+			t = r.withDetails(t)
+			if err := r.record(ctx, ActionPurge, &t, nil); err != nil {
+				return len(purged), err
+			}
+			delete(r.tasks, id)
+			delete(r.taskTags, id)
+			delete(r.checklists, id)
+			delete(r.dependencies, id)
+			delete(r.shares, id)
+			purged[id] = true
+		}
+	}
+	for _, blockers := range r.dependencies {
+		for id := range purged {
+			delete(blockers, id)
+		}
+	}
+	// Subtasks of purged tasks become top level tasks
+	for id, t := range r.tasks {
+		if t.ParentId != nil && purged[*t.ParentId] {
+			t.ParentId = nil
+			r.tasks[id] = t
+		}
+	}
+	return len(purged), nil
+}
+
+func (r *MemoryRepository) ByDate(ctx context.Context, date time.Time, done *bool) ([]models.Task, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	next := date.AddDate(0, 0, 1)
+	return r.withRoles(ctx, r.filter(func(t models.Task) bool {
+		return r.available(ctx, t) && t.ArchivedAt == nil && !t.Deadline.Before(date) && t.Deadline.Before(next) && (done == nil || t.Done == *done)
+	})), nil
+}
+
+func (r *MemoryRepository) History(ctx context.Context, id int) ([]models.TaskEvent, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var events []models.TaskEvent
+	for _, e := range r.events {
+		if e.TaskId == id {
+			events = append(events, e)
+		}
+	}
+	return events, nil
+}
+
+func (r *MemoryRepository) Descendants(ctx context.Context, id int) ([]models.Task, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	subtree := map[int]bool{id: true}
+	for added := true; added; {
+		added = false
+		for _, t := range r.tasks {
+			if t.DeletedAt == nil && t.ParentId != nil && subtree[*t.ParentId] && !subtree[t.Id] {
+				subtree[t.Id] = true
+				added = true
+			}
+		}
+	}
+	return r.withRoles(ctx, r.filter(func(t models.Task) bool {
+		return t.Id != id && subtree[t.Id] && r.available(ctx, t)
+	})), nil
+}
+
+func (r *MemoryRepository) Ancestors(ctx context.Context, id int) ([]int, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.ancestors(id), nil
+}
+
+// Caller must hold the lock
+func (r *MemoryRepository) ancestors(id int) []int {
+	var ids []int
+	for task, ok := r.tasks[id]; ok && !slices.Contains(ids, task.Id); {
+		ids = append(ids, task.Id)
+		if task.ParentId == nil {
+			break
+		}
+		task, ok = r.tasks[*task.ParentId]
+	}
+	return ids
+}
+
+func (r *MemoryRepository) Blockers(ctx context.Context, id int) ([]int, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.blockers(id), nil
+}
+
+// Caller must hold the lock
+func (r *MemoryRepository) blockers(id int) []int {
+	var ids []int
+	found := map[int]bool{}
+	for queue := []int{id}; len(queue) > 0; queue = queue[1:] {
+		for blocker := range r.dependencies[queue[0]] {
+			if !found[blocker] {
+				found[blocker] = true
+				ids = append(ids, blocker)
+				queue = append(queue, blocker)
+			}
+		}
+	}
+	return ids
+}
+
+func (r *MemoryRepository) AddBlocker(ctx context.Context, id, version, blockerId int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	current, err := r.checkVersion(ctx, id, version)
+	if err != nil || r.dependencies[id][blockerId] {
+		return err
+	}
+	if blocker, ok := r.tasks[blockerId]; !ok || blocker.DeletedAt != nil || !r.available(ctx, blocker) {
+		return taskNotFound(blockerId)
+	}
+	if blockerId == id || slices.Contains(r.blockers(blockerId), id) {
+		return dependencyCycle(id, blockerId)
+	}
+	if r.dependencies[id] == nil {
+		r.dependencies[id] = make(map[int]bool)
+	}
+	r.dependencies[id][blockerId] = true
+	task := current
+	task.Version++
+	return r.save(ctx, ActionUpdate, current, task)
+}
+
+func (r *MemoryRepository) RemoveBlocker(ctx context.Context, id, version, blockerId int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	current, err := r.checkVersion(ctx, id, version)
+	if err != nil || !r.dependencies[id][blockerId] {
+		return err
+	}
+	delete(r.dependencies[id], blockerId)
+	task := current
+	task.Version++
+	return r.save(ctx, ActionUpdate, current, task)
+}
+
+func (r *MemoryRepository) AddChecklistItem(ctx context.Context, id, version int, item models.ChecklistItem) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	current, err := r.checkVersion(ctx, id, version)
+	if err != nil {
+		return -1, err
+	}
+	r.lastChecklistId++
+	item.Id = r.lastChecklistId
+	item.TaskId = id
+	r.checklists[id] = append(r.checklists[id], item)
+	task := current
+	task.Version++
+	if err := r.save(ctx, ActionUpdate, current, task); err != nil {
+		return -1, err
+	}
+	return item.Id, nil
+}
+
+func (r *MemoryRepository) UpdateChecklistItem(ctx context.Context, id, version int, item models.ChecklistItem) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	current, i, err := r.checkChecklistItem(ctx, id, version, item.Id)
+	if err != nil {
+		return err
+	}
+	existing := &r.checklists[id][i]
+	if existing.Text == item.Text && existing.Done == item.Done {
+		return nil
+	}
+	existing.Text, existing.Done = item.Text, item.Done
+	task := current
+	task.Version++
+	return r.save(ctx, ActionUpdate, current, task)
+}
+
+func (r *MemoryRepository) DeleteChecklistItem(ctx context.Context, id, version, itemId int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	current, i, err := r.checkChecklistItem(ctx, id, version, itemId)
+	if err != nil {
+		return err
+	}
+	r.checklists[id] = slices.Delete(r.checklists[id], i, i+1)
+	task := current
+	task.Version++
+	return r.save(ctx, ActionUpdate, current, task)
+}
+
+func (r *MemoryRepository) AttachTag(ctx context.Context, id, version, tagId int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	current, err := r.checkTag(ctx, id, version, tagId)
+	if err != nil || r.taskTags[id][tagId] {
+		return err
+	}
+	if r.taskTags[id] == nil {
+		r.taskTags[id] = make(map[int]bool)
+	}
+	r.taskTags[id][tagId] = true
+	task := current
+	task.Version++
+	return r.save(ctx, ActionUpdate, current, task)
+}
+
+func (r *MemoryRepository) DetachTag(ctx context.Context, id, version, tagId int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	current, err := r.checkTag(ctx, id, version, tagId)
+	if err != nil || !r.taskTags[id][tagId] {
+		return err
+	}
+	delete(r.taskTags[id], tagId)
+	task := current
+	task.Version++
+	return r.save(ctx, ActionUpdate, current, task)
+}
+
+func (r *MemoryRepository) Tags(ctx context.Context) ([]models.Tag, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	tags := []models.Tag{}
+	for _, tag := range r.tags {
+		tags = append(tags, tag)
+	}
+	sortTags(tags)
+	return tags, nil
+}
+
+func (r *MemoryRepository) CreateTag(ctx context.Context, tag models.Tag) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err := r.checkTagName(0, tag.Name); err != nil {
+		return -1, err
+	}
+	r.lastTagId++
+	tag.Id = r.lastTagId
+	tag.OwnerId = ownerId(ctx)
+	r.tags[tag.Id] = tag
+	return tag.Id, nil
+}
+
+func (r *MemoryRepository) UpdateTag(ctx context.Context, id int, tag models.Tag) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	current, ok := r.tags[id]
+	if !ok {
+		return tagNotFound(id)
+	}
+	if err := r.checkTagName(id, tag.Name); err != nil {
+		return err
+	}
+	tag.Id = id
+	tag.OwnerId = current.OwnerId
+	r.tags[id] = tag
+	return nil
+}
+
+func (r *MemoryRepository) DeleteTag(ctx context.Context, id int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.tags[id]; !ok {
+		return tagNotFound(id)
+	}
+	for taskId, tags := range r.taskTags {
+		if tags[id] && !owns(ctx, r.tasks[taskId]) {
+			return tagOfOtherUsers(id)
+		}
+	}
+	delete(r.tags, id)
+	for _, tags := range r.taskTags {
+		delete(tags, id)
+	}
+	return nil
+}
+
+func (r *MemoryRepository) Projects(ctx context.Context, archived bool) ([]models.Project, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	projects := []models.Project{}
+	for _, p := range r.projects {
+		if (p.ArchivedAt != nil) == archived {
+			projects = append(projects, r.withCounts(ctx, p))
+		}
+	}
+	sort.Slice(projects, func(i, j int) bool {
+		if projects[i].Name != projects[j].Name {
+			return projects[i].Name < projects[j].Name
+		}
+		return projects[i].Id < projects[j].Id
+	})
+	return projects, nil
+}
+
+func (r *MemoryRepository) Project(ctx context.Context, id int) (*models.Project, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	project, ok := r.projects[id]
+	if !ok {
+		return nil, projectNotFound(id)
+	}
+	project = r.withCounts(ctx, project)
+	return &project, nil
+}
+
+func (r *MemoryRepository) CreateProject(ctx context.Context, project models.Project) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.lastProjectId++
+	r.projects[r.lastProjectId] = models.Project{Id: r.lastProjectId, Name: project.Name, OwnerId: ownerId(ctx)}
+	return r.lastProjectId, nil
+}
+
+func (r *MemoryRepository) UpdateProject(ctx context.Context, id int, project models.Project) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	current, ok := r.projects[id]
+	if !ok {
+		return projectNotFound(id)
+	}
+	current.Name = project.Name
+	r.projects[id] = current
+	return nil
+}
+
+func (r *MemoryRepository) ArchiveProject(ctx context.Context, id int) error {
+	return r.setProjectArchived(ctx, id, true)
+}
+
+func (r *MemoryRepository) UnarchiveProject(ctx context.Context, id int) error {
+	return r.setProjectArchived(ctx, id, false)
+}
+
+// Archives or unarchives project and its tasks out of trash, recording change of every task
+func (r *MemoryRepository) setProjectArchived(ctx context.Context, id int, archive bool) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	project, ok := r.projects[id]
+	if !ok {
+		return projectNotFound(id)
+	}
+	if (project.ArchivedAt != nil) == archive {
+		return nil
+	}
+	action := ActionUnarchive
+	project.ArchivedAt = nil
+	if archive {
+		action = ActionArchive
+		now := normalizeTime(time.Now())
+		project.ArchivedAt = &now
+	}
+	r.projects[id] = project
+
+	for _, current := range r.filter(func(t models.Task) bool { return t.ProjectId != nil && *t.ProjectId == id && r.available(ctx, t) }) {
+		task := current
+		task.ArchivedAt = project.ArchivedAt
+		task.Version++
+		if err := r.save(ctx, action, current, task); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *MemoryRepository) CreateUser(ctx context.Context, user models.User) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, u := range r.users {
+		if u.Username == user.Username {
+			return -1, userExists(user.Username)
+		}
+	}
+	r.lastUserId++
+	user.Id = r.lastUserId
+	user.CreatedAt = normalizeTime(user.CreatedAt)
+	r.users[user.Id] = user
+	return user.Id, nil
+}
+
+func (r *MemoryRepository) UserByName(ctx context.Context, username string) (*models.User, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, u := range r.users {
+		if u.Username == username {
+			return &u, nil
+		}
+	}
+	return nil, userNotFound(username)
+}
+
+func (r *MemoryRepository) CreateAPIKey(ctx context.Context, key models.APIKey) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.lastKeyId++
+	key.Id = r.lastKeyId
+	key.Key = ""
+	key.Username = ""
+	key.Scopes = slices.Clone(key.Scopes)
+	key.CreatedAt = normalizeTime(key.CreatedAt)
+	key.LastUsedAt = nil
+	key.RevokedAt = nil
+	r.apiKeys[key.Id] = key
+	return key.Id, nil
+}
+
+func (r *MemoryRepository) APIKeys(ctx context.Context) ([]models.APIKey, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	owner := ownerId(ctx)
+	keys := []models.APIKey{}
+	for _, key := range r.apiKeys {
+		if owner == nil || key.UserId == *owner {
+			key.Scopes = slices.Clone(key.Scopes)
+			keys = append(keys, key)
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].Id < keys[j].Id
+	})
+	return keys, nil
+}
+
+func (r *MemoryRepository) RevokeAPIKey(ctx context.Context, id int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key, ok := r.apiKeys[id]
+	if owner := ownerId(ctx); !ok || (owner != nil && key.UserId != *owner) {
+		return apiKeyNotFound(id)
+	}
+	if key.RevokedAt == nil {
+		now := normalizeTime(time.Now())
+		key.RevokedAt = &now
+		r.apiKeys[id] = key
+	}
+	return nil
+}
+
+func (r *MemoryRepository) APIKeyByHash(ctx context.Context, hash string) (*models.APIKey, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, key := range r.apiKeys {
+		if key.KeyHash == hash && key.RevokedAt == nil {
+			key.Scopes = slices.Clone(key.Scopes)
+			key.Username = r.users[key.UserId].Username
+			return &key, nil
+		}
+	}
+	return nil, errInvalidAPIKey
+}
+
+func (r *MemoryRepository) TouchAPIKey(ctx context.Context, id int, at time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if key, ok := r.apiKeys[id]; ok {
+		at = normalizeTime(at)
+		key.LastUsedAt = &at
+		r.apiKeys[id] = key
+	}
+	return nil
+}
+
+func (r *MemoryRepository) Role(ctx context.Context, id int) (string, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	task, ok := r.tasks[id]
+	if !ok {
+		return "", taskNotFound(id)
+	}
+	return r.role(ctx, task), nil
+}
+
+func (r *MemoryRepository) Shares(ctx context.Context, id int) ([]models.TaskShare, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	shares := []models.TaskShare{}
+	for userId, share := range r.shares[id] {
+		share.Username = r.users[userId].Username
+		shares = append(shares, share)
+	}
+	sort.Slice(shares, func(i, j int) bool {
+		return shares[i].Username < shares[j].Username
+	})
+	return shares, nil
+}
+
+func (r *MemoryRepository) ShareTask(ctx context.Context, share models.TaskShare) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.shares[share.TaskId] == nil {
+		r.shares[share.TaskId] = make(map[int]models.TaskShare)
+	}
+	if existing, ok := r.shares[share.TaskId][share.UserId]; ok {
+		share.CreatedAt = existing.CreatedAt
+	}
+	share.Username = ""
+	share.CreatedAt = normalizeTime(share.CreatedAt)
+	r.shares[share.TaskId][share.UserId] = share
+	return nil
+}
+
+func (r *MemoryRepository) UnshareTask(ctx context.Context, id, userId int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.shares[id][userId]; !ok {
+		return &NotFoundError{Resource: "share of task", Id: id}
+	}
+	delete(r.shares[id], userId)
+	return nil
+}
+
+// Returns role of principal of ctx on task, owner without principal. Caller must hold the lock
+func (r *MemoryRepository) role(ctx context.Context, task models.Task) string {
+	id := ownerId(ctx)
+	if id == nil || (task.OwnerId != nil && *task.OwnerId == *id) {
+		return RoleOwner
+	}
+	return r.shares[task.Id][*id].Role
+}
+
+// Reports whether task is owned by principal of ctx or shared with it. Caller must hold the lock
+func (r *MemoryRepository) available(ctx context.Context, task models.Task) bool {
+	return r.role(ctx, task) != ""
+}
+
+// Sets role of principal of ctx on tasks, roles are not set without principal. Caller must hold the lock
+func (r *MemoryRepository) withRoles(ctx context.Context, tasks []models.Task) []models.Task {
+	if ownerId(ctx) == nil {
+		return tasks
+	}
+	for i := range tasks {
+		tasks[i].Role = r.role(ctx, tasks[i])
+	}
+	return tasks
+}
+
+// Returns project with numbers of its tasks out of trash available in ctx. Caller must hold the lock
+func (r *MemoryRepository) withCounts(ctx context.Context, project models.Project) models.Project {
+	for _, t := range r.tasks {
+		if t.DeletedAt != nil || t.ProjectId == nil || *t.ProjectId != project.Id || !r.available(ctx, t) {
+			continue
+		}
+		if t.Done {
+			project.DoneTasks++
+		} else {
+			project.OpenTasks++
+		}
+	}
+	return project
+}
+
+// Returns task with id like checkVersion if tag with tagId exists. Caller must hold the lock
+func (r *MemoryRepository) checkTag(ctx context.Context, id, version, tagId int) (models.Task, error) {
+	task, err := r.checkVersion(ctx, id, version)
+	if err != nil {
+		return task, err
+	}
+	if _, ok := r.tags[tagId]; !ok {
+		return task, tagNotFound(tagId)
+	}
+	return task, nil
+}
+
+// Returns task with id like checkVersion and index of its checklist item with itemId. Caller must hold the lock
+func (r *MemoryRepository) checkChecklistItem(ctx context.Context, id, version, itemId int) (models.Task, int, error) {
+	task, err := r.checkVersion(ctx, id, version)
+	if err != nil {
+		return task, -1, err
+	}
+	i := slices.IndexFunc(r.checklists[id], func(item models.ChecklistItem) bool { return item.Id == itemId })
+	if i < 0 {
+		return task, -1, checklistItemNotFound(itemId)
+	}
+	return task, i, nil
+}
+
+// Returns ConflictError if tag other than one with id has name. Caller must hold the lock
+func (r *MemoryRepository) checkTagName(id int, name string) error {
+	for _, tag := range r.tags {
+		if tag.Id != id && tag.Name == name {
+			return tagExists(name)
+		}
+	}
+	return nil
+}
+
+// Records change of task from current to task and stores it. Details of task are taken from the repository. Caller must hold the lock
+func (r *MemoryRepository) save(ctx context.Context, action string, current, task models.Task) error {
+	task = r.withDetails(task)
+	if err := r.record(ctx, action, &current, &task); err != nil {
+		return err
+	}
+	r.store(task)
+	return nil
+}
+
+// Tasks are stored without details, tags are attached in taskTags and checklist items are kept in checklists.
+// Caller must hold the lock
+func (r *MemoryRepository) store(task models.Task) {
+	task.Tags = nil
+	task.Checklist = nil
+	task.Progress = 0
+	task.Role = ""
+	r.tasks[task.Id] = task
+}
+
+// Returns task with its tags ordered by name, checklist, blockers and progress. Caller must hold the lock
+func (r *MemoryRepository) withDetails(task models.Task) models.Task {
+	return r.detailed(task, r.subtaskCounts())
+}
+
+// Returns task with details like withDetails, progress is computed from counts of subtaskCounts. Caller must hold the lock
+func (r *MemoryRepository) detailed(task models.Task, counts map[int][2]int) models.Task {
+	task.Tags = []models.Tag{}
+	for id := range r.taskTags[task.Id] {
+		task.Tags = append(task.Tags, r.tags[id])
+	}
+	sortTags(task.Tags)
+	task.Checklist = append([]models.ChecklistItem{}, r.checklists[task.Id]...)
+	task.BlockedBy = []int{}
+	for id := range r.dependencies[task.Id] {
+		task.BlockedBy = append(task.BlockedBy, id)
+	}
+	slices.Sort(task.BlockedBy)
+	task.Progress = taskProgress(task, counts[task.Id][0], counts[task.Id][1])
+	task.Children = nil
+	return task
+}
+
+// Returns numbers of all and done subtasks out of trash by id of their parent. Caller must hold the lock
+func (r *MemoryRepository) subtaskCounts() map[int][2]int {
+	counts := make(map[int][2]int)
+	for _, t := range r.tasks {
+		if t.DeletedAt != nil || t.ParentId == nil {
+			continue
+		}
+		count := counts[*t.ParentId]
+		count[0]++
+		if t.Done {
+			count[1]++
+		}
+		counts[*t.ParentId] = count
+	}
+	return counts
+}
+
+func sortTags(tags []models.Tag) {
+	sort.Slice(tags, func(i, j int) bool {
+		if tags[i].Name != tags[j].Name {
+			return tags[i].Name < tags[j].Name
+		}
+		return tags[i].Id < tags[j].Id
+	})
+}
+
+// Appends event of change to history. Caller must hold the lock
+func (r *MemoryRepository) record(ctx context.Context, action string, old, new *models.Task) error {
+	event, err := newTaskEvent(ctx, action, old, new)
+	if err != nil {
+		return err
+	}
+	r.lastEventId++
+	event.Id = r.lastEventId
+	r.events = append(r.events, event)
+	return nil
+}
+
+// Returns task with id if it exists out of trash, is available in ctx, is not archived and has expected version (any if 0).
+// Caller must hold the lock
+func (r *MemoryRepository) checkVersion(ctx context.Context, id, version int) (models.Task, error) {
+	task, ok := r.tasks[id]
+	if !ok || task.DeletedAt != nil || !r.available(ctx, task) {
+		return task, taskNotFound(id)
+	}
+	if task.ArchivedAt != nil {
+		return task, taskArchived(id)
+	}
+	if version != 0 && task.Version != version {
+		return task, &VersionMismatchError{Id: id, Expected: version}
+	}
+	return r.withDetails(task), nil
+}
+
+// Returns task with id like checkVersion if it can become subtask of parent (nil or 0 keeps it top level):
+// parent is neither the task itself nor its subtask. Caller must hold the lock
+func (r *MemoryRepository) checkParent(ctx context.Context, id, version int, parentId *int) (models.Task, error) {
+	task, err := r.checkVersion(ctx, id, version)
+	if err != nil || optionalId(parentId) == nil {
+		return task, err
+	}
+	if slices.Contains(r.ancestors(*parentId), id) {
+		return task, parentCycle()
+	}
+	return task, nil
+}
+
+// Reports whether task matches filter, tasks blocking other task are found by its dependencies. Caller must hold the lock
+func (r *MemoryRepository) matches(filter TaskFilter, t models.Task) bool {
+	if filter.Blocking != nil && !r.dependencies[*filter.Blocking][t.Id] {
+		return false
+	}
+	return matches(filter, t)
+}
+
+// Returns tasks out of trash matching fn ordered by id. Caller must hold the lock
+func (r *MemoryRepository) filter(fn func(t models.Task) bool) []models.Task {
+	var tasks []models.Task
+	counts := r.subtaskCounts()
+	for _, t := range r.tasks {
+		if t = r.detailed(t, counts); t.DeletedAt == nil && fn(t) {
+			tasks = append(tasks, t)
+		}
+	}
+	sort.Slice(tasks, func(i, j int) bool {
+		return tasks[i].Id < tasks[j].Id
+	})
+	return tasks
+}
+
+// Returns first limit tasks after cursor or last limit tasks before backward cursor. Tasks must be ordered by id
+func keysetPage(tasks []models.Task, cursor *Cursor, limit int) []models.Task {
+	if cursor == nil {
+		return tasks[:min(limit, len(tasks))]
+	}
+	if cursor.Backward {
+		end := sort.Search(len(tasks), func(i int) bool {
+			return tasks[i].Id >= cursor.Id
+		})
+		return tasks[max(end-limit, 0):end]
+	}
+	start := sort.Search(len(tasks), func(i int) bool {
+		return tasks[i].Id > cursor.Id
+	})
+	return tasks[start:min(start+limit, len(tasks))]
+}
+
+// Times are kept in UTC with precision of microseconds, the same way as SQLRepository returns them
+func normalizeTime(t time.Time) time.Time {
+	return t.Truncate(time.Microsecond).UTC()
+}
@@ -0,0 +1,102 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/O-Tempora/SberIT/internal/models"
+)
+
+// Roles of users on tasks
+const (
+	RoleOwner  = "owner"
+	RoleEditor = "editor"
+	RoleViewer = "viewer"
+)
+
+// Operations on tasks which are allowed or denied by policy
+const (
+	OperationRead   = "read"
+	OperationUpdate = "update"
+	OperationDelete = "delete"
+	OperationShare  = "share"
+)
+
+// Policy maps roles on task to operations they allow
+type Policy map[string][]string
+
+// DefaultPolicy lets owner do everything with task, editor read and change it and viewer only read it
+var DefaultPolicy = Policy{
+	RoleOwner:  {OperationRead, OperationUpdate, OperationDelete, OperationShare},
+	RoleEditor: {OperationRead, OperationUpdate},
+	RoleViewer: {OperationRead},
+}
+
+// Allows reports whether role allows operation. Empty role allows nothing
+func (p Policy) Allows(role, operation string) bool {
+	return slices.Contains(p[role], operation)
+}
+
+// Returns policy of service, DefaultPolicy if it is not set
+func (s *Service) policy() Policy {
+	if s.Policy == nil {
+		return DefaultPolicy
+	}
+	return s.Policy
+}
+
+// Authorize consults policy whether principal of ctx may do operation on task with id, which may be in trash.
+// Fails with NotFoundError if task does not exist and with ForbiddenError if principal has no role on task
+// or its role does not allow operation. Everything is allowed without principal
+func (s *Service) Authorize(ctx context.Context, id int, operation string) error {
+	if _, ok := PrincipalFrom(ctx); !ok {
+		return nil
+	}
+	role, err := s.Repo.Role(ctx, id)
+	if err != nil {
+		return err
+	}
+	if s.policy().Allows(role, operation) {
+		return nil
+	}
+	if role == "" {
+		return &ForbiddenError{Message: fmt.Sprintf("task with id %d is not shared with user", id)}
+	}
+	return &ForbiddenError{Message: fmt.Sprintf("role %s does not allow %s of task with id %d", role, operation, id)}
+}
+
+// GetShares returns users task with id is shared with, ordered by username
+func (s *Service) GetShares(ctx context.Context, id int) ([]models.TaskShare, error) {
+	return s.Repo.Shares(ctx, id)
+}
+
+// ShareTask grants user with username role on task with id, replacing role user had before.
+// Task can not be shared with its owner
+func (s *Service) ShareTask(ctx context.Context, id int, username, role string) error {
+	if role != RoleViewer && role != RoleEditor {
+		return &ValidationError{Field: "role", Message: "must be viewer or editor"}
+	}
+	task, err := s.Repo.Get(ctx, id)
+	if err != nil {
+		return err
+	}
+	user, err := s.Repo.UserByName(ctx, username)
+	if err != nil {
+		return err
+	}
+	if task.OwnerId != nil && *task.OwnerId == user.Id {
+		return &ValidationError{Field: "username", Message: "task can not be shared with its owner"}
+	}
+	return s.Repo.ShareTask(ctx, models.TaskShare{TaskId: id, UserId: user.Id, Role: role, CreatedAt: normalizeTime(time.Now())})
+}
+
+// UnshareTask revokes role of user with username on task with id
+func (s *Service) UnshareTask(ctx context.Context, id int, username string) error {
+	user, err := s.Repo.UserByName(ctx, username)
+	if err != nil {
+		return err
+	}
+	return s.Repo.UnshareTask(ctx, id, user.Id)
+}
@@ -0,0 +1,273 @@
+package service
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/O-Tempora/SberIT/internal/models"
+)
+
+// Fields tasks can be sorted by and their columns. Only these names get into text of queries
+var sortColumns = map[string]string{
+	"id":       "id",
+	"header":   "header",
+	"deadline": "deadline",
+	"done":     "done",
+	"priority": "priority",
+}
+
+// SortField is a field of task order, ascending unless Desc is set
+type SortField struct {
+	Field string
+	Desc  bool
+}
+
+// ParseSort parses comma separated list of fields, each optionally prefixed with "-" for descending
+// order (or "+" for ascending), e.g. "deadline,-id". Fields must be sortable
+func ParseSort(value string) ([]SortField, error) {
+	if value == "" {
+		return nil, nil
+	}
+	var fields []SortField
+	seen := make(map[string]bool)
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+		field := SortField{Field: strings.TrimLeft(item, "+-"), Desc: strings.HasPrefix(item, "-")}
+		if len(item)-len(field.Field) > 1 {
+			return nil, fmt.Errorf("%q has more than one direction prefix", item)
+		}
+		if _, ok := sortColumns[field.Field]; !ok {
+			return nil, fmt.Errorf("can not sort by %q, allowed fields: deadline, done, header, id, priority", field.Field)
+		}
+		if seen[field.Field] {
+			return nil, fmt.Errorf("%q is listed more than once", field.Field)
+		}
+		seen[field.Field] = true
+		fields = append(fields, field)
+	}
+	return fields, nil
+}
+
+// OrderedById reports whether sort is empty or ascending by id only, which is the order of keyset pagination
+func OrderedById(fields []SortField) bool {
+	return len(fields) == 0 || (len(fields) == 1 && fields[0] == SortField{Field: "id"})
+}
+
+// taskQuery builds select statement over tasks from TaskFilter. Columns come only from code and
+// sortColumns, all values are passed as parameters
+type taskQuery struct {
+	conditions []string
+	args       []interface{}
+	order      []string
+}
+
+// Search folds case of header and description with SQL function named casefold, which must fold like strings.ToLower
+func newTaskQuery(ctx context.Context, filter TaskFilter, casefold string) *taskQuery {
+	// Tasks in trash and tasks not owned by principal of ctx or shared with it are never listed
+	q := &taskQuery{}
+	q.where("deleted_at is null")
+	if id := ownerId(ctx); id != nil {
+		q.where(sharedCondition, *id, *id)
+	}
+	if !filter.IncludeArchived {
+		q.where("archived_at is null")
+	}
+	if filter.ProjectId != nil {
+		q.where("project_id = ?", *filter.ProjectId)
+	}
+	if filter.ParentId != nil {
+		q.where("parent_id = ?", *filter.ParentId)
+	}
+	if filter.Blocking != nil {
+		q.where("id in (select blocker_id from task_dependencies where task_id = ?)", *filter.Blocking)
+	}
+	if filter.BlockedBy != nil {
+		q.where("id in (select task_id from task_dependencies where blocker_id = ?)", *filter.BlockedBy)
+	}
+	if filter.Done != nil {
+		q.where("done = ?", *filter.Done)
+	}
+	if filter.Recurring != nil {
+		if *filter.Recurring {
+			q.where("recurrence <> ''")
+		} else {
+			q.where("recurrence = ''")
+		}
+	}
+	if filter.DeadlineFrom != nil {
+		q.where("deadline >= ?", formatTime(*filter.DeadlineFrom))
+	}
+	if filter.DeadlineBefore != nil {
+		q.where("deadline < ?", formatTime(*filter.DeadlineBefore))
+	}
+	if filter.Overdue != nil {
+		now := formatTime(time.Now())
+		if *filter.Overdue {
+			q.where("deadline < ? and done = ?", now, false)
+		} else {
+			q.where("(deadline >= ? or done = ?)", now, true)
+		}
+	}
+	if filter.Search != "" {
+		pattern := "%" + escapeLike(strings.ToLower(filter.Search)) + "%"
+		q.where(`(`+casefold+`(header) like ? escape '\' or `+casefold+`(description) like ? escape '\')`, pattern, pattern)
+	}
+	if names := uniqueTags(filter.Tags); len(names) > 0 {
+		args := make([]interface{}, len(names))
+		for i, name := range names {
+			args[i] = name
+		}
+		tagged := `id in (select task_tags.task_id from task_tags join tags on tags.id = task_tags.tag_id
+			where tags.name in (?` + strings.Repeat(", ?", len(names)-1) + `)`
+		// Task has all tags if it has as many of them as there are names
+		if filter.AllTags {
+			tagged += ` group by task_tags.task_id having count(*) = ?`
+			args = append(args, len(names))
+		}
+		q.where(tagged+`)`, args...)
+	}
+	for _, field := range filter.Sort {
+		column, ok := sortColumns[field.Field]
+		if !ok {
+			continue
+		}
+		if field.Desc {
+			column += " desc"
+		}
+		q.order = append(q.order, column)
+	}
+	return q
+}
+
+// Adds condition joined with others by "and"
+func (q *taskQuery) where(condition string, args ...interface{}) {
+	q.conditions = append(q.conditions, condition)
+	q.args = append(q.args, args...)
+}
+
+// Returns statement selecting columns and its arguments. Tasks are ordered by id after fields of sort,
+// so order is always total
+func (q *taskQuery) build(columns string, ordered bool) (string, []interface{}) {
+	query := "select " + columns + " from tasks"
+	if len(q.conditions) > 0 {
+		query += " where " + strings.Join(q.conditions, " and ")
+	}
+	if !ordered {
+		return query, q.args
+	}
+
+	order := q.order
+	if !slices.ContainsFunc(order, func(o string) bool { return strings.Fields(o)[0] == "id" }) {
+		order = append(order, "id")
+	}
+	return query + " order by " + strings.Join(order, ", "), q.args
+}
+
+// Escapes wildcards of like pattern, backslash is used as escape character
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
+}
+
+// Reports whether task matches conditions of filter. Used by MemoryRepository the same way as taskQuery is used by SQLRepository,
+// Blocking is checked by MemoryRepository itself
+func matches(filter TaskFilter, t models.Task) bool {
+	if !filter.IncludeArchived && t.ArchivedAt != nil {
+		return false
+	}
+	if filter.ProjectId != nil && (t.ProjectId == nil || *t.ProjectId != *filter.ProjectId) {
+		return false
+	}
+	if filter.ParentId != nil && (t.ParentId == nil || *t.ParentId != *filter.ParentId) {
+		return false
+	}
+	if filter.BlockedBy != nil && !slices.Contains(t.BlockedBy, *filter.BlockedBy) {
+		return false
+	}
+	if filter.Done != nil && t.Done != *filter.Done {
+		return false
+	}
+	if filter.Recurring != nil && *filter.Recurring != (t.Recurrence != "") {
+		return false
+	}
+	if filter.DeadlineFrom != nil && t.Deadline.Before(*filter.DeadlineFrom) {
+		return false
+	}
+	if filter.DeadlineBefore != nil && !t.Deadline.Before(*filter.DeadlineBefore) {
+		return false
+	}
+	if filter.Overdue != nil && *filter.Overdue != (!t.Done && t.Deadline.Before(time.Now())) {
+		return false
+	}
+	if filter.Search != "" {
+		search := strings.ToLower(filter.Search)
+		if !strings.Contains(strings.ToLower(t.Header), search) && !strings.Contains(strings.ToLower(t.Description), search) {
+			return false
+		}
+	}
+	if names := uniqueTags(filter.Tags); len(names) > 0 {
+		found := 0
+		for _, tag := range t.Tags {
+			if slices.Contains(names, tag.Name) {
+				found++
+			}
+		}
+		if found == 0 || (filter.AllTags && found < len(names)) {
+			return false
+		}
+	}
+	return true
+}
+
+// Returns names of tags without duplicates
+func uniqueTags(names []string) []string {
+	var unique []string
+	for _, name := range names {
+		if !slices.Contains(unique, name) {
+			unique = append(unique, name)
+		}
+	}
+	return unique
+}
+
+// Sorts tasks by fields, tasks must already be ordered by id which stays the last key
+func sortTasks(tasks []models.Task, fields []SortField) {
+	sort.SliceStable(tasks, func(i, j int) bool {
+		for _, field := range fields {
+			c := compareField(tasks[i], tasks[j], field.Field)
+			if field.Desc {
+				c = -c
+			}
+			if c != 0 {
+				return c < 0
+			}
+		}
+		return false
+	})
+}
+
+func compareField(a, b models.Task, field string) int {
+	switch field {
+	case "header":
+		return strings.Compare(a.Header, b.Header)
+	case "deadline":
+		return a.Deadline.Compare(b.Deadline)
+	case "done":
+		return cmp.Compare(boolToInt(a.Done), boolToInt(b.Done))
+	case "priority":
+		return cmp.Compare(a.Priority, b.Priority)
+	default:
+		return cmp.Compare(a.Id, b.Id)
+	}
+}
+
+func boolToInt(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,187 @@
+package service
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/O-Tempora/SberIT/internal/models"
+	"github.com/teambition/rrule-go"
+)
+
+// Returns rule in canonical form: without optional RRULE: prefix and surrounding spaces, in upper case
+func normalizeRecurrence(rule string) string {
+	rule = strings.ToUpper(strings.TrimSpace(rule))
+	return strings.TrimSpace(strings.TrimPrefix(rule, "RRULE:"))
+}
+
+// Parses recurrence rule of task. Occurrences start at deadline, which is taken in loc, so rules like BYDAY
+// and BYHOUR are applied in that timezone
+func parseRecurrence(rule string, deadline time.Time, loc *time.Location) (*rrule.RRule, error) {
+	option, err := rrule.StrToROptionInLocation(rule, loc)
+	if err != nil {
+		return nil, err
+	}
+	if !option.Dtstart.IsZero() {
+		return nil, errRecurrenceStart
+	}
+	option.Dtstart = deadline.In(loc)
+	return rrule.NewRRule(*option)
+}
+
+// Returns timezone occurrences of recurring tasks are computed in, UTC if it is not set
+func (s *Service) location() *time.Location {
+	if s.Location == nil {
+		return time.UTC
+	}
+	return s.Location
+}
+
+// Returns deadline of the next occurrence of recurring task: the first one after its deadline and current time,
+// and rule of task of that occurrence, which has COUNT reduced by passed occurrences. Zero time means that
+// rule has no more occurrences
+func (s *Service) nextOccurrence(task models.Task) (time.Time, string, error) {
+	rule, err := parseRecurrence(task.Recurrence, task.Deadline, s.location())
+	if err != nil {
+		return time.Time{}, "", err
+	}
+	after := time.Now()
+	if task.Deadline.After(after) {
+		after = task.Deadline
+	}
+	next := rule.Iterator()
+	passed := 0
+	for {
+		occurrence, ok := next()
+		if !ok {
+			return time.Time{}, "", nil
+		}
+		if occurrence.After(after) {
+			option := rule.OrigOptions
+			if option.Count > 0 {
+				option.Count -= passed
+			}
+			return occurrence.UTC(), option.RRuleString(), nil
+		}
+		passed++
+	}
+}
+
+// Returns the next occurrence of recurring task which is marked done: new open task with the same
+// header, description, priority, effort, project, parent and tags, deadline of the occurrence and the rest of the rule.
+// Nil if rule has no more occurrences
+func (s *Service) occurrence(task models.Task) (*models.Task, error) {
+	deadline, rule, err := s.nextOccurrence(task)
+	if err != nil || deadline.IsZero() {
+		return nil, err
+	}
+	return &models.Task{
+		Header:      task.Header,
+		Description: task.Description,
+		Deadline:    deadline,
+		Priority:    task.Priority,
+		Effort:      task.Effort,
+		ProjectId:   task.ProjectId,
+		ParentId:    task.ParentId,
+		Recurrence:  rule,
+		Tags:        task.Tags,
+	}, nil
+}
+
+// Returns the next occurrence by next of current task which patch marks done, if task is open and recurs after patch.
+// Then rule is moved from task to occurrence, so it is removed by patch. Used by repositories in Complete
+func completion(current models.Task, patch *models.TaskPatch, next func(task models.Task) (*models.Task, error)) (*models.Task, error) {
+	if current.Done {
+		return nil, nil
+	}
+	task := patched(current, *patch)
+	if task.Recurrence == "" {
+		return nil, nil
+	}
+	none := ""
+	patch.Recurrence = &none
+	return next(task)
+}
+
+// Returns task with fields of patch applied, which matter for its next occurrence
+func patched(task models.Task, patch models.TaskPatch) models.Task {
+	if patch.Header != nil {
+		task.Header = *patch.Header
+	}
+	if patch.Description != nil {
+		task.Description = *patch.Description
+	}
+	if patch.Deadline != nil {
+		task.Deadline = *patch.Deadline
+	}
+	if patch.Priority != nil {
+		task.Priority = *patch.Priority
+	}
+	if patch.Effort != nil {
+		task.Effort = optionalId(patch.Effort)
+	}
+	if patch.ProjectId != nil {
+		task.ProjectId = optionalId(patch.ProjectId)
+	}
+	if patch.ParentId != nil {
+		task.ParentId = optionalId(patch.ParentId)
+	}
+	if patch.Recurrence != nil {
+		task.Recurrence = *patch.Recurrence
+	}
+	return task
+}
+
+// Returns virtual occurrences of open recurring tasks with deadline from start until end, ordered by deadline.
+// Only occurrences after deadline of their task and current time are returned, as only they can be created
+// by marking tasks done. Occurrences have id 0 and id of their task in OccurrenceOf
+func (s *Service) virtualOccurrences(ctx context.Context, start, end time.Time) ([]models.Task, error) {
+	done, recurring := false, true
+	tasks, err := s.Repo.List(ctx, ListFilter{TaskFilter: TaskFilter{Done: &done, Recurring: &recurring, DeadlineBefore: &end}})
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	var occurrences []models.Task
+	for _, task := range tasks {
+		rule, err := parseRecurrence(task.Recurrence, task.Deadline, s.location())
+		if err != nil {
+			return nil, err
+		}
+		next := rule.Iterator()
+		for {
+			deadline, ok := next()
+			if !ok || !deadline.Before(end) {
+				break
+			}
+			if deadline.Before(start) || !deadline.After(task.Deadline) || !deadline.After(now) {
+				continue
+			}
+			occurrences = append(occurrences, virtualOccurrence(task, deadline))
+		}
+	}
+	sort.SliceStable(occurrences, func(i, j int) bool {
+		return occurrences[i].Deadline.Before(occurrences[j].Deadline)
+	})
+	return occurrences, nil
+}
+
+// Returns occurrence of recurring task with deadline, which is not stored
+func virtualOccurrence(task models.Task, deadline time.Time) models.Task {
+	id := task.Id
+	return models.Task{
+		Header:       task.Header,
+		Description:  task.Description,
+		Deadline:     deadline.UTC(),
+		Priority:     task.Priority,
+		Effort:       task.Effort,
+		Tags:         task.Tags,
+		ProjectId:    task.ProjectId,
+		ParentId:     task.ParentId,
+		Checklist:    []models.ChecklistItem{},
+		BlockedBy:    []int{},
+		Recurrence:   task.Recurrence,
+		OccurrenceOf: &id,
+	}
+}
@@ -0,0 +1,164 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/O-Tempora/SberIT/internal/models"
+)
+
+// TaskRepository is a storage of tasks used by Service.
+// Changes of tasks are recorded to their history together with actor and request id of ctx (see WithAudit).
+// If ctx has principal (see WithPrincipal), tasks are created owned by it and only tasks it owns or which are shared
+// with it are found, except by Ancestors and Blockers. Found tasks have role of principal on them.
+// Projects and tags are listed to all users and are created owned by principal, archive of project changes only tasks
+// available in ctx
+type TaskRepository interface {
+	Create(ctx context.Context, task models.Task) (int, error)
+	Get(ctx context.Context, id int) (*models.Task, error)
+	List(ctx context.Context, filter ListFilter) ([]models.Task, error)
+	// Count returns number of tasks matching filter, pagination fields of filter are ignored
+	Count(ctx context.Context, filter ListFilter) (int, error)
+	// Update, Patch and Delete fail with VersionMismatchError if version is not 0 and task has other version.
+	// They increment version of task. Update, Patch and Complete fail with ValidationError if task would become
+	// subtask of itself or of its subtasks
+	Update(ctx context.Context, id, version int, task models.Task) error
+	// Patch updates only fields set in patch
+	Patch(ctx context.Context, id, version int, patch models.TaskPatch) error
+	// Complete applies patch, which marks task done, as Patch. If task was open and recurs after patch, its rule
+	// is removed and its next occurrence returned by next is created in the same transaction, unless it is nil.
+	// Occurrence is owned by owner of task, has tags of occurrence and is shared with users task is shared with
+	Complete(ctx context.Context, id, version int, patch models.TaskPatch, next func(task models.Task) (*models.Task, error)) error
+	// Delete moves task to trash. Tasks in trash are not found by other methods except Trash, Restore and History.
+	// Update, Patch, Delete and changes of tags fail with ConflictError if task is archived
+	Delete(ctx context.Context, id, version int) error
+	// Trash returns tasks in trash, recently deleted first
+	Trash(ctx context.Context) ([]models.Task, error)
+	// Restore moves task back from trash and increments its version
+	Restore(ctx context.Context, id int) error
+	// Purge permanently deletes tasks moved to trash before time and returns their number
+	Purge(ctx context.Context, before time.Time) (int, error)
+	// ByDate returns tasks with deadline within day which starts at date (in its location), optionally filtered by status
+	ByDate(ctx context.Context, date time.Time, done *bool) ([]models.Task, error)
+	// History returns recorded changes of task in order they were made, also after task is purged
+	History(ctx context.Context, id int) ([]models.TaskEvent, error)
+	// Descendants returns subtasks of task at any depth ordered by id. Subtasks of tasks in trash are skipped
+	Descendants(ctx context.Context, id int) ([]models.Task, error)
+	// Ancestors returns ids of task and of all its parents up to the top level task, including tasks in trash
+	Ancestors(ctx context.Context, id int) ([]int, error)
+
+	// Blockers returns ids of tasks which block task with id directly or through other tasks, including tasks in trash
+	Blockers(ctx context.Context, id int) ([]int, error)
+
+	// AddBlocker and RemoveBlocker change blockers of task the same way as Update changes its fields.
+	// They do nothing if task is already blocked or not blocked by blocker, AddBlocker fails with NotFoundError
+	// if blocker does not exist and with ConflictError if blocker is task itself or depends on it
+	AddBlocker(ctx context.Context, id, version, blockerId int) error
+	RemoveBlocker(ctx context.Context, id, version, blockerId int) error
+
+	// AddChecklistItem, UpdateChecklistItem and DeleteChecklistItem change checklist of task the same way as Update
+	// changes its fields. Update and delete fail with NotFoundError if task has no such item, update of item to
+	// the same text and status does nothing
+	AddChecklistItem(ctx context.Context, id, version int, item models.ChecklistItem) (int, error)
+	UpdateChecklistItem(ctx context.Context, id, version int, item models.ChecklistItem) error
+	DeleteChecklistItem(ctx context.Context, id, version, itemId int) error
+
+	// AttachTag and DetachTag change tags of task the same way as Update changes its fields.
+	// They do nothing if tag is already attached or detached, and fail with NotFoundError if tag does not exist
+	AttachTag(ctx context.Context, id, version, tagId int) error
+	DetachTag(ctx context.Context, id, version, tagId int) error
+	// Tags returns all tags ordered by name
+	Tags(ctx context.Context) ([]models.Tag, error)
+	// CreateTag and UpdateTag fail with ConflictError if other tag has the same name
+	CreateTag(ctx context.Context, tag models.Tag) (int, error)
+	UpdateTag(ctx context.Context, id int, tag models.Tag) error
+	// DeleteTag deletes tag and detaches it from all tasks. It fails with ConflictError if tag is attached to tasks
+	// not owned by principal of ctx, including tasks shared with it
+	DeleteTag(ctx context.Context, id int) error
+
+	// Projects returns archived or not archived projects with counts of their tasks ordered by name, only tasks
+	// available in ctx are counted
+	Projects(ctx context.Context, archived bool) ([]models.Project, error)
+	Project(ctx context.Context, id int) (*models.Project, error)
+	CreateProject(ctx context.Context, project models.Project) (int, error)
+	// UpdateProject renames project
+	UpdateProject(ctx context.Context, id int, project models.Project) error
+	// ArchiveProject archives project with its tasks out of trash, UnarchiveProject brings them back.
+	// Changes of tasks are recorded, nothing is done if project already is in requested state
+	ArchiveProject(ctx context.Context, id int) error
+	UnarchiveProject(ctx context.Context, id int) error
+
+	// CreateUser fails with ConflictError if other user has the same username
+	CreateUser(ctx context.Context, user models.User) (int, error)
+	// UserByName returns user with username, NotFoundError if there is no such user
+	UserByName(ctx context.Context, username string) (*models.User, error)
+
+	// CreateAPIKey stores key of its user, only hash of key is stored
+	CreateAPIKey(ctx context.Context, key models.APIKey) (int, error)
+	// APIKeys returns keys of principal in ctx including revoked ones, ordered by id
+	APIKeys(ctx context.Context) ([]models.APIKey, error)
+	// RevokeAPIKey revokes key of principal in ctx, NotFoundError if principal has no key with id.
+	// Revoking revoked key does nothing
+	RevokeAPIKey(ctx context.Context, id int) error
+	// APIKeyByHash returns key which is not revoked with hash and username of its user,
+	// AuthenticationError if there is no such key
+	APIKeyByHash(ctx context.Context, hash string) (*models.APIKey, error)
+	// TouchAPIKey sets time key with id was last used at
+	TouchAPIKey(ctx context.Context, id int, at time.Time) error
+
+	// Role returns role of principal in ctx on task with id, which may be in trash, empty if task is not shared with
+	// principal and owner without principal. NotFoundError if there is no such task
+	Role(ctx context.Context, id int) (string, error)
+	// Shares returns users task with id is shared with, ordered by username
+	Shares(ctx context.Context, id int) ([]models.TaskShare, error)
+	// ShareTask grants user role on task or replaces role user has
+	ShareTask(ctx context.Context, share models.TaskShare) error
+	// UnshareTask revokes role of user on task with id, NotFoundError if task is not shared with user
+	UnshareTask(ctx context.Context, id, userId int) error
+}
+
+// TaskFilter holds conditions and order of task list, all set conditions must be met
+type TaskFilter struct {
+	Done *bool
+	// Task has recurrence rule (or has not, if false)
+	Recurring *bool
+	// Deadline is not earlier than DeadlineFrom and earlier than DeadlineBefore
+	DeadlineFrom   *time.Time
+	DeadlineBefore *time.Time
+	// Task is not done and its deadline has passed (or the opposite, if false)
+	Overdue *bool
+	// Case insensitive substring of header or description
+	Search string
+	// Names of tags, task has any of them (or all of them if AllTags is set)
+	Tags      []string
+	AllTags   bool
+	ProjectId *int
+	// Subtasks of task with ParentId
+	ParentId *int
+	// Tasks which block task with Blocking, tasks which are blocked by task with BlockedBy
+	Blocking  *int
+	BlockedBy *int
+	// Archived tasks are listed too, otherwise they are skipped like tasks in trash
+	IncludeArchived bool
+	// Tasks are ordered by id after these fields
+	Sort []SortField
+}
+
+// ListFilter selects tasks matching TaskFilter. Keyset pagination ignores Sort, its tasks are always ordered by id
+type ListFilter struct {
+	TaskFilter
+	// Offset pagination is applied only if Take is positive
+	Page int
+	Take int
+	// Keyset pagination is applied only if Limit is positive: first Limit tasks after Cursor
+	// (or last Limit tasks before it if Cursor is backward), from the beginning if Cursor is nil
+	Cursor *Cursor
+	Limit  int
+}
+
+// Cursor points to task from which keyset pagination continues
+type Cursor struct {
+	Id int
+	// Tasks before Id are selected instead of tasks after it
+	Backward bool
+}
@@ -1,105 +1,576 @@
 package service
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
 	"time"
 
+	"github.com/O-Tempora/SberIT/config"
 	"github.com/O-Tempora/SberIT/internal/models"
-	"github.com/jmoiron/sqlx"
 )
 
 type Service struct {
-	Db *sqlx.DB
+	Repo         TaskRepository
+	Validation   config.Validation
+	Subtasks     config.Subtasks
+	Dependencies config.Dependencies
+	Agenda       config.Agenda
+	Users        config.Users
+	// Timezone of occurrences of recurring tasks, UTC if nil
+	Location *time.Location
+	// Operations allowed by roles on tasks, DefaultPolicy if nil
+	Policy Policy
+
+	// Hash compared with password of unknown user, made on first use with the cost of real hashes
+	dummyHash     []byte
+	dummyHashOnce sync.Once
 }
 
-func (s *Service) Create(task models.Task) (int, error) {
-	if task.Deadline.Before(time.Now()) {
-		task.Deadline = time.Now().Add(24 * time.Hour)
+func (s *Service) Create(ctx context.Context, task models.Task) (int, error) {
+	task.Recurrence = normalizeRecurrence(task.Recurrence)
+	if err := s.validate(0, task); err != nil {
+		return -1, err
 	}
-
-	var id int
-	err := s.Db.Get(&id, `insert into tasks
-		(header, description, deadline, done)
-		values ($1, $2, $3, $4)
-		returning id`,
-		task.Header, task.Description, task.Deadline, task.Done)
-	if err != nil {
+	task.ProjectId = optionalId(task.ProjectId)
+	task.ParentId = optionalId(task.ParentId)
+	task.Effort = optionalId(task.Effort)
+	if err := errors.Join(s.checkProject(ctx, task.ProjectId), s.checkParent(ctx, task.ParentId)); err != nil {
 		return -1, err
 	}
-	return id, nil
+	return s.Repo.Create(ctx, task)
+}
+
+func (s *Service) GetList(ctx context.Context, filter TaskFilter) ([]models.Task, error) {
+	return s.Repo.List(ctx, ListFilter{
+		TaskFilter: filter,
+	})
 }
 
-func (s *Service) GetList(done *bool) ([]models.Task, error) {
-	var tasks []models.Task
-	var err error
+// OffsetPage is a page of offset pagination. Total is number of all tasks matching filter
+type OffsetPage struct {
+	Tasks   []models.Task
+	Page    int
+	Take    int
+	Total   int
+	HasMore bool
+}
 
-	if done == nil {
-		err = s.Db.Select(&tasks, `select * from tasks`)
-	} else {
-		err = s.Db.Select(&tasks, `select * from tasks where done = $1`, *done)
+func (s *Service) GetListWithPagination(ctx context.Context, page, take int, filter TaskFilter) (*OffsetPage, error) {
+	list := ListFilter{
+		TaskFilter: filter,
+		Page:       page,
+		Take:       take,
+	}
+	tasks, err := s.Repo.List(ctx, list)
+	if err != nil {
+		return nil, err
 	}
+	total, err := s.Repo.Count(ctx, list)
+	if err != nil {
+		return nil, err
+	}
+	return &OffsetPage{
+		Tasks:   tasks,
+		Page:    page,
+		Take:    take,
+		Total:   total,
+		HasMore: page*take < total,
+	}, nil
+}
+
+// CursorPage is a page of tasks ordered by id with cursors of adjacent pages, nil if there is no such page.
+// Total is number of all tasks matching filter, HasMore shows whether there are more tasks in direction of pagination
+type CursorPage struct {
+	Tasks   []models.Task
+	Next    *Cursor
+	Prev    *Cursor
+	Total   int
+	HasMore bool
+}
 
+// GetListWithCursor returns limit tasks after cursor (or before it, if cursor is backward), from the beginning if cursor is nil.
+// Tasks are ordered by id, other order of filter is ignored
+func (s *Service) GetListWithCursor(ctx context.Context, cursor *Cursor, limit int, filter TaskFilter) (*CursorPage, error) {
+	// One extra task shows whether there are more tasks in direction of pagination
+	list := ListFilter{
+		TaskFilter: filter,
+		Cursor:     cursor,
+		Limit:      limit + 1,
+	}
+	tasks, err := s.Repo.List(ctx, list)
+	if err != nil {
+		return nil, err
+	}
+	total, err := s.Repo.Count(ctx, list)
 	if err != nil {
 		return nil, err
 	}
 
-	return tasks, nil
+	more := len(tasks) > limit
+	page := &CursorPage{Total: total, HasMore: more}
+	backward := cursor != nil && cursor.Backward
+	switch {
+	case more && backward:
+		tasks = tasks[1:]
+	case more:
+		tasks = tasks[:limit]
+	}
+	page.Tasks = tasks
+	if len(tasks) == 0 {
+		return page, nil
+	}
+
+	first, last := tasks[0].Id, tasks[len(tasks)-1].Id
+	if more || backward {
+		page.Next = &Cursor{Id: last}
+	}
+	if (more && backward) || (cursor != nil && !backward) {
+		page.Prev = &Cursor{Id: first, Backward: true}
+	}
+	return page, nil
 }
 
-func (s *Service) GetListWithPagination(page, take int, done *bool) ([]models.Task, error) {
-	var tasks []models.Task
-	var err error
+func (s *Service) Get(ctx context.Context, id int) (*models.Task, error) {
+	return s.Repo.Get(ctx, id)
+}
 
-	if done == nil {
-		err = s.Db.Select(&tasks, `select * from tasks limit $1 offset $2`, take, take*(page-1))
-	} else {
-		err = s.Db.Select(&tasks, `select * from tasks where done = $1 limit $2 offset $3`, *done, take, take*(page-1))
+// GetWithChildren returns task with tree of its subtasks out of trash in Children
+func (s *Service) GetWithChildren(ctx context.Context, id int) (*models.Task, error) {
+	task, err := s.Repo.Get(ctx, id)
+	if err != nil {
+		return nil, err
 	}
+	descendants, err := s.Repo.Descendants(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	tree := withChildren(*task, descendants)
+	return &tree, nil
+}
+
+// Delete moves task to trash. As Update and Patch, it checks that task has expected version unless version is 0
+func (s *Service) Delete(ctx context.Context, id, version int) error {
+	return s.Repo.Delete(ctx, id, version)
+}
+
+// GetTrash returns tasks in trash, recently deleted first
+func (s *Service) GetTrash(ctx context.Context) ([]models.Task, error) {
+	return s.Repo.Trash(ctx)
+}
+
+// Restore moves task from trash back to list of tasks
+func (s *Service) Restore(ctx context.Context, id int) error {
+	return s.Repo.Restore(ctx, id)
+}
+
+// PurgeTrash permanently deletes tasks which are in trash longer than retention and returns their number
+func (s *Service) PurgeTrash(ctx context.Context, retention time.Duration) (int, error) {
+	return s.Repo.Purge(ctx, time.Now().Add(-retention))
+}
 
+// GetHistory returns changes of task with field-level diffs, oldest first. Task may be in trash or purged,
+// then its history is available only to its owner
+func (s *Service) GetHistory(ctx context.Context, id int) ([]models.TaskEvent, error) {
+	events, err := s.Repo.History(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if len(events) == 0 {
+		return nil, taskNotFound(id)
+	}
+	ok, err := ownsHistory(ctx, events)
 	if err != nil {
 		return nil, err
 	}
+	if !ok {
+		// History of task shared with principal is available while task exists
+		if role, err := s.Repo.Role(ctx, id); err != nil || role == "" {
+			return nil, taskNotFound(id)
+		}
+	}
+	for i := range events {
+		if events[i].Changes, err = diffTask(events[i].OldData, events[i].NewData); err != nil {
+			return nil, err
+		}
+	}
+	return events, nil
+}
+
+// Update replaces task. When recurring task is marked done, rule is moved to new task of its next occurrence,
+// which is created together with the change
+func (s *Service) Update(ctx context.Context, id, version int, task models.Task) error {
+	task.Recurrence = normalizeRecurrence(task.Recurrence)
+	if err := s.validate(id, task); err != nil {
+		return err
+	}
+	task.ProjectId = optionalId(task.ProjectId)
+	task.ParentId = optionalId(task.ParentId)
+	task.Effort = optionalId(task.Effort)
+	if err := errors.Join(s.checkProject(ctx, task.ProjectId), s.checkParent(ctx, task.ParentId)); err != nil {
+		return err
+	}
+	if task.Done {
+		if err := s.checkDone(ctx, id); err != nil {
+			return err
+		}
+		return s.Repo.Complete(ctx, id, version, replacement(task), s.occurrence)
+	}
+	return s.Repo.Update(ctx, id, version, task)
+}
 
-	return tasks, nil
+// Returns patch which replaces all fields of task changed by Update
+func replacement(task models.Task) models.TaskPatch {
+	effort, projectId, parentId := 0, 0, 0
+	if task.Effort != nil {
+		effort = *task.Effort
+	}
+	if task.ProjectId != nil {
+		projectId = *task.ProjectId
+	}
+	if task.ParentId != nil {
+		parentId = *task.ParentId
+	}
+	return models.TaskPatch{
+		Header:      &task.Header,
+		Description: &task.Description,
+		Deadline:    &task.Deadline,
+		Done:        &task.Done,
+		Priority:    &task.Priority,
+		Effort:      &effort,
+		ProjectId:   &projectId,
+		ParentId:    &parentId,
+		Recurrence:  &task.Recurrence,
+	}
 }
 
-func (s *Service) Get(id int) (*models.Task, error) {
+// Patch changes only fields set in patch. Only these fields are validated. Recurring task marked done is handled as in Update
+func (s *Service) Patch(ctx context.Context, id, version int, patch models.TaskPatch) error {
 	var task models.Task
-	if err := s.Db.Get(&task, `select * from tasks where id = $1`, id); err != nil {
-		return nil, err
+	var fields []string
+	if patch.Header != nil {
+		task.Header = *patch.Header
+		fields = append(fields, "header")
+	}
+	if patch.Description != nil {
+		task.Description = *patch.Description
+		fields = append(fields, "description")
+	}
+	if patch.Deadline != nil {
+		task.Deadline = *patch.Deadline
+		fields = append(fields, "deadline")
+	}
+	if patch.Done != nil {
+		fields = append(fields, "done")
+	}
+	if patch.Priority != nil {
+		task.Priority = *patch.Priority
+		fields = append(fields, "priority")
+	}
+	if patch.Effort != nil {
+		task.Effort = patch.Effort
+		fields = append(fields, "effort")
+	}
+	if patch.Recurrence != nil {
+		recurrence := normalizeRecurrence(*patch.Recurrence)
+		patch.Recurrence = &recurrence
+		task.Recurrence = recurrence
+		fields = append(fields, "recurrence")
+	}
+
+	if len(fields) > 0 {
+		if err := s.validate(id, task, fields...); err != nil {
+			return err
+		}
+	}
+	if err := errors.Join(s.checkProject(ctx, patch.ProjectId), s.checkParent(ctx, patch.ParentId)); err != nil {
+		return err
+	}
+	if patch.Done != nil && *patch.Done {
+		if err := s.checkDone(ctx, id); err != nil {
+			return err
+		}
+		return s.Repo.Complete(ctx, id, version, patch, s.occurrence)
+	}
+	return s.Repo.Patch(ctx, id, version, patch)
+}
+
+// AttachTag attaches tag with tagId to task, it is recorded as update of task. As Update, it checks version of task unless it is 0
+func (s *Service) AttachTag(ctx context.Context, id, version, tagId int) error {
+	return s.Repo.AttachTag(ctx, id, version, tagId)
+}
+
+// DetachTag detaches tag with tagId from task the same way as AttachTag attaches it
+func (s *Service) DetachTag(ctx context.Context, id, version, tagId int) error {
+	return s.Repo.DetachTag(ctx, id, version, tagId)
+}
+
+// AddChecklistItem validates item and appends it to checklist of task, it is recorded as update of task.
+// As Update, it checks version of task unless it is 0
+func (s *Service) AddChecklistItem(ctx context.Context, id, version int, item models.ChecklistItem) (int, error) {
+	item.Text = strings.TrimSpace(item.Text)
+	if err := validateChecklistItem(0, item); err != nil {
+		return -1, err
 	}
-	return &task, nil
+	return s.Repo.AddChecklistItem(ctx, id, version, item)
 }
 
-func (s *Service) Delete(id int) error {
-	if _, err := s.Db.Exec(`delete from tasks where id = $1`, id); err != nil {
+// UpdateChecklistItem changes text and status of item with itemId, item is validated as on create
+func (s *Service) UpdateChecklistItem(ctx context.Context, id, version, itemId int, item models.ChecklistItem) error {
+	item.Text = strings.TrimSpace(item.Text)
+	if err := validateChecklistItem(itemId, item); err != nil {
 		return err
 	}
+	item.Id = itemId
+	return s.Repo.UpdateChecklistItem(ctx, id, version, item)
+}
+
+// DeleteChecklistItem removes item with itemId from checklist of task
+func (s *Service) DeleteChecklistItem(ctx context.Context, id, version, itemId int) error {
+	return s.Repo.DeleteChecklistItem(ctx, id, version, itemId)
+}
+
+// GetTags returns all tags ordered by name
+func (s *Service) GetTags(ctx context.Context) ([]models.Tag, error) {
+	return s.Repo.Tags(ctx)
+}
+
+// CreateTag validates tag and creates it, name is trimmed and must be unique
+func (s *Service) CreateTag(ctx context.Context, tag models.Tag) (int, error) {
+	tag.Name = strings.TrimSpace(tag.Name)
+	if err := validateTag(0, tag); err != nil {
+		return -1, err
+	}
+	return s.Repo.CreateTag(ctx, tag)
+}
+
+// UpdateTag renames tag and changes its colour, tag is validated as on create. Only creator of tag changes it
+func (s *Service) UpdateTag(ctx context.Context, id int, tag models.Tag) error {
+	tag.Name = strings.TrimSpace(tag.Name)
+	if err := validateTag(id, tag); err != nil {
+		return err
+	}
+	if err := s.checkTagCreator(ctx, id); err != nil {
+		return err
+	}
+	return s.Repo.UpdateTag(ctx, id, tag)
+}
+
+// DeleteTag deletes tag and detaches it from all tasks. Only creator of tag deletes it
+// and only if it is not attached to tasks of other users
+func (s *Service) DeleteTag(ctx context.Context, id int) error {
+	if err := s.checkTagCreator(ctx, id); err != nil {
+		return err
+	}
+	return s.Repo.DeleteTag(ctx, id)
+}
+
+// GetProjects returns archived or not archived projects ordered by name with numbers of their open and done tasks
+func (s *Service) GetProjects(ctx context.Context, archived bool) ([]models.Project, error) {
+	return s.Repo.Projects(ctx, archived)
+}
+
+func (s *Service) GetProject(ctx context.Context, id int) (*models.Project, error) {
+	return s.Repo.Project(ctx, id)
+}
+
+// CreateProject validates project and creates it, name is trimmed
+func (s *Service) CreateProject(ctx context.Context, project models.Project) (int, error) {
+	project.Name = strings.TrimSpace(project.Name)
+	if err := validateProject(0, project); err != nil {
+		return -1, err
+	}
+	return s.Repo.CreateProject(ctx, project)
+}
+
+// UpdateProject renames project, project is validated as on create. Only creator of project changes it
+func (s *Service) UpdateProject(ctx context.Context, id int, project models.Project) error {
+	project.Name = strings.TrimSpace(project.Name)
+	if err := validateProject(id, project); err != nil {
+		return err
+	}
+	if err := s.checkProjectCreator(ctx, id); err != nil {
+		return err
+	}
+	return s.Repo.UpdateProject(ctx, id, project)
+}
+
+// ArchiveProject archives project with its tasks owned by principal or shared with it, tasks of other users are left
+// as they are. Archived tasks are not listed (except tasks of the project) and can not be changed until project
+// is unarchived. Only creator of project archives it
+func (s *Service) ArchiveProject(ctx context.Context, id int) error {
+	if err := s.checkProjectCreator(ctx, id); err != nil {
+		return err
+	}
+	return s.Repo.ArchiveProject(ctx, id)
+}
+
+// UnarchiveProject brings project and its tasks available to principal back from archive
+func (s *Service) UnarchiveProject(ctx context.Context, id int) error {
+	if err := s.checkProjectCreator(ctx, id); err != nil {
+		return err
+	}
+	return s.Repo.UnarchiveProject(ctx, id)
+}
+
+// Fails with ForbiddenError if project with id was not created by principal of ctx
+func (s *Service) checkProjectCreator(ctx context.Context, id int) error {
+	project, err := s.Repo.Project(ctx, id)
+	if err != nil {
+		return err
+	}
+	if !ownedBy(ctx, project.OwnerId) {
+		return notCreator("project", id)
+	}
 	return nil
 }
 
-func (s *Service) Update(id int, task models.Task) error {
-	if task.Deadline.Before(time.Now()) {
-		return errInvalidDeadline
+// Fails with ForbiddenError if tag with id was not created by principal of ctx
+func (s *Service) checkTagCreator(ctx context.Context, id int) error {
+	tags, err := s.Repo.Tags(ctx)
+	if err != nil {
+		return err
+	}
+	for _, tag := range tags {
+		if tag.Id == id {
+			if !ownedBy(ctx, tag.OwnerId) {
+				return notCreator("tag", id)
+			}
+			return nil
+		}
 	}
-	if _, err := s.Db.Exec(`update tasks set header=$1, description=$2, deadline=$3, done=$4 where id = $5`,
-		task.Header, task.Description, task.Deadline, task.Done, id); err != nil {
+	return tagNotFound(id)
+}
+
+// Id 0 of project or parent means that task is not in any project or has no parent, effort 0 means no estimate
+func optionalId(id *int) *int {
+	if id != nil && *id == 0 {
+		return nil
+	}
+	return id
+}
+
+// Checks that task can be put into project: it exists and is not archived. Nil or 0 id means no project
+func (s *Service) checkProject(ctx context.Context, projectId *int) error {
+	if optionalId(projectId) == nil {
+		return nil
+	}
+	project, err := s.Repo.Project(ctx, *projectId)
+	if errors.Is(err, ErrNotFound) {
+		return &ValidationError{Field: "project_id", Message: fmt.Sprintf("project with id %d does not exist", *projectId)}
+	}
+	if err != nil {
 		return err
 	}
+	if project.ArchivedAt != nil {
+		return projectArchived(project.Id)
+	}
 	return nil
 }
 
-func (s *Service) GetByDateAndStatus(date time.Time, done, statusWasSet bool) ([]models.Task, error) {
-	var err error
-	var tasks []models.Task
+// Checks that task with id can be marked done if config blocks completion of tasks with open subtasks
+// or open blockers. Blockers in trash or archived do not block. Task which is already done stays done.
+// Subtasks and blockers of every user are counted, not only those available in ctx
+func (s *Service) checkDone(ctx context.Context, id int) error {
+	if !s.Subtasks.BlockParentDone && !s.Dependencies.BlockDone {
+		return nil
+	}
+	task, err := s.Repo.Get(ctx, id)
+	if err != nil || task.Done {
+		return err
+	}
+	done := false
+	all := withoutPrincipal(ctx)
+	if s.Subtasks.BlockParentDone {
+		open, err := s.Repo.Count(all, ListFilter{TaskFilter: TaskFilter{ParentId: &id, Done: &done, IncludeArchived: true}})
+		if err != nil {
+			return err
+		}
+		if open > 0 {
+			return openSubtasks(id, open)
+		}
+	}
+	if s.Dependencies.BlockDone {
+		open, err := s.Repo.Count(all, ListFilter{TaskFilter: TaskFilter{Blocking: &id, Done: &done}})
+		if err != nil {
+			return err
+		}
+		if open > 0 {
+			return openBlockers(id, open)
+		}
+	}
+	return nil
+}
 
+// GetByDateAndStatus returns tasks with deadline within day which starts at date, optionally filtered by status.
+// Unless only done tasks are requested, virtual occurrences of recurring tasks within day follow them
+func (s *Service) GetByDateAndStatus(ctx context.Context, date time.Time, done, statusWasSet bool) ([]models.Task, error) {
+	var status *bool
 	if statusWasSet {
-		err = s.Db.Select(&tasks, `select * from tasks where deadline = $1 and done = $2`, date, done)
-	} else {
-		err = s.Db.Select(&tasks, `select * from tasks where deadline = $1`, date)
+		status = &done
+	}
+	tasks, err := s.Repo.ByDate(ctx, date, status)
+	if err != nil || (statusWasSet && done) {
+		return tasks, err
 	}
+	occurrences, err := s.virtualOccurrences(ctx, date, date.AddDate(0, 0, 1))
+	if err != nil {
+		return nil, err
+	}
+	return append(tasks, occurrences...), nil
+}
+
+// Layout of dates which identify days of GetByDays
+const dateLayout = "2006-01-02"
+
+// TasksOfDay are tasks with deadline on Date
+type TasksOfDay struct {
+	Date  time.Time
+	Tasks []models.Task
+}
 
+// GetByDays returns tasks with deadline from first to last day inclusive, optionally filtered by status,
+// grouped by day. Days start at first and last, which should be midnights in timezone of the days.
+// Every day of range is present, even without tasks. Unless only done tasks are requested, virtual
+// occurrences of recurring tasks are included, tasks of a day are ordered by deadline
+func (s *Service) GetByDays(ctx context.Context, first, last time.Time, done *bool) ([]TasksOfDay, error) {
+	loc := first.Location()
+	end := last.AddDate(0, 0, 1)
+	tasks, err := s.Repo.List(ctx, ListFilter{
+		TaskFilter: TaskFilter{
+			Done:           done,
+			DeadlineFrom:   &first,
+			DeadlineBefore: &end,
+			Sort:           []SortField{{Field: "deadline"}},
+		},
+	})
 	if err != nil {
 		return nil, err
 	}
-	return tasks, nil
+	if done == nil || !*done {
+		occurrences, err := s.virtualOccurrences(ctx, first, end)
+		if err != nil {
+			return nil, err
+		}
+		tasks = append(tasks, occurrences...)
+		sort.SliceStable(tasks, func(i, j int) bool {
+			return tasks[i].Deadline.Before(tasks[j].Deadline)
+		})
+	}
+
+	var days []TasksOfDay
+	index := make(map[string]int)
+	for day := first; day.Before(end); day = day.AddDate(0, 0, 1) {
+		index[day.Format(dateLayout)] = len(days)
+		days = append(days, TasksOfDay{Date: day, Tasks: []models.Task{}})
+	}
+	for _, task := range tasks {
+		if i, ok := index[task.Deadline.In(loc).Format(dateLayout)]; ok {
+			days[i].Tasks = append(days[i].Tasks, task)
+		}
+	}
+	return days, nil
 }
@@ -0,0 +1,1300 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/O-Tempora/SberIT/internal/models"
+	"github.com/jmoiron/sqlx"
+	"modernc.org/sqlite"
+)
+
+// Times are passed to database as UTC text of fixed width, which postgres reads as timestamptz
+// and sqlite stores as is, so they stay comparable and ordered regardless of session timezone
+const timeLayout = "2006-01-02 15:04:05.000000Z"
+
+func formatTime(t time.Time) string {
+	return t.UTC().Format(timeLayout)
+}
+
+// Times of tasks are returned in UTC whatever timezone driver has set
+func utcTimes(tasks []models.Task) {
+	for i := range tasks {
+		utcTime(&tasks[i])
+	}
+}
+
+func utcTime(task *models.Task) {
+	task.Deadline = task.Deadline.UTC()
+	task.DeletedAt = utcPointer(task.DeletedAt)
+	task.ArchivedAt = utcPointer(task.ArchivedAt)
+}
+
+func utcPointer(t *time.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+	utc := t.UTC()
+	return &utc
+}
+
+func init() {
+	// Lower of sqlite folds only ASCII letters, casefold folds all of them like MemoryRepository
+	// and lower of postgres in UTF-8 database do
+	sqlite.MustRegisterDeterministicScalarFunction("casefold", 1, func(ctx *sqlite.FunctionContext, args []driver.Value) (driver.Value, error) {
+		switch arg := args[0].(type) {
+		case string:
+			return strings.ToLower(arg), nil
+		case []byte:
+			return strings.ToLower(string(arg)), nil
+		default:
+			return arg, nil
+		}
+	})
+}
+
+// Condition appended to where clause, which limits tasks to tasks owned by principal of ctx, and its arguments.
+// Empty if all tasks are available
+func ownerScope(ctx context.Context) (string, []interface{}) {
+	if id := ownerId(ctx); id != nil {
+		return " and owner_id = ?", []interface{}{*id}
+	}
+	return "", nil
+}
+
+// Condition like ownerScope, which also lets through tasks shared with principal
+func taskScope(ctx context.Context) (string, []interface{}) {
+	if id := ownerId(ctx); id != nil {
+		return " and " + sharedCondition, []interface{}{*id, *id}
+	}
+	return "", nil
+}
+
+// Task is owned by user or shared with it, takes id of user twice
+const sharedCondition = `(tasks.owner_id = ? or tasks.id in (select task_id from task_shares where user_id = ?))`
+
+// SQLRepository stores tasks in postgres or sqlite database.
+// Queries are written with "?" placeholders and rebound for db's driver
+type SQLRepository struct {
+	Db *sqlx.DB
+}
+
+func NewSQLRepository(db *sqlx.DB) *SQLRepository {
+	return &SQLRepository{
+		Db: db,
+	}
+}
+
+func (r *SQLRepository) Create(ctx context.Context, task models.Task) (int, error) {
+	var id int
+	err := r.inTx(ctx, func(tx *sqlx.Tx) (err error) {
+		id, err = r.insert(ctx, tx, task, ownerId(ctx), nil)
+		return err
+	})
+	if err != nil {
+		return -1, err
+	}
+	return id, nil
+}
+
+// Inserts task owned by owner with tags ordered by name and records its creation
+func (r *SQLRepository) insert(ctx context.Context, tx *sqlx.Tx, task models.Task, owner *int, tags []models.Tag) (int, error) {
+	var created models.Task
+	err := tx.GetContext(ctx, &created, tx.Rebind(`insert into tasks
+		(header, description, deadline, done, priority, effort, project_id, parent_id, recurrence, owner_id)
+		values (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		returning *`),
+		task.Header, task.Description, formatTime(task.Deadline), task.Done, task.Priority, task.Effort,
+		task.ProjectId, task.ParentId, task.Recurrence, owner)
+	if err != nil {
+		return -1, err
+	}
+	for _, tag := range tags {
+		if _, err := tx.ExecContext(ctx, tx.Rebind(`insert into task_tags (task_id, tag_id) values (?, ?)`), created.Id, tag.Id); err != nil {
+			return -1, err
+		}
+	}
+	utcTime(&created)
+	created.Tags = append([]models.Tag{}, tags...)
+	created.Checklist = []models.ChecklistItem{}
+	created.BlockedBy = []int{}
+	created.Progress = taskProgress(created, 0, 0)
+	return created.Id, r.record(ctx, tx, ActionCreate, nil, &created)
+}
+
+func (r *SQLRepository) Get(ctx context.Context, id int) (*models.Task, error) {
+	var task models.Task
+	scope, args := taskScope(ctx)
+	query := `select * from tasks where id = ? and deleted_at is null` + scope
+	if err := r.Db.GetContext(ctx, &task, r.Db.Rebind(query), append([]interface{}{id}, args...)...); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, taskNotFound(id)
+		}
+		return nil, err
+	}
+	utcTime(&task)
+	if err := loadTaskDetails(ctx, r.Db, &task); err != nil {
+		return nil, err
+	}
+	return &task, nil
+}
+
+func (r *SQLRepository) List(ctx context.Context, filter ListFilter) ([]models.Task, error) {
+	q := newTaskQuery(ctx, filter.TaskFilter, r.casefold())
+	backward := false
+	if filter.Limit > 0 {
+		q.order = []string{"id"}
+		if filter.Cursor != nil && filter.Cursor.Backward {
+			q.where("id < ?", filter.Cursor.Id)
+			q.order = []string{"id desc"}
+			backward = true
+		} else if filter.Cursor != nil {
+			q.where("id > ?", filter.Cursor.Id)
+		}
+	}
+
+	query, args := q.build("*", true)
+	switch {
+	case filter.Limit > 0:
+		query += " limit ?"
+		args = append(args, filter.Limit)
+	case filter.Take > 0:
+		query += " limit ? offset ?"
+		args = append(args, filter.Take, filter.Take*(filter.Page-1))
+	}
+
+	var tasks []models.Task
+	if err := r.Db.SelectContext(ctx, &tasks, r.Db.Rebind(query), args...); err != nil {
+		return nil, err
+	}
+	if backward {
+		slices.Reverse(tasks)
+	}
+	utcTimes(tasks)
+	return tasks, loadDetails(ctx, r.Db, tasks)
+}
+
+func (r *SQLRepository) Count(ctx context.Context, filter ListFilter) (int, error) {
+	query, args := newTaskQuery(ctx, filter.TaskFilter, r.casefold()).build("count(*)", false)
+	var count int
+	if err := r.Db.GetContext(ctx, &count, r.Db.Rebind(query), args...); err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+func (r *SQLRepository) Update(ctx context.Context, id, version int, task models.Task) error {
+	return r.inTx(ctx, func(tx *sqlx.Tx) error {
+		current, err := r.lockTaskWithParent(ctx, tx, id, version, task.ParentId)
+		if err != nil {
+			return err
+		}
+		return r.change(ctx, tx, ActionUpdate, current, `header=?, description=?, deadline=?, done=?, priority=?, effort=?, project_id=?, parent_id=?, recurrence=?`,
+			task.Header, task.Description, formatTime(task.Deadline), task.Done, task.Priority, task.Effort,
+			task.ProjectId, task.ParentId, task.Recurrence)
+	})
+}
+
+func (r *SQLRepository) Patch(ctx context.Context, id, version int, patch models.TaskPatch) error {
+	set, args := patchSet(patch)
+	return r.inTx(ctx, func(tx *sqlx.Tx) error {
+		current, err := r.lockTaskWithParent(ctx, tx, id, version, patch.ParentId)
+		// Nothing to change, but missing task or version mismatch must still be reported
+		if err != nil || set == "" {
+			return err
+		}
+		return r.change(ctx, tx, ActionUpdate, current, set, args...)
+	})
+}
+
+func (r *SQLRepository) Complete(ctx context.Context, id, version int, patch models.TaskPatch, next func(task models.Task) (*models.Task, error)) error {
+	return r.inTx(ctx, func(tx *sqlx.Tx) error {
+		current, err := r.lockTaskWithParent(ctx, tx, id, version, patch.ParentId)
+		if err != nil {
+			return err
+		}
+		occurrence, err := completion(*current, &patch, next)
+		if err != nil {
+			return err
+		}
+		set, args := patchSet(patch)
+		if err = r.change(ctx, tx, ActionUpdate, current, set, args...); err != nil || occurrence == nil {
+			return err
+		}
+		occurrenceId, err := r.insert(ctx, tx, *occurrence, current.OwnerId, occurrence.Tags)
+		if err != nil {
+			return err
+		}
+		_, err = tx.ExecContext(ctx, tx.Rebind(`insert into task_shares (task_id, user_id, role, created_at)
+			select ?, user_id, role, created_at from task_shares where task_id = ?`), occurrenceId, id)
+		return err
+	})
+}
+
+// Returns set clause of update of fields set in patch and its arguments
+func patchSet(patch models.TaskPatch) (string, []interface{}) {
+	var set []string
+	var args []interface{}
+	if patch.Header != nil {
+		set = append(set, "header=?")
+		args = append(args, *patch.Header)
+	}
+	if patch.Description != nil {
+		set = append(set, "description=?")
+		args = append(args, *patch.Description)
+	}
+	if patch.Deadline != nil {
+		set = append(set, "deadline=?")
+		args = append(args, formatTime(*patch.Deadline))
+	}
+	if patch.Done != nil {
+		set = append(set, "done=?")
+		args = append(args, *patch.Done)
+	}
+	if patch.Priority != nil {
+		set = append(set, "priority=?")
+		args = append(args, *patch.Priority)
+	}
+	if patch.Effort != nil {
+		set = append(set, "effort=?")
+		args = append(args, optionalIdArg(*patch.Effort))
+	}
+	if patch.ProjectId != nil {
+		set = append(set, "project_id=?")
+		args = append(args, optionalIdArg(*patch.ProjectId))
+	}
+	if patch.ParentId != nil {
+		set = append(set, "parent_id=?")
+		args = append(args, optionalIdArg(*patch.ParentId))
+	}
+	if patch.Recurrence != nil {
+		set = append(set, "recurrence=?")
+		args = append(args, *patch.Recurrence)
+	}
+	return strings.Join(set, ", "), args
+}
+
+func (r *SQLRepository) Delete(ctx context.Context, id, version int) error {
+	return r.inTx(ctx, func(tx *sqlx.Tx) error {
+		current, err := r.lockTask(ctx, tx, id, version)
+		if err != nil {
+			return err
+		}
+		return r.change(ctx, tx, ActionDelete, current, `deleted_at=?`, formatTime(time.Now()))
+	})
+}
+
+func (r *SQLRepository) Trash(ctx context.Context) ([]models.Task, error) {
+	var tasks []models.Task
+	scope, args := taskScope(ctx)
+	query := `select * from tasks where deleted_at is not null` + scope + ` order by deleted_at desc, id`
+	if err := r.Db.SelectContext(ctx, &tasks, r.Db.Rebind(query), args...); err != nil {
+		return nil, err
+	}
+	utcTimes(tasks)
+	return tasks, loadDetails(ctx, r.Db, tasks)
+}
+
+func (r *SQLRepository) Restore(ctx context.Context, id int) error {
+	return r.inTx(ctx, func(tx *sqlx.Tx) error {
+		var current models.Task
+		scope, args := taskScope(ctx)
+		query := `select * from tasks where id = ? and deleted_at is not null` + scope + r.forUpdate()
+		err := tx.GetContext(ctx, &current, tx.Rebind(query), append([]interface{}{id}, args...)...)
+		if errors.Is(err, sql.ErrNoRows) {
+			return trashedTaskNotFound(id)
+		}
+		if err != nil {
+			return err
+		}
+		utcTime(&current)
+		if err = loadTaskDetails(ctx, tx, &current); err != nil {
+			return err
+		}
+		// Task of archived project is restored archived
+		return r.change(ctx, tx, ActionRestore, &current,
+			`deleted_at=null, archived_at=(select archived_at from projects where projects.id = tasks.project_id)`)
+	})
+}
+
+func (r *SQLRepository) Purge(ctx context.Context, before time.Time) (int, error) {
+	var purged []models.Task
+	err := r.inTx(ctx, func(tx *sqlx.Tx) error {
+		scope, args := ownerScope(ctx)
+		query := `select * from tasks where deleted_at < ?` + scope + r.forUpdate()
+		err := tx.SelectContext(ctx, &purged, tx.Rebind(query), append([]interface{}{formatTime(before)}, args...)...)
+		if err != nil || len(purged) == 0 {
+			return err
+		}
+		utcTimes(purged)
+		if err = loadDetails(ctx, tx, purged); err != nil {
+			return err
+		}
+		ids := taskIds(purged)
+		// Subtasks of purged tasks become top level tasks
+		for _, query := range []string{
+			`update tasks set parent_id = null where parent_id in (?)`,
+			`delete from task_tags where task_id in (?)`,
+			`delete from checklist_items where task_id in (?)`,
+			`delete from task_shares where task_id in (?)`,
+			`delete from task_dependencies where task_id in (?)`,
+			`delete from task_dependencies where blocker_id in (?)`,
+			`delete from tasks where id in (?)`,
+		} {
+			query, args, err := sqlx.In(query, ids)
+			if err != nil {
+				return err
+			}
+			if _, err = tx.ExecContext(ctx, tx.Rebind(query), args...); err != nil {
+				return err
+			}
+		}
+		for i := range purged {
+			if err = r.record(ctx, tx, ActionPurge, &purged[i], nil); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return len(purged), nil
+}
+
+func (r *SQLRepository) ByDate(ctx context.Context, date time.Time, done *bool) ([]models.Task, error) {
+	scope, scopeArgs := taskScope(ctx)
+	query := `select * from tasks where deleted_at is null and archived_at is null and deadline >= ? and deadline < ?` + scope
+	args := append([]interface{}{formatTime(date), formatTime(date.AddDate(0, 0, 1))}, scopeArgs...)
+	if done != nil {
+		query += ` and done = ?`
+		args = append(args, *done)
+	}
+
+	var tasks []models.Task
+	if err := r.Db.SelectContext(ctx, &tasks, r.Db.Rebind(query), args...); err != nil {
+		return nil, err
+	}
+	utcTimes(tasks)
+	return tasks, loadDetails(ctx, r.Db, tasks)
+}
+
+func (r *SQLRepository) History(ctx context.Context, id int) ([]models.TaskEvent, error) {
+	var events []models.TaskEvent
+	if err := r.Db.SelectContext(ctx, &events, r.Db.Rebind(`select * from task_events where task_id = ? order by id`), id); err != nil {
+		return nil, err
+	}
+	for i := range events {
+		events[i].CreatedAt = events[i].CreatedAt.UTC()
+	}
+	return events, nil
+}
+
+func (r *SQLRepository) Descendants(ctx context.Context, id int) ([]models.Task, error) {
+	var tasks []models.Task
+	scope, args := taskScope(ctx)
+	err := r.Db.SelectContext(ctx, &tasks, r.Db.Rebind(`with recursive subtree(id) as (
+			select id from tasks where parent_id = ? and deleted_at is null
+			union
+			select tasks.id from tasks join subtree on tasks.parent_id = subtree.id where tasks.deleted_at is null
+		)
+		select * from tasks where id in (select id from subtree)`+scope+` order by id`), append([]interface{}{id}, args...)...)
+	if err != nil {
+		return nil, err
+	}
+	utcTimes(tasks)
+	return tasks, loadDetails(ctx, r.Db, tasks)
+}
+
+func (r *SQLRepository) Ancestors(ctx context.Context, id int) ([]int, error) {
+	return ancestors(ctx, r.Db, id)
+}
+
+func ancestors(ctx context.Context, q queryer, id int) ([]int, error) {
+	// Union skips rows already found, so it ends even if parents make a cycle
+	var ids []int
+	err := sqlx.SelectContext(ctx, q, &ids, q.Rebind(`with recursive ancestors(id, parent_id) as (
+			select id, parent_id from tasks where id = ?
+			union
+			select tasks.id, tasks.parent_id from tasks join ancestors on tasks.id = ancestors.parent_id
+		)
+		select id from ancestors`), id)
+	if err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
+
+func (r *SQLRepository) AddChecklistItem(ctx context.Context, id, version int, item models.ChecklistItem) (int, error) {
+	var itemId int
+	err := r.inTx(ctx, func(tx *sqlx.Tx) error {
+		current, err := r.lockTask(ctx, tx, id, version)
+		if err != nil {
+			return err
+		}
+		err = tx.GetContext(ctx, &itemId, tx.Rebind(`insert into checklist_items (task_id, text, done) values (?, ?, ?) returning id`),
+			id, item.Text, item.Done)
+		if err != nil {
+			return err
+		}
+		return r.change(ctx, tx, ActionUpdate, current, "")
+	})
+	if err != nil {
+		return -1, err
+	}
+	return itemId, nil
+}
+
+func (r *SQLRepository) UpdateChecklistItem(ctx context.Context, id, version int, item models.ChecklistItem) error {
+	return r.inTx(ctx, func(tx *sqlx.Tx) error {
+		current, err := r.lockTask(ctx, tx, id, version)
+		if err != nil {
+			return err
+		}
+		i := slices.IndexFunc(current.Checklist, func(c models.ChecklistItem) bool { return c.Id == item.Id })
+		if i < 0 {
+			return checklistItemNotFound(item.Id)
+		}
+		if current.Checklist[i].Text == item.Text && current.Checklist[i].Done == item.Done {
+			return nil
+		}
+		_, err = tx.ExecContext(ctx, tx.Rebind(`update checklist_items set text=?, done=? where id = ?`), item.Text, item.Done, item.Id)
+		if err != nil {
+			return err
+		}
+		return r.change(ctx, tx, ActionUpdate, current, "")
+	})
+}
+
+func (r *SQLRepository) DeleteChecklistItem(ctx context.Context, id, version, itemId int) error {
+	return r.inTx(ctx, func(tx *sqlx.Tx) error {
+		current, err := r.lockTask(ctx, tx, id, version)
+		if err != nil {
+			return err
+		}
+		res, err := tx.ExecContext(ctx, tx.Rebind(`delete from checklist_items where id = ? and task_id = ?`), itemId, id)
+		if err != nil {
+			return err
+		}
+		n, err := res.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if n == 0 {
+			return checklistItemNotFound(itemId)
+		}
+		return r.change(ctx, tx, ActionUpdate, current, "")
+	})
+}
+
+// Runs fn in transaction, which is committed if fn succeeds and rolled back otherwise
+func (r *SQLRepository) inTx(ctx context.Context, fn func(tx *sqlx.Tx) error) error {
+	tx, err := r.Db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if err = fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// Function which folds case of text like strings.ToLower, casefold is registered for sqlite in init
+func (r *SQLRepository) casefold() string {
+	if r.Db.DriverName() == "postgres" {
+		return "lower"
+	}
+	return "casefold"
+}
+
+// Row lock of postgres, sqlite has no row locks and allows only one writing transaction at a time
+func (r *SQLRepository) forUpdate() string {
+	if r.Db.DriverName() == "postgres" {
+		return " for update"
+	}
+	return ""
+}
+
+// Returns task with id out of trash locked until the end of tx.
+// Fails if it does not exist, is archived or has other version than expected (any if version is 0)
+func (r *SQLRepository) lockTask(ctx context.Context, tx *sqlx.Tx, id, version int) (*models.Task, error) {
+	var task models.Task
+	scope, args := taskScope(ctx)
+	query := `select * from tasks where id = ? and deleted_at is null` + scope + r.forUpdate()
+	err := tx.GetContext(ctx, &task, tx.Rebind(query), append([]interface{}{id}, args...)...)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, taskNotFound(id)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if task.ArchivedAt != nil {
+		return nil, taskArchived(id)
+	}
+	if version != 0 && task.Version != version {
+		return nil, &VersionMismatchError{Id: id, Expected: version}
+	}
+	utcTime(&task)
+	if err = loadTaskDetails(ctx, tx, &task); err != nil {
+		return nil, err
+	}
+	return &task, nil
+}
+
+// Locks task with id like lockTask. If task becomes subtask of parent (nil or 0 keeps it top level), parent is locked
+// together with it and ValidationError is returned if parent is the task itself or its subtask
+func (r *SQLRepository) lockTaskWithParent(ctx context.Context, tx *sqlx.Tx, id, version int, parentId *int) (*models.Task, error) {
+	if optionalId(parentId) == nil {
+		return r.lockTask(ctx, tx, id, version)
+	}
+	if err := r.lockTasks(ctx, tx, id, *parentId); err != nil {
+		return nil, err
+	}
+	current, err := r.lockTask(ctx, tx, id, version)
+	if err != nil {
+		return nil, err
+	}
+	ids, err := ancestors(ctx, tx, *parentId)
+	if err != nil {
+		return nil, err
+	}
+	if slices.Contains(ids, id) {
+		return nil, parentCycle()
+	}
+	return current, nil
+}
+
+// Locks rows of tasks with ids in order of ids until the end of tx, so transactions which link the same tasks
+// wait for each other without deadlock and check links committed by them. Sqlite needs no row locks
+func (r *SQLRepository) lockTasks(ctx context.Context, tx *sqlx.Tx, ids ...int) error {
+	if r.forUpdate() == "" {
+		return nil
+	}
+	query, args, err := sqlx.In(`select id from tasks where id in (?) order by id`+r.forUpdate(), ids)
+	if err != nil {
+		return err
+	}
+	var locked []int
+	return tx.SelectContext(ctx, &locked, tx.Rebind(query), args...)
+}
+
+// Sets columns of current task by set clause (only version if it is empty), increments its version and records the change
+func (r *SQLRepository) change(ctx context.Context, tx *sqlx.Tx, action string, current *models.Task, set string, args ...interface{}) error {
+	set = strings.TrimPrefix(set+", version=version+1", ", ")
+	var task models.Task
+	err := tx.GetContext(ctx, &task, tx.Rebind(`update tasks set `+set+` where id = ? returning *`),
+		append(args, current.Id)...)
+	if err != nil {
+		return err
+	}
+	utcTime(&task)
+	if err = loadTaskDetails(ctx, tx, &task); err != nil {
+		return err
+	}
+	return r.record(ctx, tx, action, current, &task)
+}
+
+// Inserts event of change from old to new state of task
+func (r *SQLRepository) record(ctx context.Context, tx *sqlx.Tx, action string, old, new *models.Task) error {
+	event, err := newTaskEvent(ctx, action, old, new)
+	if err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, tx.Rebind(`insert into task_events
+		(task_id, action, old_data, new_data, actor, request_id, created_at)
+		values (?, ?, ?, ?, ?, ?, ?)`),
+		event.TaskId, event.Action, jsonArg(event.OldData), jsonArg(event.NewData), event.Actor, event.RequestId, formatTime(event.CreatedAt))
+	return err
+}
+
+// JSON is passed as text, nil as null
+func jsonArg(data []byte) interface{} {
+	if data == nil {
+		return nil
+	}
+	return string(data)
+}
+
+func (r *SQLRepository) AttachTag(ctx context.Context, id, version, tagId int) error {
+	return r.changeLinks(ctx, id, version, tagId, checkTag, `insert into task_tags (task_id, tag_id) values (?, ?) on conflict do nothing`)
+}
+
+func (r *SQLRepository) DetachTag(ctx context.Context, id, version, tagId int) error {
+	return r.changeLinks(ctx, id, version, tagId, checkTag, `delete from task_tags where task_id = ? and tag_id = ?`)
+}
+
+func (r *SQLRepository) Blockers(ctx context.Context, id int) ([]int, error) {
+	return blockers(ctx, r.Db, id)
+}
+
+func blockers(ctx context.Context, q queryer, id int) ([]int, error) {
+	// Union skips rows already found, so it ends even if dependencies make a cycle
+	var ids []int
+	err := sqlx.SelectContext(ctx, q, &ids, q.Rebind(`with recursive blockers(id) as (
+			select blocker_id from task_dependencies where task_id = ?
+			union
+			select task_dependencies.blocker_id from task_dependencies join blockers on task_dependencies.task_id = blockers.id
+		)
+		select id from blockers`), id)
+	if err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
+
+func (r *SQLRepository) AddBlocker(ctx context.Context, id, version, blockerId int) error {
+	check := func(ctx context.Context, tx *sqlx.Tx, blockerId int) error {
+		if err := checkTask(ctx, tx, blockerId); err != nil {
+			return err
+		}
+		ids, err := blockers(ctx, tx, blockerId)
+		if err != nil {
+			return err
+		}
+		if blockerId == id || slices.Contains(ids, id) {
+			return dependencyCycle(id, blockerId)
+		}
+		return nil
+	}
+	return r.inTx(ctx, func(tx *sqlx.Tx) error {
+		if err := r.lockTasks(ctx, tx, id, blockerId); err != nil {
+			return err
+		}
+		return r.link(ctx, tx, id, version, blockerId, check,
+			`insert into task_dependencies (task_id, blocker_id) values (?, ?) on conflict do nothing`)
+	})
+}
+
+func (r *SQLRepository) RemoveBlocker(ctx context.Context, id, version, blockerId int) error {
+	return r.changeLinks(ctx, id, version, blockerId, nil, `delete from task_dependencies where task_id = ? and blocker_id = ?`)
+}
+
+func (r *SQLRepository) Tags(ctx context.Context) ([]models.Tag, error) {
+	tags := []models.Tag{}
+	if err := r.Db.SelectContext(ctx, &tags, `select * from tags order by name, id`); err != nil {
+		return nil, err
+	}
+	return tags, nil
+}
+
+func (r *SQLRepository) CreateTag(ctx context.Context, tag models.Tag) (int, error) {
+	var id int
+	err := r.inTx(ctx, func(tx *sqlx.Tx) error {
+		if err := checkTagName(ctx, tx, 0, tag.Name); err != nil {
+			return err
+		}
+		return tx.GetContext(ctx, &id, tx.Rebind(`insert into tags (name, color, owner_id) values (?, ?, ?) returning id`),
+			tag.Name, tag.Color, ownerId(ctx))
+	})
+	if err != nil {
+		return -1, err
+	}
+	return id, nil
+}
+
+func (r *SQLRepository) UpdateTag(ctx context.Context, id int, tag models.Tag) error {
+	return r.inTx(ctx, func(tx *sqlx.Tx) error {
+		if err := checkTagName(ctx, tx, id, tag.Name); err != nil {
+			return err
+		}
+		res, err := tx.ExecContext(ctx, tx.Rebind(`update tags set name=?, color=? where id = ?`), tag.Name, tag.Color, id)
+		if err != nil {
+			return err
+		}
+		return checkTagAffected(res, id)
+	})
+}
+
+func (r *SQLRepository) DeleteTag(ctx context.Context, id int) error {
+	return r.inTx(ctx, func(tx *sqlx.Tx) error {
+		var foreign bool
+		// Tasks shared with principal are tasks of other users too
+		scope, args := ownerScope(ctx)
+		query := `select count(*) > 0 from task_tags where tag_id = ?
+			and not exists (select 1 from tasks where tasks.id = task_tags.task_id` + scope + `)`
+		if err := tx.GetContext(ctx, &foreign, tx.Rebind(query), append([]interface{}{id}, args...)...); err != nil {
+			return err
+		}
+		if foreign {
+			return tagOfOtherUsers(id)
+		}
+		if _, err := tx.ExecContext(ctx, tx.Rebind(`delete from task_tags where tag_id = ?`), id); err != nil {
+			return err
+		}
+		res, err := tx.ExecContext(ctx, tx.Rebind(`delete from tags where id = ?`), id)
+		if err != nil {
+			return err
+		}
+		return checkTagAffected(res, id)
+	})
+}
+
+// Runs statement linking task with id to other entity, e.g. tag, or unlinking it, with ids of task and entity as arguments.
+// Entity is checked by check if it is set. Change of task is recorded only if statement has affected its links
+func (r *SQLRepository) changeLinks(ctx context.Context, id, version, otherId int, check func(ctx context.Context, tx *sqlx.Tx, id int) error, statement string) error {
+	return r.inTx(ctx, func(tx *sqlx.Tx) error {
+		return r.link(ctx, tx, id, version, otherId, check, statement)
+	})
+}
+
+// Runs statement of changeLinks in tx
+func (r *SQLRepository) link(ctx context.Context, tx *sqlx.Tx, id, version, otherId int, check func(ctx context.Context, tx *sqlx.Tx, id int) error, statement string) error {
+	current, err := r.lockTask(ctx, tx, id, version)
+	if err != nil {
+		return err
+	}
+	if check != nil {
+		if err = check(ctx, tx, otherId); err != nil {
+			return err
+		}
+	}
+	res, err := tx.ExecContext(ctx, tx.Rebind(statement), id, otherId)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil || n == 0 {
+		return err
+	}
+	return r.change(ctx, tx, ActionUpdate, current, "")
+}
+
+// Returns NotFoundError if tag with id does not exist
+func checkTag(ctx context.Context, tx *sqlx.Tx, id int) error {
+	var exists bool
+	if err := tx.GetContext(ctx, &exists, tx.Rebind(`select count(*) > 0 from tags where id = ?`), id); err != nil {
+		return err
+	}
+	if !exists {
+		return tagNotFound(id)
+	}
+	return nil
+}
+
+// Returns NotFoundError if task with id does not exist out of trash or is not available in ctx
+func checkTask(ctx context.Context, tx *sqlx.Tx, id int) error {
+	var exists bool
+	scope, args := taskScope(ctx)
+	query := `select count(*) > 0 from tasks where id = ? and deleted_at is null` + scope
+	if err := tx.GetContext(ctx, &exists, tx.Rebind(query), append([]interface{}{id}, args...)...); err != nil {
+		return err
+	}
+	if !exists {
+		return taskNotFound(id)
+	}
+	return nil
+}
+
+// Returns ConflictError if tag other than one with id has name
+func checkTagName(ctx context.Context, tx *sqlx.Tx, id int, name string) error {
+	var taken bool
+	if err := tx.GetContext(ctx, &taken, tx.Rebind(`select count(*) > 0 from tags where name = ? and id <> ?`), name, id); err != nil {
+		return err
+	}
+	if taken {
+		return tagExists(name)
+	}
+	return nil
+}
+
+// Returns NotFoundError if statement did not affect tag with id
+func checkTagAffected(res sql.Result, id int) error {
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return tagNotFound(id)
+	}
+	return nil
+}
+
+// Querier of task details, either db or transaction
+type queryer interface {
+	sqlx.QueryerContext
+	Rebind(query string) string
+}
+
+// Loads tags of all tasks with one query. Tasks without tags get empty list
+func loadTags(ctx context.Context, q queryer, tasks []models.Task) error {
+	if len(tasks) == 0 {
+		return nil
+	}
+	query, args, err := sqlx.In(`select task_tags.task_id, tags.* from task_tags
+		join tags on tags.id = task_tags.tag_id
+		where task_tags.task_id in (?)
+		order by tags.name, tags.id`, taskIds(tasks))
+	if err != nil {
+		return err
+	}
+	var rows []struct {
+		TaskId int `db:"task_id"`
+		models.Tag
+	}
+	if err = sqlx.SelectContext(ctx, q, &rows, q.Rebind(query), args...); err != nil {
+		return err
+	}
+
+	tags := make(map[int][]models.Tag)
+	for _, row := range rows {
+		tags[row.TaskId] = append(tags[row.TaskId], row.Tag)
+	}
+	for i := range tasks {
+		tasks[i].Tags = tags[tasks[i].Id]
+		if tasks[i].Tags == nil {
+			tasks[i].Tags = []models.Tag{}
+		}
+	}
+	return nil
+}
+
+// Loads tags, checklist items, blockers and progress of all tasks
+func loadDetails(ctx context.Context, q queryer, tasks []models.Task) error {
+	if err := loadTags(ctx, q, tasks); err != nil {
+		return err
+	}
+	if err := loadChecklists(ctx, q, tasks); err != nil {
+		return err
+	}
+	if err := loadBlockers(ctx, q, tasks); err != nil {
+		return err
+	}
+	if err := loadRoles(ctx, q, tasks); err != nil {
+		return err
+	}
+	return loadProgress(ctx, q, tasks)
+}
+
+func loadTaskDetails(ctx context.Context, q queryer, task *models.Task) error {
+	tasks := []models.Task{*task}
+	if err := loadDetails(ctx, q, tasks); err != nil {
+		return err
+	}
+	task.Tags, task.Checklist, task.BlockedBy, task.Progress = tasks[0].Tags, tasks[0].Checklist, tasks[0].BlockedBy, tasks[0].Progress
+	task.Role = tasks[0].Role
+	return nil
+}
+
+// Sets role of principal in ctx on tasks with one query for tasks it does not own. Roles are not set without principal
+func loadRoles(ctx context.Context, q queryer, tasks []models.Task) error {
+	userId := ownerId(ctx)
+	if userId == nil || len(tasks) == 0 {
+		return nil
+	}
+	query, args, err := sqlx.In(`select task_id, role from task_shares where user_id = ? and task_id in (?)`, *userId, taskIds(tasks))
+	if err != nil {
+		return err
+	}
+	var shares []models.TaskShare
+	if err = sqlx.SelectContext(ctx, q, &shares, q.Rebind(query), args...); err != nil {
+		return err
+	}
+	roles := make(map[int]string)
+	for _, share := range shares {
+		roles[share.TaskId] = share.Role
+	}
+	for i := range tasks {
+		tasks[i].Role = roles[tasks[i].Id]
+		if tasks[i].OwnerId != nil && *tasks[i].OwnerId == *userId {
+			tasks[i].Role = RoleOwner
+		}
+	}
+	return nil
+}
+
+// Loads checklist items of all tasks with one query. Tasks without items get empty list
+func loadChecklists(ctx context.Context, q queryer, tasks []models.Task) error {
+	if len(tasks) == 0 {
+		return nil
+	}
+	query, args, err := sqlx.In(`select * from checklist_items where task_id in (?) order by id`, taskIds(tasks))
+	if err != nil {
+		return err
+	}
+	var items []models.ChecklistItem
+	if err = sqlx.SelectContext(ctx, q, &items, q.Rebind(query), args...); err != nil {
+		return err
+	}
+
+	checklists := make(map[int][]models.ChecklistItem)
+	for _, item := range items {
+		checklists[item.TaskId] = append(checklists[item.TaskId], item)
+	}
+	for i := range tasks {
+		tasks[i].Checklist = checklists[tasks[i].Id]
+		if tasks[i].Checklist == nil {
+			tasks[i].Checklist = []models.ChecklistItem{}
+		}
+	}
+	return nil
+}
+
+// Loads ids of blockers of all tasks with one query. Tasks without blockers get empty list
+func loadBlockers(ctx context.Context, q queryer, tasks []models.Task) error {
+	if len(tasks) == 0 {
+		return nil
+	}
+	query, args, err := sqlx.In(`select task_id, blocker_id from task_dependencies where task_id in (?) order by blocker_id`, taskIds(tasks))
+	if err != nil {
+		return err
+	}
+	var rows []struct {
+		TaskId    int `db:"task_id"`
+		BlockerId int `db:"blocker_id"`
+	}
+	if err = sqlx.SelectContext(ctx, q, &rows, q.Rebind(query), args...); err != nil {
+		return err
+	}
+
+	blockers := make(map[int][]int)
+	for _, row := range rows {
+		blockers[row.TaskId] = append(blockers[row.TaskId], row.BlockerId)
+	}
+	for i := range tasks {
+		tasks[i].BlockedBy = blockers[tasks[i].Id]
+		if tasks[i].BlockedBy == nil {
+			tasks[i].BlockedBy = []int{}
+		}
+	}
+	return nil
+}
+
+// Computes progress of all tasks, counting their subtasks out of trash with one query. Checklists must be loaded
+func loadProgress(ctx context.Context, q queryer, tasks []models.Task) error {
+	if len(tasks) == 0 {
+		return nil
+	}
+	query, args, err := sqlx.In(`select parent_id, count(*) as total, sum(case when done then 1 else 0 end) as done
+		from tasks where deleted_at is null and parent_id in (?)
+		group by parent_id`, taskIds(tasks))
+	if err != nil {
+		return err
+	}
+	var rows []struct {
+		ParentId int `db:"parent_id"`
+		Total    int `db:"total"`
+		Done     int `db:"done"`
+	}
+	if err = sqlx.SelectContext(ctx, q, &rows, q.Rebind(query), args...); err != nil {
+		return err
+	}
+
+	subtasks := make(map[int][2]int)
+	for _, row := range rows {
+		subtasks[row.ParentId] = [2]int{row.Total, row.Done}
+	}
+	for i := range tasks {
+		counts := subtasks[tasks[i].Id]
+		tasks[i].Progress = taskProgress(tasks[i], counts[0], counts[1])
+	}
+	return nil
+}
+
+func taskIds(tasks []models.Task) []int {
+	ids := make([]int, len(tasks))
+	for i, task := range tasks {
+		ids[i] = task.Id
+	}
+	return ids
+}
+
+// Project or parent id 0 of patch removes task from project or makes it top level task, effort 0 removes estimate
+func optionalIdArg(id int) interface{} {
+	if id == 0 {
+		return nil
+	}
+	return id
+}
+
+// Returns statement selecting projects with numbers of their open and done tasks available in ctx and its arguments
+func projectQuery(ctx context.Context) (string, []interface{}) {
+	scope, args := taskScope(ctx)
+	return `select projects.*,
+		coalesce(counts.open_tasks, 0) as open_tasks,
+		coalesce(counts.done_tasks, 0) as done_tasks
+	from projects left join (
+		select project_id,
+			sum(case when done then 0 else 1 end) as open_tasks,
+			sum(case when done then 1 else 0 end) as done_tasks
+		from tasks where deleted_at is null` + scope + `
+		group by project_id
+	) counts on counts.project_id = projects.id`, args
+}
+
+func (r *SQLRepository) Projects(ctx context.Context, archived bool) ([]models.Project, error) {
+	condition := ` where projects.archived_at is null`
+	if archived {
+		condition = ` where projects.archived_at is not null`
+	}
+	projects := []models.Project{}
+	query, args := projectQuery(ctx)
+	if err := r.Db.SelectContext(ctx, &projects, r.Db.Rebind(query+condition+` order by projects.name, projects.id`), args...); err != nil {
+		return nil, err
+	}
+	for i := range projects {
+		projects[i].ArchivedAt = utcPointer(projects[i].ArchivedAt)
+	}
+	return projects, nil
+}
+
+func (r *SQLRepository) Project(ctx context.Context, id int) (*models.Project, error) {
+	var project models.Project
+	query, args := projectQuery(ctx)
+	if err := r.Db.GetContext(ctx, &project, r.Db.Rebind(query+` where projects.id = ?`), append(args, id)...); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, projectNotFound(id)
+		}
+		return nil, err
+	}
+	project.ArchivedAt = utcPointer(project.ArchivedAt)
+	return &project, nil
+}
+
+func (r *SQLRepository) CreateProject(ctx context.Context, project models.Project) (int, error) {
+	var id int
+	if err := r.Db.GetContext(ctx, &id, r.Db.Rebind(`insert into projects (name, owner_id) values (?, ?) returning id`), project.Name, ownerId(ctx)); err != nil {
+		return -1, err
+	}
+	return id, nil
+}
+
+func (r *SQLRepository) UpdateProject(ctx context.Context, id int, project models.Project) error {
+	res, err := r.Db.ExecContext(ctx, r.Db.Rebind(`update projects set name=? where id = ?`), project.Name, id)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return projectNotFound(id)
+	}
+	return nil
+}
+
+func (r *SQLRepository) ArchiveProject(ctx context.Context, id int) error {
+	return r.setProjectArchived(ctx, id, true)
+}
+
+func (r *SQLRepository) UnarchiveProject(ctx context.Context, id int) error {
+	return r.setProjectArchived(ctx, id, false)
+}
+
+// Archives or unarchives project and its tasks out of trash available in ctx, recording change of every task
+func (r *SQLRepository) setProjectArchived(ctx context.Context, id int, archive bool) error {
+	return r.inTx(ctx, func(tx *sqlx.Tx) error {
+		var archivedAt *time.Time
+		err := tx.GetContext(ctx, &archivedAt, tx.Rebind(`select archived_at from projects where id = ?`+r.forUpdate()), id)
+		if errors.Is(err, sql.ErrNoRows) {
+			return projectNotFound(id)
+		}
+		if err != nil || (archivedAt != nil) == archive {
+			return err
+		}
+
+		action, value := ActionUnarchive, interface{}(nil)
+		if archive {
+			action, value = ActionArchive, formatTime(time.Now())
+		}
+		if _, err = tx.ExecContext(ctx, tx.Rebind(`update projects set archived_at=? where id = ?`), value, id); err != nil {
+			return err
+		}
+
+		var tasks []models.Task
+		scope, args := taskScope(ctx)
+		query := `select * from tasks where project_id = ? and deleted_at is null` + scope + ` order by id` + r.forUpdate()
+		err = tx.SelectContext(ctx, &tasks, tx.Rebind(query), append([]interface{}{id}, args...)...)
+		if err != nil {
+			return err
+		}
+		utcTimes(tasks)
+		if err = loadDetails(ctx, tx, tasks); err != nil {
+			return err
+		}
+		for i := range tasks {
+			if err = r.change(ctx, tx, action, &tasks[i], `archived_at=?`, value); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (r *SQLRepository) CreateUser(ctx context.Context, user models.User) (int, error) {
+	var id int
+	err := r.inTx(ctx, func(tx *sqlx.Tx) error {
+		var taken bool
+		if err := tx.GetContext(ctx, &taken, tx.Rebind(`select count(*) > 0 from users where username = ?`), user.Username); err != nil {
+			return err
+		}
+		if taken {
+			return userExists(user.Username)
+		}
+		return tx.GetContext(ctx, &id, tx.Rebind(`insert into users (username, password_hash, created_at) values (?, ?, ?) returning id`),
+			user.Username, user.PasswordHash, formatTime(user.CreatedAt))
+	})
+	if err != nil {
+		return -1, err
+	}
+	return id, nil
+}
+
+func (r *SQLRepository) UserByName(ctx context.Context, username string) (*models.User, error) {
+	var user models.User
+	if err := r.Db.GetContext(ctx, &user, r.Db.Rebind(`select * from users where username = ?`), username); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, userNotFound(username)
+		}
+		return nil, err
+	}
+	user.CreatedAt = user.CreatedAt.UTC()
+	return &user, nil
+}
+
+// Row of api_keys table, scopes are stored separated by spaces
+type apiKeyRow struct {
+	models.APIKey
+	Scopes string `db:"scopes"`
+}
+
+func (row apiKeyRow) key() models.APIKey {
+	key := row.APIKey
+	key.Scopes = strings.Fields(row.Scopes)
+	key.CreatedAt = key.CreatedAt.UTC()
+	key.LastUsedAt = utcPointer(key.LastUsedAt)
+	key.RevokedAt = utcPointer(key.RevokedAt)
+	return key
+}
+
+func (r *SQLRepository) CreateAPIKey(ctx context.Context, key models.APIKey) (int, error) {
+	var id int
+	err := r.Db.GetContext(ctx, &id, r.Db.Rebind(`insert into api_keys
+			(user_id, name, prefix, key_hash, scopes, created_at)
+			values (?, ?, ?, ?, ?, ?)
+			returning id`),
+		key.UserId, key.Name, key.Prefix, key.KeyHash, strings.Join(key.Scopes, " "), formatTime(key.CreatedAt))
+	if err != nil {
+		return -1, err
+	}
+	return id, nil
+}
+
+func (r *SQLRepository) APIKeys(ctx context.Context) ([]models.APIKey, error) {
+	query := `select * from api_keys where true`
+	var args []interface{}
+	if id := ownerId(ctx); id != nil {
+		query += ` and user_id = ?`
+		args = append(args, *id)
+	}
+	var rows []apiKeyRow
+	if err := r.Db.SelectContext(ctx, &rows, r.Db.Rebind(query+` order by id`), args...); err != nil {
+		return nil, err
+	}
+	keys := make([]models.APIKey, 0, len(rows))
+	for _, row := range rows {
+		keys = append(keys, row.key())
+	}
+	return keys, nil
+}
+
+func (r *SQLRepository) RevokeAPIKey(ctx context.Context, id int) error {
+	query := `update api_keys set revoked_at = coalesce(revoked_at, ?) where id = ?`
+	args := []interface{}{formatTime(time.Now()), id}
+	if owner := ownerId(ctx); owner != nil {
+		query += ` and user_id = ?`
+		args = append(args, *owner)
+	}
+	res, err := r.Db.ExecContext(ctx, r.Db.Rebind(query), args...)
+	if err != nil {
+		return err
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return apiKeyNotFound(id)
+	}
+	return nil
+}
+
+func (r *SQLRepository) APIKeyByHash(ctx context.Context, hash string) (*models.APIKey, error) {
+	var row apiKeyRow
+	err := r.Db.GetContext(ctx, &row, r.Db.Rebind(`select api_keys.*, users.username
+		from api_keys join users on users.id = api_keys.user_id
+		where api_keys.key_hash = ? and api_keys.revoked_at is null`), hash)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errInvalidAPIKey
+		}
+		return nil, err
+	}
+	key := row.key()
+	return &key, nil
+}
+
+func (r *SQLRepository) TouchAPIKey(ctx context.Context, id int, at time.Time) error {
+	_, err := r.Db.ExecContext(ctx, r.Db.Rebind(`update api_keys set last_used_at = ? where id = ?`), formatTime(at), id)
+	return err
+}
+
+func (r *SQLRepository) Role(ctx context.Context, id int) (string, error) {
+	var task models.Task
+	if err := r.Db.GetContext(ctx, &task, r.Db.Rebind(`select * from tasks where id = ?`), id); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", taskNotFound(id)
+		}
+		return "", err
+	}
+	if ownerId(ctx) == nil {
+		return RoleOwner, nil
+	}
+	tasks := []models.Task{task}
+	if err := loadRoles(ctx, r.Db, tasks); err != nil {
+		return "", err
+	}
+	return tasks[0].Role, nil
+}
+
+func (r *SQLRepository) Shares(ctx context.Context, id int) ([]models.TaskShare, error) {
+	shares := []models.TaskShare{}
+	err := r.Db.SelectContext(ctx, &shares, r.Db.Rebind(`select task_shares.*, users.username
+		from task_shares join users on users.id = task_shares.user_id
+		where task_shares.task_id = ?
+		order by users.username`), id)
+	if err != nil {
+		return nil, err
+	}
+	for i := range shares {
+		shares[i].CreatedAt = shares[i].CreatedAt.UTC()
+	}
+	return shares, nil
+}
+
+func (r *SQLRepository) ShareTask(ctx context.Context, share models.TaskShare) error {
+	_, err := r.Db.ExecContext(ctx, r.Db.Rebind(`insert into task_shares (task_id, user_id, role, created_at) values (?, ?, ?, ?)
+		on conflict (task_id, user_id) do update set role = excluded.role`),
+		share.TaskId, share.UserId, share.Role, formatTime(share.CreatedAt))
+	return err
+}
+
+func (r *SQLRepository) UnshareTask(ctx context.Context, id, userId int) error {
+	res, err := r.Db.ExecContext(ctx, r.Db.Rebind(`delete from task_shares where task_id = ? and user_id = ?`), id, userId)
+	if err != nil {
+		return err
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return &NotFoundError{Resource: "share of task", Id: id}
+	}
+	return nil
+}
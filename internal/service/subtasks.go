@@ -0,0 +1,66 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/O-Tempora/SberIT/internal/models"
+)
+
+// Returns progress of task from numbers of its subtasks and its checklist: done task is complete,
+// otherwise progress is percentage of done subtasks and checklist items
+func taskProgress(task models.Task, subtasks, doneSubtasks int) int {
+	if task.Done {
+		return 100
+	}
+	total, done := subtasks, doneSubtasks
+	for _, item := range task.Checklist {
+		total++
+		if item.Done {
+			done++
+		}
+	}
+	if total == 0 {
+		return 0
+	}
+	return done * 100 / total
+}
+
+// Returns task with tree of its subtasks built from its descendants
+func withChildren(task models.Task, descendants []models.Task) models.Task {
+	children := make(map[int][]models.Task)
+	for _, t := range descendants {
+		if t.ParentId != nil {
+			children[*t.ParentId] = append(children[*t.ParentId], t)
+		}
+	}
+	attachChildren(&task, children, make(map[int]bool))
+	return task
+}
+
+// Tasks already in tree are skipped, so broken data can not make it infinite
+func attachChildren(task *models.Task, children map[int][]models.Task, seen map[int]bool) {
+	seen[task.Id] = true
+	for _, child := range children[task.Id] {
+		if seen[child.Id] {
+			continue
+		}
+		attachChildren(&child, children, seen)
+		task.Children = append(task.Children, child)
+	}
+}
+
+// Checks that parent of task exists out of trash. Nil or 0 parent means top level task. That parent is neither
+// the task itself nor its subtask is checked by repository in transaction of the change
+func (s *Service) checkParent(ctx context.Context, parentId *int) error {
+	if optionalId(parentId) == nil {
+		return nil
+	}
+	if _, err := s.Repo.Get(ctx, *parentId); errors.Is(err, ErrNotFound) {
+		return &ValidationError{Field: "parent_id", Message: fmt.Sprintf("task with id %d does not exist", *parentId)}
+	} else if err != nil {
+		return err
+	}
+	return nil
+}
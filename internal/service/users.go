@@ -0,0 +1,149 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/O-Tempora/SberIT/internal/models"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	minPassword = 8
+	// Longer passwords are truncated by bcrypt
+	maxPassword = 72
+)
+
+var usernamePattern = regexp.MustCompile(`^[a-zA-Z0-9_.-]{3,50}$`)
+
+// Principal is authenticated user on whose behalf tasks are accessed
+type Principal struct {
+	UserId   int
+	Username string
+	// Scopes of api key which authenticated request, nil if user authenticated with own credentials
+	Scopes []string
+}
+
+type principalKey struct{}
+
+// WithPrincipal returns ctx in which tasks are limited to tasks of principal. Without principal all tasks are
+// available, which is meant only for the system itself, e.g. purge of trash
+func WithPrincipal(ctx context.Context, principal Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, principal)
+}
+
+// PrincipalFrom returns principal of ctx, false if ctx has none
+func PrincipalFrom(ctx context.Context) (Principal, bool) {
+	principal, ok := ctx.Value(principalKey{}).(Principal)
+	return principal, ok
+}
+
+// Returns ctx in which all tasks are available, for checks which must see tasks of every user, e.g. subtasks
+// of shared task created by its editor
+func withoutPrincipal(ctx context.Context) context.Context {
+	return context.WithValue(ctx, principalKey{}, nil)
+}
+
+// Returns id of user whose tasks are available in ctx, nil if all tasks are
+func ownerId(ctx context.Context) *int {
+	principal, ok := PrincipalFrom(ctx)
+	if !ok {
+		return nil
+	}
+	return &principal.UserId
+}
+
+// Reports whether task is owned by principal of ctx, tasks shared with principal are not
+func owns(ctx context.Context, task models.Task) bool {
+	return ownedBy(ctx, task.OwnerId)
+}
+
+// Reports whether task, project or tag with owner is owned by principal of ctx. Everything is owned without principal
+func ownedBy(ctx context.Context, owner *int) bool {
+	id := ownerId(ctx)
+	return id == nil || (owner != nil && *owner == *id)
+}
+
+// Reports whether task of history is available in ctx. Owner of task never changes, so it is taken from the first event
+func ownsHistory(ctx context.Context, events []models.TaskEvent) (bool, error) {
+	data := events[0].NewData
+	if len(data) == 0 {
+		data = events[0].OldData
+	}
+	var task models.Task
+	if err := json.Unmarshal(data, &task); err != nil {
+		return false, err
+	}
+	return owns(ctx, task), nil
+}
+
+// Register validates credentials and creates user with hash of password. Username is trimmed and must be unique
+func (s *Service) Register(ctx context.Context, credentials models.Credentials) (int, error) {
+	credentials.Username = strings.TrimSpace(credentials.Username)
+	if err := validateCredentials(credentials); err != nil {
+		return -1, err
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(credentials.Password), s.passwordCost())
+	if err != nil {
+		return -1, err
+	}
+	return s.Repo.CreateUser(ctx, models.User{
+		Username:     credentials.Username,
+		PasswordHash: string(hash),
+		CreatedAt:    normalizeTime(time.Now()),
+	})
+}
+
+// Login returns user with username if password matches its hash, AuthenticationError otherwise
+func (s *Service) Login(ctx context.Context, credentials models.Credentials) (*models.User, error) {
+	user, err := s.Repo.UserByName(ctx, strings.TrimSpace(credentials.Username))
+	if errors.Is(err, ErrNotFound) {
+		bcrypt.CompareHashAndPassword(s.dummy(), []byte(credentials.Password))
+		return nil, errInvalidCredentials
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err = bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(credentials.Password)); err != nil {
+		return nil, errInvalidCredentials
+	}
+	return user, nil
+}
+
+// Returns bcrypt cost of password hashes from config, bcrypt.DefaultCost if it is not set
+func (s *Service) passwordCost() int {
+	if s.Users.PasswordCost == 0 {
+		return bcrypt.DefaultCost
+	}
+	return s.Users.PasswordCost
+}
+
+// Returns hash compared with password of unknown user, so login takes the same time whether user exists or not.
+// It has the same cost as hashes of users
+func (s *Service) dummy() []byte {
+	s.dummyHashOnce.Do(func() {
+		s.dummyHash, _ = bcrypt.GenerateFromPassword([]byte("dummy password"), s.passwordCost())
+	})
+	return s.dummyHash
+}
+
+// GetUser returns user with username
+func (s *Service) GetUser(ctx context.Context, username string) (*models.User, error) {
+	return s.Repo.UserByName(ctx, username)
+}
+
+// Checks credentials on registration and joins violations into one error
+func validateCredentials(credentials models.Credentials) error {
+	var errs []error
+	if !usernamePattern.MatchString(credentials.Username) {
+		errs = append(errs, &ValidationError{Field: "username", Message: "must be 3 to 50 latin letters, digits, dots, dashes or underscores"})
+	}
+	if len(credentials.Password) < minPassword || len(credentials.Password) > maxPassword {
+		errs = append(errs, &ValidationError{Field: "password", Message: "must be 8 to 72 bytes long"})
+	}
+	return errors.Join(errs...)
+}
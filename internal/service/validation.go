@@ -0,0 +1,171 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/O-Tempora/SberIT/config"
+	"github.com/O-Tempora/SberIT/internal/models"
+	"github.com/teambition/rrule-go"
+)
+
+// Limits used if they are not set in config
+const (
+	defaultMaxHeader      = 200
+	defaultMaxDescription = 10000
+)
+
+const (
+	maxTagName       = 50
+	maxProjectName   = 200
+	maxChecklistText = 500
+	maxPriority      = 4
+)
+
+var tagColorPattern = regexp.MustCompile(`^#[0-9a-fA-F]{6}$`)
+
+// rule checks one field of task and returns its violation or nil
+type rule struct {
+	field string
+	check func(id int, task models.Task) *ValidationError
+}
+
+// Rules applied to task on create (id is 0) and update
+func taskRules(cf config.Validation) []rule {
+	maxHeader := cf.MaxHeader
+	if maxHeader <= 0 {
+		maxHeader = defaultMaxHeader
+	}
+	maxDescription := cf.MaxDescription
+	if maxDescription <= 0 {
+		maxDescription = defaultMaxDescription
+	}
+
+	return []rule{
+		{"id", func(id int, task models.Task) *ValidationError {
+			switch {
+			case task.Id == 0 || task.Id == id:
+				return nil
+			case id == 0:
+				return &ValidationError{Field: "id", Message: "is assigned by server and must not be set"}
+			default:
+				return &ValidationError{Field: "id", Message: "must not differ from id in path"}
+			}
+		}},
+		{"header", func(id int, task models.Task) *ValidationError {
+			if strings.TrimSpace(task.Header) == "" {
+				return &ValidationError{Field: "header", Message: "must not be empty"}
+			}
+			return maxLength("header", task.Header, maxHeader)
+		}},
+		{"description", func(id int, task models.Task) *ValidationError {
+			return maxLength("description", task.Description, maxDescription)
+		}},
+		{"deadline", func(id int, task models.Task) *ValidationError {
+			if task.Deadline.IsZero() {
+				return &ValidationError{Field: "deadline", Message: "is required"}
+			}
+			if task.Deadline.Before(time.Now()) {
+				return errInvalidDeadline
+			}
+			return nil
+		}},
+		{"priority", func(id int, task models.Task) *ValidationError {
+			if task.Priority < 0 || task.Priority > maxPriority {
+				return &ValidationError{Field: "priority", Message: fmt.Sprintf("must be from 0 to %d", maxPriority)}
+			}
+			return nil
+		}},
+		{"effort", func(id int, task models.Task) *ValidationError {
+			if task.Effort != nil && *task.Effort < 0 {
+				return &ValidationError{Field: "effort", Message: "must not be negative"}
+			}
+			return nil
+		}},
+		{"recurrence", func(id int, task models.Task) *ValidationError {
+			if task.Recurrence == "" {
+				return nil
+			}
+			rule, err := parseRecurrence(task.Recurrence, task.Deadline, time.UTC)
+			if err != nil {
+				return &ValidationError{Field: "recurrence", Message: "must be RFC 5545 RRULE: " + err.Error()}
+			}
+			// More frequent rules would make too many occurrences in a day
+			if rule.OrigOptions.Freq > rrule.HOURLY {
+				return &ValidationError{Field: "recurrence", Message: "must not repeat more often than hourly"}
+			}
+			return nil
+		}},
+	}
+}
+
+// Checks task against rules of listed fields (all rules if none are listed) and joins violations into one error
+func (s *Service) validate(id int, task models.Task, fields ...string) error {
+	var errs []error
+	for _, r := range taskRules(s.Validation) {
+		if len(fields) > 0 && !slices.Contains(fields, r.field) {
+			continue
+		}
+		if err := r.check(id, task); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func maxLength(field, value string, max int) *ValidationError {
+	if utf8.RuneCountInString(value) > max {
+		return &ValidationError{Field: field, Message: fmt.Sprintf("must be at most %d characters long", max)}
+	}
+	return nil
+}
+
+// Checks tag on create (id is 0) and update and joins violations into one error
+func validateTag(id int, tag models.Tag) error {
+	var errs []error
+	if tag.Id != 0 && tag.Id != id {
+		errs = append(errs, &ValidationError{Field: "id", Message: "must not be set or differ from id in path"})
+	}
+	if strings.TrimSpace(tag.Name) == "" {
+		errs = append(errs, &ValidationError{Field: "name", Message: "must not be empty"})
+	} else if err := maxLength("name", tag.Name, maxTagName); err != nil {
+		errs = append(errs, err)
+	}
+	if tag.Color != "" && !tagColorPattern.MatchString(tag.Color) {
+		errs = append(errs, &ValidationError{Field: "color", Message: "must be in format #rrggbb"})
+	}
+	return errors.Join(errs...)
+}
+
+// Checks project on create (id is 0) and update and joins violations into one error
+func validateProject(id int, project models.Project) error {
+	var errs []error
+	if project.Id != 0 && project.Id != id {
+		errs = append(errs, &ValidationError{Field: "id", Message: "must not be set or differ from id in path"})
+	}
+	if strings.TrimSpace(project.Name) == "" {
+		errs = append(errs, &ValidationError{Field: "name", Message: "must not be empty"})
+	} else if err := maxLength("name", project.Name, maxProjectName); err != nil {
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
+
+// Checks checklist item on create (id is 0) and update and joins violations into one error
+func validateChecklistItem(id int, item models.ChecklistItem) error {
+	var errs []error
+	if item.Id != 0 && item.Id != id {
+		errs = append(errs, &ValidationError{Field: "id", Message: "must not be set or differ from id in path"})
+	}
+	if strings.TrimSpace(item.Text) == "" {
+		errs = append(errs, &ValidationError{Field: "text", Message: "must not be empty"})
+	} else if err := maxLength("text", item.Text, maxChecklistText); err != nil {
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}